@@ -0,0 +1,95 @@
+package main
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+// newTestDatabase connects to the Postgres server in TEST_DB_URL and applies
+// the migrations in a schema of its own, dropped when the test ends. Tests
+// that depend on what the SQL actually does are skipped without it.
+func newTestDatabase(t *testing.T) (*sql.DB, *database.Queries) {
+	t.Helper()
+	dbURL := os.Getenv("TEST_DB_URL")
+	if dbURL == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		t.Fatalf("Expected no error connecting, but got %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	suffix := make([]byte, 6)
+	rand.Read(suffix)
+	schema := "test_" + hex.EncodeToString(suffix)
+	_, err = admin.Exec("CREATE SCHEMA " + schema)
+	if err != nil {
+		t.Fatalf("Expected no error creating schema, but got %v", err)
+	}
+	t.Cleanup(func() { admin.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	u, err := url.Parse(dbURL)
+	if err != nil {
+		t.Fatalf("Expected a URL in TEST_DB_URL, but got %v", err)
+	}
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+	db, err := sql.Open("postgres", u.String())
+	if err != nil {
+		t.Fatalf("Expected no error connecting, but got %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	migrations, _ := filepath.Glob("sql/schema/*.sql")
+	for _, path := range migrations {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("Expected no error reading %s, but got %v", path, err)
+		}
+		up, _, _ := strings.Cut(string(data), "-- +goose Down")
+		_, err = db.Exec(strings.TrimPrefix(up, "-- +goose Up"))
+		if err != nil {
+			t.Fatalf("Expected no error applying %s, but got %v", path, err)
+		}
+	}
+
+	return db, database.New(db)
+}
+
+func createTestUser(t *testing.T, q *database.Queries, email string) database.User {
+	t.Helper()
+	user, err := q.CreateUser(t.Context(), database.CreateUserParams{Email: email, HashedPassword: "unused"})
+	if err != nil {
+		t.Fatalf("Expected no error creating user, but got %v", err)
+	}
+	return user
+}
+
+func createTestChirp(t *testing.T, q *database.Queries, userID uuid.UUID, body string) database.Chirp {
+	t.Helper()
+	chirp, err := q.CreateChirp(t.Context(), database.CreateChirpParams{Body: body, UserID: userID})
+	if err != nil {
+		t.Fatalf("Expected no error creating chirp, but got %v", err)
+	}
+	return chirp
+}
+
+func chirpIDs(chirps []database.Chirp) map[uuid.UUID]bool {
+	ids := make(map[uuid.UUID]bool, len(chirps))
+	for _, chirp := range chirps {
+		ids[chirp.ID] = true
+	}
+	return ids
+}
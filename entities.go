@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+var (
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])#([\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*)`)
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])@([A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,})`)
+)
+
+type chirpEntity struct {
+	Text  string
+	Start int
+	End   int
+}
+
+// extractHashtags returns the hashtags in body, lowercased, with their rune
+// offsets. The offsets cover the leading '#'.
+func extractHashtags(body string) []chirpEntity {
+	return extractEntities(body, hashtagPattern)
+}
+
+// extractMentions returns the email addresses mentioned in body, lowercased,
+// with their rune offsets. The offsets cover the leading '@'.
+func extractMentions(body string) []chirpEntity {
+	return extractEntities(body, mentionPattern)
+}
+
+func extractEntities(body string, pattern *regexp.Regexp) []chirpEntity {
+	var entities []chirpEntity
+	for _, match := range pattern.FindAllStringSubmatchIndex(body, -1) {
+		// match[2] points just past the '#' or '@' prefix.
+		start := utf8.RuneCountInString(body[:match[2]-1])
+		entities = append(entities, chirpEntity{
+			Text: strings.ToLower(body[match[2]:match[3]]),
+			Start: start,
+			End: start + 1 + utf8.RuneCountInString(body[match[2]:match[3]]),
+		})
+	}
+	return entities
+}
+
+func uniqueEntityTexts(entities []chirpEntity) []string {
+	seen := make(map[string]bool)
+	var texts []string
+	for _, entity := range entities {
+		if !seen[entity.Text] {
+			seen[entity.Text] = true
+			texts = append(texts, entity.Text)
+		}
+	}
+	return texts
+}
+
+// saveChirpEntities replaces the stored hashtags and mentions of a chirp with
+// the ones found in its current body. Mentions of unknown emails are ignored.
+func saveChirpEntities(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	err := q.DeleteChirpHashtags(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+	err = q.DeleteChirpMentions(ctx, chirp.ID)
+	if err != nil {
+		return err
+	}
+
+	tags := uniqueEntityTexts(extractHashtags(chirp.Body))
+	if len(tags) > 0 {
+		err = q.InsertChirpHashtags(ctx, database.InsertChirpHashtagsParams{ChirpID: chirp.ID, Tags: tags, CreatedAt: chirp.CreatedAt})
+		if err != nil {
+			return err
+		}
+	}
+
+	emails := uniqueEntityTexts(extractMentions(chirp.Body))
+	if len(emails) == 0 {
+		return nil
+	}
+	users, err := q.GetUserIdsByEmails(ctx, emails)
+	if err != nil {
+		return err
+	}
+	if len(users) == 0 {
+		return nil
+	}
+	userIDs := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		userIDs[i] = user.ID
+	}
+	return q.InsertChirpMentions(ctx, database.InsertChirpMentionsParams{ChirpID: chirp.ID, UserIds: userIDs})
+}
+
+// chirpEntitiesResponse builds the entity list for a chirp body. mentioned maps
+// lowercased emails to the users they resolved to when the chirp was saved.
+func chirpEntitiesResponse(body string, mentioned map[string]uuid.UUID) ChirpEntities {
+	entities := ChirpEntities{Hashtags: []HashtagEntity{}, Mentions: []MentionEntity{}}
+	for _, hashtag := range extractHashtags(body) {
+		entities.Hashtags = append(entities.Hashtags, HashtagEntity{Tag: hashtag.Text, Start: hashtag.Start, End: hashtag.End})
+	}
+	for _, mention := range extractMentions(body) {
+		userID, ok := mentioned[mention.Text]
+		if !ok {
+			continue
+		}
+		entities.Mentions = append(entities.Mentions, MentionEntity{UserID: userID, Email: mention.Text, Start: mention.Start, End: mention.End})
+	}
+	return entities
+}
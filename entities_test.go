@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []chirpEntity
+	}{
+		{"Loving #Go and #golang!", []chirpEntity{{"go", 7, 10}, {"golang", 15, 22}}},
+		{"Café #crème_brûlée", []chirpEntity{{"crème_brûlée", 5, 18}}},
+		{"Issue #123 and a#b", nil},
+		{"#start", []chirpEntity{{"start", 0, 6}}},
+	}
+
+	for _, test := range tests {
+		result := extractHashtags(test.input)
+		if len(result) != len(test.expected) {
+			t.Fatalf("Expected %d hashtags in '%s', but got %d", len(test.expected), test.input, len(result))
+		}
+		for i := range result {
+			if result[i] != test.expected[i] {
+				t.Errorf("Expected '%v', but got '%v'", test.expected[i], result[i])
+			}
+		}
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	result := extractMentions("Hi @Alice@Example.com, meet @bob@example.org. Mail me at carol@example.com")
+
+	expected := []chirpEntity{{"alice@example.com", 3, 21}, {"bob@example.org", 28, 44}}
+	if len(result) != len(expected) {
+		t.Fatalf("Expected %d mentions, but got %d", len(expected), len(result))
+	}
+	for i := range result {
+		if result[i] != expected[i] {
+			t.Errorf("Expected '%v', but got '%v'", expected[i], result[i])
+		}
+	}
+}
+
+func TestChirpEntitiesResponse(t *testing.T) {
+	aliceID := uuid.New()
+	entities := chirpEntitiesResponse("#hello @alice@example.com @nobody@example.com", map[string]uuid.UUID{"alice@example.com": aliceID})
+
+	if len(entities.Hashtags) != 1 || entities.Hashtags[0].Tag != "hello" {
+		t.Errorf("Expected the hello hashtag, but got %v", entities.Hashtags)
+	}
+	if len(entities.Mentions) != 1 || entities.Mentions[0].UserID != aliceID {
+		t.Errorf("Expected only the resolved mention, but got %v", entities.Mentions)
+	}
+}
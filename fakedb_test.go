@@ -0,0 +1,285 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/entitlements"
+	"github.com/isotronic/http-go-server/internal/moderation"
+	"github.com/lib/pq"
+)
+
+// fakeDB is a database/sql driver for handler tests. Queries are matched by
+// the sqlc query name and answered by scripted functions, so a test states
+// exactly what the database returns without needing Postgres.
+//
+// A function returns a struct for a single row, a slice for several rows,
+// nil for no rows, or a plain value for a single column. Struct fields become
+// columns in order and nested structs, as produced by sqlc.embed, are
+// flattened. For exec queries an int64 is the number of rows affected and
+// nil means one.
+type fakeDB struct {
+	t         *testing.T
+	mu        sync.Mutex
+	handlers  map[string]func(args []driver.Value) (any, error)
+	queries   []fakeQuery
+	commits   int
+	rollbacks int
+}
+
+type fakeQuery struct {
+	Name string
+	Args []driver.Value
+}
+
+var queryNamePattern = regexp.MustCompile(`-- name: (\w+)`)
+
+// newTestAPIConfig returns an apiConfig backed by a fakeDB, signing tokens
+// with an HMAC key. It uses the default tiers and a moderation filter without
+// rules.
+func newTestAPIConfig(t *testing.T) (*apiConfig, *fakeDB) {
+	t.Helper()
+	fake := &fakeDB{t: t, handlers: make(map[string]func(args []driver.Value) (any, error))}
+	db := sql.OpenDB(fakeConnector{fake})
+	t.Cleanup(func() { db.Close() })
+
+	keys, err := auth.NewKeySet(nil, "", "test-secret")
+	if err != nil {
+		t.Fatalf("Expected no error creating keys, but got %v", err)
+	}
+	filter, err := moderation.NewFilter(nil)
+	if err != nil {
+		t.Fatalf("Expected no error creating the filter, but got %v", err)
+	}
+	return &apiConfig{
+		db:           db,
+		database:     database.New(db),
+		keys:         keys,
+		baseURL:      "http://localhost:8080",
+		moderation:   filter,
+		entitlements: entitlements.Default,
+	}, fake
+}
+
+// on scripts the answer to a query.
+func (f *fakeDB) on(name string, fn func(args []driver.Value) (any, error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.handlers[name] = fn
+}
+
+// returns scripts a query to always give the same result.
+func (f *fakeDB) returns(name string, result any) {
+	f.on(name, func([]driver.Value) (any, error) { return result, nil })
+}
+
+// calls returns the arguments of every run of the named query.
+func (f *fakeDB) calls(name string) [][]driver.Value {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls [][]driver.Value
+	for _, query := range f.queries {
+		if query.Name == name {
+			calls = append(calls, query.Args)
+		}
+	}
+	return calls
+}
+
+func (f *fakeDB) run(query string, named []driver.NamedValue) (any, error) {
+	match := queryNamePattern.FindStringSubmatch(query)
+	if match == nil {
+		return nil, fmt.Errorf("fakedb: query without a name: %s", query)
+	}
+	args := make([]driver.Value, len(named))
+	for i, arg := range named {
+		args[i] = arg.Value
+	}
+
+	f.mu.Lock()
+	f.queries = append(f.queries, fakeQuery{Name: match[1], Args: args})
+	fn, ok := f.handlers[match[1]]
+	f.mu.Unlock()
+	if !ok {
+		f.t.Errorf("Unexpected query %s", match[1])
+		return nil, fmt.Errorf("fakedb: unexpected query %s", match[1])
+	}
+	return fn(args)
+}
+
+type fakeConnector struct{ db *fakeDB }
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: c.db}, nil }
+func (c fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return nil, errors.New("fakedb: use a connector") }
+
+type fakeConn struct{ db *fakeDB }
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("fakedb: prepared statements are not supported")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return &fakeTx{db: c.db}, nil }
+
+func (c *fakeConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return &fakeTx{db: c.db}, nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return newFakeRows(result), nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	result, err := c.db.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	affected, ok := result.(int64)
+	if !ok {
+		affected = 1
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+type fakeTx struct{ db *fakeDB }
+
+func (tx *fakeTx) Commit() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+	tx.db.rollbacks++
+	return nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func newFakeRows(result any) *fakeRows {
+	rows := &fakeRows{}
+	if result == nil {
+		return rows
+	}
+	v := reflect.ValueOf(result)
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		for i := 0; i < v.Len(); i++ {
+			rows.rows = append(rows.rows, rowValues(v.Index(i)))
+		}
+	} else {
+		rows.rows = append(rows.rows, rowValues(v))
+	}
+	if len(rows.rows) > 0 {
+		rows.columns = make([]string, len(rows.rows[0]))
+		for i := range rows.columns {
+			rows.columns[i] = fmt.Sprintf("column%d", i+1)
+		}
+	}
+	return rows
+}
+
+func rowValues(v reflect.Value) []driver.Value {
+	if !isComposite(v) {
+		return []driver.Value{driverValue(v)}
+	}
+	var values []driver.Value
+	for i := 0; i < v.NumField(); i++ {
+		values = append(values, rowValues(v.Field(i))...)
+	}
+	return values
+}
+
+func isComposite(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct || v.Type() == reflect.TypeOf(time.Time{}) {
+		return false
+	}
+	_, valuer := v.Interface().(driver.Valuer)
+	return !valuer
+}
+
+func driverValue(v reflect.Value) driver.Value {
+	switch value := v.Interface().(type) {
+	case driver.Valuer:
+		result, _ := value.Value()
+		return result
+	case time.Time:
+		return value
+	case json.RawMessage:
+		return []byte(value)
+	case []byte:
+		return value
+	case []string:
+		result, _ := pq.Array(value).Value()
+		return result
+	case []uuid.UUID:
+		result, _ := pq.Array(value).Value()
+		return result
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	}
+	panic(fmt.Sprintf("fakedb: unsupported column type %s", v.Type()))
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+// withUser returns r as if middleWareAuth had let userID through, for
+// handlers whose authentication is not under test.
+func withUser(r *http.Request, userID uuid.UUID) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), principalContextKey{}, auth.Principal{UserID: userID}))
+}
+
+// bearer returns an Authorization header for an access token with the
+// user's role scopes.
+func bearer(t *testing.T, apiCfg *apiConfig, user database.User) http.Header {
+	t.Helper()
+	token, err := apiCfg.keys.MakeJWT(userPrincipal(user), time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error making a token, but got %v", err)
+	}
+	return http.Header{"Authorization": {"Bearer " + token}}
+}
@@ -1,11 +1,13 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"sort"
 	"time"
 
 	"github.com/google/uuid"
@@ -22,19 +24,22 @@ func apiHealthzHandler(w http.ResponseWriter, r *http.Request) {
 func apiPostChirpsHandler(apiCfg *apiConfig) http.HandlerFunc {return func(w http.ResponseWriter, r *http.Request) {
 	type requestData struct {
 		Body string `json:"body"`
+		ParentID *uuid.UUID `json:"parent_id"`
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	userID := principalFromRequest(r).UserID
+
+	user, err := apiCfg.database.GetUserById(r.Context(), userID)
 	if err != nil {
-		respondWithError(w, 401, err.Error())
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error creating chirp")
 		return
 	}
-
-	userID, err := auth.ValidateJWT(token, apiCfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, 401, "Invalid token")
+	if apiCfg.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, 403, "Verify your email address before posting")
 		return
 	}
+	limits := userLimits(apiCfg, user)
 
 	decoder := json.NewDecoder(r.Body)
 	reqData := requestData{}
@@ -45,60 +50,264 @@ func apiPostChirpsHandler(apiCfg *apiConfig) http.HandlerFunc {return func(w htt
 		return
 	}
 
-	if len([]rune(reqData.Body)) > 140 {
-		respondWithError(w, 400, "Your message is too long")
+	moderated, err := cleanChirpBody(apiCfg.moderation, reqData.Body, limits.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	newChirp := database.CreateChirpParams{UserID: userID, Body: moderated.Text}
+	if reqData.ParentID != nil {
+		parent, err := validateChirpParent(r.Context(), apiCfg, userID, *reqData.ParentID)
+		var rejection *chirpRejection
+		if errors.As(err, &rejection) {
+			respondWithError(w, rejection.Code, rejection.Message)
+			return
+		}
+		if err != nil {
+			log.Printf("Error checking parent chirp: %v", err)
+			respondWithError(w, 500, "Error creating chirp")
+			return
+		}
+		newChirp.ParentID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error creating chirp")
 		return
 	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
 
-	body := profanityFilter(reqData.Body)
-	newChirp := database.CreateChirpParams{UserID: userID, Body: body}
-	chirp, err := apiCfg.database.CreateChirp(r.Context(), newChirp)
+	_, err = qtx.GetUserByIdForUpdate(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error locking user: %v", err)
+		respondWithError(w, 500, "Error creating chirp")
+		return
+	}
+	if rejectChirpRate(w, r, qtx, userID, limits) {
+		return
+	}
+
+	chirp, err := insertChirp(r.Context(), qtx, newChirp, moderated)
 	if err != nil {
 		log.Printf("Error creating chirp: %v", err)
 		respondWithError(w, 500, "Error creating chirp")
 		return
 	}
 
-	respondWithJSON(w, 201, ChirpResponse(chirp))
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error creating chirp")
+		return
+	}
+
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, userID, []database.Chirp{chirp})
+	if err != nil {
+		log.Printf("Error building chirp response: %v", err)
+		respondWithError(w, 500, "Error creating chirp")
+		return
+	}
+
+	respondWithJSON(w, 201, chirpResponse[0])
 }}
 
-func apiDeleteChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
-	pathParam := r.PathValue("chirpID")
-	if pathParam == "" {
-		respondWithError(w, 400, "No chirpID provided")
+func apiUpdateChirpHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Body string `json:"body"`
+	}
+
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "ChirpID is invalid")
 		return
 	}
 
-	chirpID, err := uuid.Parse(pathParam)
+	userID := principalFromRequest(r).UserID
+
+	user, err := apiCfg.database.GetUserById(r.Context(), userID)
 	if err != nil {
-		log.Printf("Error parsing UUID: %v", err)
-		respondWithError(w, 500, "ChirpID is invalid")
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
 		return
 	}
-	chirp, err := apiCfg.database.GetChirpById(r.Context(), chirpID)
+	limits := userLimits(apiCfg, user)
+	if !limits.EditChirps {
+		respondWithError(w, 403, "Your plan does not include editing chirps")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqData := requestData{}
+	err = decoder.Decode(&reqData)
+	if err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	moderated, err := cleanChirpBody(apiCfg.moderation, reqData.Body, limits.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	body := moderated.Text
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	chirp, err := qtx.GetChirpByIdForUpdate(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
 		respondWithError(w, 404, "Chirp does not exist")
 		return
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
+	if chirp.UserID != userID {
+		respondWithError(w, 403, "You are not authorized to edit this chirp")
+		return
+	}
+
+	if chirp.Body == body {
+		chirpResponse, err := chirpResponses(r.Context(), apiCfg, userID, []database.Chirp{chirp})
+		if err != nil {
+			log.Printf("Error building chirp response: %v", err)
+			respondWithError(w, 500, "Error updating chirp")
+			return
+		}
+		respondWithJSON(w, 200, chirpResponse[0])
+		return
+	}
+
+	versionCreatedAt := chirp.CreatedAt
+	if chirp.EditedAt.Valid {
+		versionCreatedAt = chirp.EditedAt.Time
+	}
+	revisionParams := database.CreateChirpRevisionParams{ChirpID: chirp.ID, Body: chirp.Body, CreatedAt: versionCreatedAt}
+	_, err = qtx.CreateChirpRevision(r.Context(), revisionParams)
 	if err != nil {
-		respondWithError(w, 401, err.Error())
+		log.Printf("Error creating chirp revision: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	updatedChirp, err := qtx.UpdateChirpBody(r.Context(), database.UpdateChirpBodyParams{ID: chirp.ID, Body: body})
+	if err != nil {
+		log.Printf("Error updating chirp: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	err = saveChirpEntities(r.Context(), qtx, updatedChirp)
+	if err != nil {
+		log.Printf("Error saving chirp entities: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	err = saveModerationFlags(r.Context(), qtx, updatedChirp.ID, moderated)
+	if err != nil {
+		log.Printf("Error saving moderation flags: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
 		return
 	}
 
-	userID, err := auth.ValidateJWT(token, apiCfg.tokenSecret)
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, userID, []database.Chirp{updatedChirp})
 	if err != nil {
-		respondWithError(w, 401, "Invalid token")
+		log.Printf("Error building chirp response: %v", err)
+		respondWithError(w, 500, "Error updating chirp")
+		return
+	}
+
+	respondWithJSON(w, 200, chirpResponse[0])
+}}
+
+func apiGetChirpRevisionsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "ChirpID is invalid")
+		return
+	}
+
+	chirp, err := apiCfg.database.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "No chirp with that ID exists")
 		return
 	}
 
+	rel, err := getRelationship(r.Context(), apiCfg, viewerIDFromRequest(r, apiCfg), chirp.UserID)
+	if err != nil {
+		log.Printf("Error fetching relationship: %v", err)
+		respondWithError(w, 500, "Error fetching chirp revisions")
+		return
+	}
+	if !rel.canView() {
+		respondWithError(w, 404, "No chirp with that ID exists")
+		return
+	}
+
+	revisions, err := apiCfg.database.GetChirpRevisions(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error fetching chirp revisions: %v", err)
+		respondWithError(w, 500, "Error fetching chirp revisions")
+		return
+	}
+
+	revisionResponse := make([]ChirpRevisionResponse, len(revisions))
+	for i, revision := range revisions {
+		revisionResponse[i] = ChirpRevisionResponse{
+			ID: revision.ID,
+			ChirpID: revision.ChirpID,
+			Body: revision.Body,
+			CreatedAt: revision.CreatedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, revisionResponse)
+}}
+
+func apiDeleteChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	pathParam := r.PathValue("chirpID")
+	if pathParam == "" {
+		respondWithError(w, 400, "No chirpID provided")
+		return
+	}
+
+	chirpID, err := uuid.Parse(pathParam)
+	if err != nil {
+		log.Printf("Error parsing UUID: %v", err)
+		respondWithError(w, 500, "ChirpID is invalid")
+		return
+	}
+	chirp, err := apiCfg.database.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp does not exist")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
 	if chirp.UserID != userID {
 		respondWithError(w, 403, "You are not authorized to delete this chirp")
 		return
 	}
 
-	err = apiCfg.database.DeleteChirpById(r.Context(), chirpID)
+	err = deleteChirp(r.Context(), apiCfg, chirpID)
 	if err != nil {
 		log.Printf("Error deleting chirp: %v", err)
 		respondWithError(w, 500, "Error deleting chirp")
@@ -108,8 +317,56 @@ func apiDeleteChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w
 	w.WriteHeader(204)
 }}
 
+// deleteChirp runs removeChirp in its own transaction.
+func deleteChirp(ctx context.Context, apiCfg *apiConfig, chirpID uuid.UUID) error {
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = removeChirp(ctx, apiCfg.database.WithTx(tx), chirpID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// removeChirp removes a chirp, or leaves a tombstone in its place when other
+// chirps reply to it so the thread stays intact. The row lock keeps new replies
+// from being attached while the decision is made.
+func removeChirp(ctx context.Context, qtx *database.Queries, chirpID uuid.UUID) error {
+	_, err := qtx.GetChirpByIdForUpdate(ctx, chirpID)
+	if err != nil {
+		return err
+	}
+
+	hasReplies, err := qtx.ChirpHasReplies(ctx, uuid.NullUUID{UUID: chirpID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	if hasReplies {
+		tombstone, err := qtx.TombstoneChirp(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		err = qtx.DeleteChirpRevisions(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		err = saveChirpEntities(ctx, qtx, tombstone)
+	} else {
+		err = qtx.DeleteChirpById(ctx, chirpID)
+	}
+	return err
+}
+
+// adminResetHandler wipes users, chirps and refresh tokens. Besides the admin
+// scope it needs the dev platform, so it cannot be reached in production.
 func adminResetHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
-	if apiCfg.platform == "" {
+	if apiCfg.platform != "dev" {
 		w.WriteHeader(403)
 		return
 	}
@@ -177,11 +434,15 @@ func apiCreateUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w ht
 		respondWithError(w, 400, "No password was provided")
 		return
 	}
+	if rejectPassword(w, apiCfg, reqData.Password, reqData.Email) {
+		return
+	}
 
 	passHash, err := auth.HashPassword(reqData.Password)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
 		respondWithError(w, 500, "Error hashing password")
+		return
 	}
 
 	createUserParams := database.CreateUserParams{Email: reqData.Email, HashedPassword: passHash}
@@ -192,12 +453,19 @@ func apiCreateUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w ht
 		return
 	}
 
+	err = sendUserToken(r.Context(), apiCfg, user, tokenPurposeEmailVerification)
+	if err != nil {
+		log.Printf("Error sending verification email: %v", err)
+	}
+
 	newUser := UserResponse{
 		ID: user.ID,
 		Email: user.Email,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		IsChirpyRed: user.IsChirpyRed,
+		Role: user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 	respondWithJSON(w, 201, newUser)
 }}
@@ -208,21 +476,11 @@ func apiUpdateUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w ht
 		Password string `json:"password"`
 	}
 
-	token, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, err.Error())
-		return
-	}
-
-	userID, err := auth.ValidateJWT(token, apiCfg.tokenSecret)
-	if err != nil {
-		respondWithError(w, 401, "Invalid token")
-		return
-	}
+	userID := principalFromRequest(r).UserID
 
 	decoder := json.NewDecoder(r.Body)
 	reqData := requestData{}
-	err = decoder.Decode(&reqData)
+	err := decoder.Decode(&reqData)
 	if err != nil {
 		log.Printf("Error decoding request body: %v", err)
 		w.WriteHeader(500)
@@ -237,11 +495,22 @@ func apiUpdateUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w ht
 		respondWithError(w, 400, "No password was provided")
 		return
 	}
+	if rejectPassword(w, apiCfg, reqData.Password, reqData.Email) {
+		return
+	}
 
 	passHash, err := auth.HashPassword(reqData.Password)
 	if err != nil {
 		log.Printf("Error hashing password: %v", err)
 		respondWithError(w, 500, "Error hashing password")
+		return
+	}
+
+	current, err := apiCfg.database.GetUserById(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error updating user")
+		return
 	}
 
 	updateUserParams := database.UpdateUserParams{ID: userID, Email: reqData.Email, HashedPassword: passHash}
@@ -252,56 +521,88 @@ func apiUpdateUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w ht
 		return
 	}
 
+	// A new address starts out unverified and needs a link of its own.
+	if user.Email != current.Email {
+		sendUserTokenInBackground(r.Context(), apiCfg, user, tokenPurposeEmailVerification)
+	}
+
 	updatedUser := UserResponse{
 		ID: user.ID,
 		Email: user.Email,
 		CreatedAt: user.CreatedAt,
 		UpdatedAt: user.UpdatedAt,
 		IsChirpyRed: user.IsChirpyRed,
+		Role: user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
 	}
 	respondWithJSON(w, 200, updatedUser)
 }}
 
 func apiGetAllChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
 	authorID := r.URL.Query().Get("author_id")
-	var chirps []database.Chirp
-	var err error
+	var author uuid.NullUUID
 
-	if authorID == "" {
-		chirps, err = apiCfg.database.GetAllChirps(r.Context())
-		if err != nil {
-			log.Printf("Error fetching chirps: %v", err)
-			respondWithError(w, 500, "Error fetching chirps")
-			return
-		}
-	} else {
+	if authorID != "" {
 		userID, err := uuid.Parse(authorID)
 		if err != nil {
 			respondWithError(w, 400, "Invalid author ID")
 			return
 		}
-		chirps, err = apiCfg.database.GetChirpsByUserId(r.Context(), userID)
-		if err != nil {
-			log.Printf("Error fetching chirps: %v", err)
-			respondWithError(w, 500, "Error fetching chirps")
-			return
-		}
+		author = uuid.NullUUID{UUID: userID, Valid: true}
 	}
 
-	sortQuery := r.URL.Query().Get("sort")
-	if sortQuery == "desc" {
-		sort.Slice(chirps, func(i, j int) bool {
-			return chirps[i].CreatedAt.After(chirps[j].CreatedAt)
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	viewerID := viewerIDFromRequest(r, apiCfg)
+	descending := r.URL.Query().Get("sort") == "desc"
+	err = checkCursorSort(params, descending)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, descending, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetChirpsAscending(ctx, database.GetChirpsAscendingParams{
+				AuthorID: author,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+				ViewerID: nullUUID(viewerID),
+			})
+		}
+		return apiCfg.database.GetChirpsDescending(ctx, database.GetChirpsDescendingParams{
+			AuthorID: author,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+			ViewerID: nullUUID(viewerID),
 		})
+	}, chirpPageKey)
+	if err != nil {
+		log.Printf("Error fetching chirps: %v", err)
+		respondWithError(w, 500, "Error fetching chirps")
+		return
 	}
-	
 
-	chirpResponse := make([]ChirpResponse, len(chirps))
-	for i, chirp := range chirps {
-		chirpResponse[i] = ChirpResponse(chirp)
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, viewerID, page.Items)
+	if err != nil {
+		log.Printf("Error fetching chirp engagement: %v", err)
+		respondWithError(w, 500, "Error fetching chirps")
+		return
 	}
 
-	respondWithJSON(w, 200, chirpResponse)
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, ChirpPageResponse{
+		Chirps: chirpResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
 }}
 
 func apiGetChirpByIdHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
@@ -319,13 +620,101 @@ func apiGetChirpByIdHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w
 	}
 
 	chirp, err := apiCfg.database.GetChirpById(r.Context(), chirpID)
-	if err != nil {
+	if err != nil || chirp.DeletedAt.Valid {
 		log.Printf("Error fetching chirp: %v", err)
 		respondWithError(w, 404, "No chirp with that ID exists")
 		return
 	}
+
+	viewerID := viewerIDFromRequest(r, apiCfg)
+	rel, err := getRelationship(r.Context(), apiCfg, viewerID, chirp.UserID)
+	if err != nil {
+		log.Printf("Error fetching relationship: %v", err)
+		respondWithError(w, 500, "Error fetching chirp")
+		return
+	}
+	if !rel.canView() {
+		respondWithError(w, 404, "No chirp with that ID exists")
+		return
+	}
+
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, viewerID, []database.Chirp{chirp})
+	if err != nil {
+		log.Printf("Error fetching chirp engagement: %v", err)
+		respondWithError(w, 500, "Error fetching chirp")
+		return
+	}
 	
-	respondWithJSON(w, 200, ChirpResponse(chirp))
+	respondWithJSON(w, 200, chirpResponse[0])
+}}
+
+func apiGetChirpThreadHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "ChirpID is invalid")
+		return
+	}
+
+	ancestorRows, err := apiCfg.database.GetChirpAncestors(r.Context(), chirpID)
+	if err != nil {
+		log.Printf("Error fetching chirp ancestors: %v", err)
+		respondWithError(w, 500, "Error fetching thread")
+		return
+	}
+
+	descendantRows, err := apiCfg.database.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{ID: chirpID, Limit: maxThreadSize})
+	if err != nil {
+		log.Printf("Error fetching chirp replies: %v", err)
+		respondWithError(w, 500, "Error fetching thread")
+		return
+	}
+	if len(descendantRows) == 0 {
+		respondWithError(w, 404, "No chirp with that ID exists")
+		return
+	}
+
+	threadChirps := make([]database.Chirp, 0, len(ancestorRows)+len(descendantRows))
+	for _, row := range ancestorRows {
+		threadChirps = append(threadChirps, chirpFromThreadRow(database.GetChirpDescendantsRow(row)))
+	}
+	for _, row := range descendantRows {
+		threadChirps = append(threadChirps, chirpFromThreadRow(row))
+	}
+
+	viewerID := viewerIDFromRequest(r, apiCfg)
+	rel, err := getRelationship(r.Context(), apiCfg, viewerID, descendantRows[0].UserID)
+	if err != nil {
+		log.Printf("Error fetching relationship: %v", err)
+		respondWithError(w, 500, "Error fetching thread")
+		return
+	}
+	if !rel.canView() {
+		respondWithError(w, 404, "No chirp with that ID exists")
+		return
+	}
+
+	threadResponses, err := chirpResponses(r.Context(), apiCfg, viewerID, threadChirps)
+	if err != nil {
+		log.Printf("Error fetching chirp engagement: %v", err)
+		respondWithError(w, 500, "Error fetching thread")
+		return
+	}
+
+	// The requested chirp passed canView above, so it stays readable even if
+	// the viewer blocked its author. Only the rest of the thread is hidden.
+	chirp := threadResponses[len(ancestorRows)]
+	err = hideBlockedChirps(r.Context(), apiCfg, viewerID, threadResponses)
+	if err != nil {
+		log.Printf("Error fetching blocks: %v", err)
+		respondWithError(w, 500, "Error fetching thread")
+		return
+	}
+	threadResponses[len(ancestorRows)] = chirp
+
+	respondWithJSON(w, 200, ChirpThreadResponse{
+		Ancestors: threadResponses[:len(ancestorRows)],
+		Chirp: buildChirpThread(chirpID, threadResponses[len(ancestorRows):]),
+	})
 }}
 
 func apiLoginHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
@@ -348,136 +737,147 @@ func apiLoginHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.Re
 		return
 	}
 
+	account, ip := lockoutSubjects(reqData.Email, clientIP(r, apiCfg.trustedProxyHops))
+	if loginLockedOut(w, r, apiCfg, account, ip) {
+		return
+	}
+
 	user, err := apiCfg.database.GetUserByEmail(r.Context(),reqData.Email)
 	if err != nil {
+		recordLoginFailure(r.Context(), apiCfg, account, ip)
 		respondWithError(w, 401, "Incorrect email or password")
 		return
 	}
 
 	err = auth.CheckPasswordHash(reqData.Password, user.HashedPassword)
 	if err != nil {
+		recordLoginFailure(r.Context(), apiCfg, account, ip)
 		respondWithError(w, 401, "Incorrect email or password")
 		return
 	}
 
-	expiresIn := time.Duration(60 * 60) * time.Second
-	jwt, err := auth.MakeJWT(user.ID, apiCfg.tokenSecret, expiresIn)
-	if err != nil {
-		log.Printf("Error making token: %v", err)
-		w.WriteHeader(500)
+	// Hashes made with bcrypt or older argon2id parameters are upgraded while
+	// the plain password is at hand.
+	if auth.NeedsRehash(user.HashedPassword) {
+		passHash, err := auth.HashPassword(reqData.Password)
+		if err == nil {
+			err = apiCfg.database.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{ID: user.ID, HashedPassword: passHash})
+		}
+		if err != nil {
+			log.Printf("Error upgrading password hash: %v", err)
+		}
 	}
 
-	refresh, err := auth.MakeRefreshToken()
+	respondWithLogin(w, r, apiCfg, user)
+}}
+
+func apiRefreshHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	refresh, err := auth.GetBearerToken(r.Header)
 	if err != nil {
-		log.Printf("Error making refresh token: %v", err)
-		w.WriteHeader(500)
+		respondWithError(w, 401, err.Error())
+		return
 	}
 
-	insertParams := database.InsertRefreshTokenParams{
-		Token: refresh,
-		UserID: user.ID,
-		ExpiresAt: time.Now().Add(60 * 24 * time.Hour),
-	}
-	_, err = apiCfg.database.InsertRefreshToken(r.Context(), insertParams)
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
 	if err != nil {
-		log.Printf("Error inserting refresh token: %v", err)
+		log.Printf("Error starting transaction: %v", err)
 		w.WriteHeader(500)
 		return
 	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
 
-	response := LoginResponse{
-		ID: user.ID,
-		Email: user.Email,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
-		AccessToken: jwt,
-		RefreshToken: refresh,
-		IsChirpyRed: user.IsChirpyRed,
+	refreshEntry, err := qtx.GetRefreshTokenForUpdate(r.Context(), refresh)
+	if err != nil {
+		respondWithError(w, 401, "Invalid or expired token")
+		return
 	}
-	respondWithJSON(w, 200, response)
-}}
 
-func apiRefreshHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
-	refresh, err := auth.GetBearerToken(r.Header)
-	if err != nil {
-		respondWithError(w, 401, err.Error())
+	// A token that was already rotated should never come back. If it does, either
+	// the client or an attacker holds a stolen copy, so the whole family goes.
+	if refreshEntry.ReplacedBy.Valid {
+		revoked, err := qtx.RevokeRefreshTokenFamily(r.Context(), refreshEntry.FamilyID)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Error revoking refresh token family: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		logSecurityEvent("refresh_token_reuse", refreshEntry.UserID, "rotated token presented again, revoked %d tokens in family %s", revoked, refreshEntry.FamilyID)
+		respondWithError(w, 401, "Invalid or expired token")
 		return
 	}
 
-	refreshEntry, err := apiCfg.database.GetUserFromRefreshToken(r.Context(), refresh)
-	if err != nil || refreshEntry.ExpiresAt.Before(time.Now()) || refreshEntry.RevokedAt.Valid  {
+	if refreshEntry.ExpiresAt.Before(time.Now()) || refreshEntry.RevokedAt.Valid {
 		respondWithError(w, 401, "Invalid or expired token")
 		return
 	}
 
-	jwt, err := auth.MakeJWT(refreshEntry.UserID, apiCfg.tokenSecret, time.Duration(60 * 60) * time.Second)
+	user, err := qtx.GetUserById(r.Context(), refreshEntry.UserID)
 	if err != nil {
-		log.Printf("Error making JWT: %v", err)
-		w.WriteHeader(500)
+		respondWithError(w, 401, "Invalid or expired token")
 		return
 	}
-
-	response := RefreshResponse{
-		AccessToken: jwt,
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, "Your account is suspended")
+		return
 	}
-	respondWithJSON(w, 200, response)
-}}
 
-func apiRevokeHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
-	refresh, err := auth.GetBearerToken(r.Header)
+	newRefresh, err := issueRefreshToken(r.Context(), qtx, refreshEntry.UserID, refreshEntry.FamilyID, refreshEntry.ExpiresAt, deviceFromRequest(r, apiCfg))
 	if err != nil {
-		respondWithError(w, 401, err.Error())
+		log.Printf("Error inserting refresh token: %v", err)
+		w.WriteHeader(500)
+		return
 	}
 
-	_, err = apiCfg.database.RevokeRefreshToken(r.Context(), refresh)
+	_, err = qtx.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{Token: refresh, ReplacedBy: sql.NullString{String: newRefresh, Valid: true}})
 	if err != nil {
-		log.Printf("Error revoking refresh token: %v", err)
+		log.Printf("Error rotating refresh token: %v", err)
 		w.WriteHeader(500)
-	}
-	w.WriteHeader(204)
-}}
-
-func apiPolkaWebhooksHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
-	type requestData struct {
-		Event string `json:"event"`
-		Data struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+		return
 	}
 
-	apiKey, err := auth.GetAPIKey(r.Header)
-	if err != nil || apiKey != apiCfg.polkaKey {
-		respondWithError(w, 401, "You are not authorized")
+	jwt, err := apiCfg.keys.MakeJWT(userPrincipal(user), accessTokenTTL)
+	if err != nil {
+		log.Printf("Error making JWT: %v", err)
+		w.WriteHeader(500)
 		return
 	}
 
-	decoder := json.NewDecoder(r.Body)
-	reqData := requestData{}
-	err = decoder.Decode(&reqData)
+	err = tx.Commit()
 	if err != nil {
-		log.Printf("Error decoding request body: %v", err)
+		log.Printf("Error committing transaction: %v", err)
 		w.WriteHeader(500)
 		return
 	}
 
-	if reqData.Event != "user.upgraded" {
-		w.WriteHeader(204)
+	response := RefreshResponse{
+		AccessToken: jwt,
+		RefreshToken: newRefresh,
+	}
+	respondWithJSON(w, 200, response)
+}}
+
+func apiRevokeHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	refresh, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, err.Error())
 		return
 	}
 
-	userID, err := uuid.Parse(reqData.Data.UserID)
+	refreshEntry, err := apiCfg.database.GetUserFromRefreshToken(r.Context(), refresh)
 	if err != nil {
-		log.Printf("Error parsing UUID: %v", err)
-		w.WriteHeader(400)
+		respondWithError(w, 401, "Invalid or expired token")
 		return
 	}
 
-	_, err = apiCfg.database.UpgradeUserToChirpyRed(r.Context(), userID)
+	_, err = apiCfg.database.RevokeRefreshTokenFamily(r.Context(), refreshEntry.FamilyID)
 	if err != nil {
-		log.Printf("Error upgrading user to chirpy red: %v", err)
+		log.Printf("Error revoking refresh token: %v", err)
 		w.WriteHeader(500)
 		return
 	}
-
 	w.WriteHeader(204)
 }}
\ No newline at end of file
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const maxAccessTokenNameLength = 100
+
+func newPersonalAccessTokenResponse(token database.PersonalAccessToken) PersonalAccessTokenResponse {
+	response := PersonalAccessTokenResponse{
+		ID: token.ID,
+		Name: token.Name,
+		Scopes: token.Scopes,
+		CreatedAt: token.CreatedAt,
+	}
+	if token.ExpiresAt.Valid {
+		response.ExpiresAt = &token.ExpiresAt.Time
+	}
+	if token.LastUsedAt.Valid {
+		response.LastUsedAt = &token.LastUsedAt.Time
+	}
+	return response
+}
+
+func apiCreateAccessTokenHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Name string `json:"name"`
+		Scopes []string `json:"scopes"`
+		ExpiresInDays int `json:"expires_in_days"`
+	}
+
+	principal := principalFromRequest(r)
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+	if reqData.Name == "" || len([]rune(reqData.Name)) > maxAccessTokenNameLength {
+		respondWithError(w, 400, fmt.Sprintf("Name must be between 1 and %d characters long", maxAccessTokenNameLength))
+		return
+	}
+	if reqData.ExpiresInDays < 0 {
+		respondWithError(w, 400, "Expiry must not be negative")
+		return
+	}
+
+	scopes, err := accessTokenScopes(reqData.Scopes, principal)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	token, err := auth.MakePersonalAccessToken()
+	if err != nil {
+		log.Printf("Error generating personal access token: %v", err)
+		respondWithError(w, 500, "Error creating token")
+		return
+	}
+
+	var expiresAt sql.NullTime
+	if reqData.ExpiresInDays > 0 {
+		expiresAt = sql.NullTime{Time: time.Now().AddDate(0, 0, reqData.ExpiresInDays), Valid: true}
+	}
+
+	accessToken, err := apiCfg.database.CreatePersonalAccessToken(r.Context(), database.CreatePersonalAccessTokenParams{
+		UserID: principal.UserID,
+		Name: reqData.Name,
+		TokenHash: auth.HashToken(token),
+		Scopes: scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("Error creating personal access token: %v", err)
+		respondWithError(w, 500, "Error creating token")
+		return
+	}
+
+	logSecurityEvent("personal_access_token_created", principal.UserID, "token %s with scopes %v", accessToken.ID, scopes)
+
+	// The token itself is only ever returned here; afterwards only its hash is
+	// known.
+	response := newPersonalAccessTokenResponse(accessToken)
+	response.Token = token
+	respondWithJSON(w, 201, response)
+}}
+
+func apiGetAccessTokensHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	tokens, err := apiCfg.database.GetPersonalAccessTokens(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching personal access tokens: %v", err)
+		respondWithError(w, 500, "Error fetching tokens")
+		return
+	}
+
+	response := make([]PersonalAccessTokenResponse, len(tokens))
+	for i, token := range tokens {
+		response[i] = newPersonalAccessTokenResponse(token)
+	}
+
+	respondWithJSON(w, 200, response)
+}}
+
+func apiRevokeAccessTokenHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	tokenID, err := uuid.Parse(r.PathValue("tokenID"))
+	if err != nil {
+		respondWithError(w, 400, "TokenID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	revoked, err := apiCfg.database.RevokePersonalAccessToken(r.Context(), database.RevokePersonalAccessTokenParams{ID: tokenID, UserID: userID})
+	if err != nil {
+		log.Printf("Error revoking personal access token: %v", err)
+		respondWithError(w, 500, "Error revoking token")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Token does not exist")
+		return
+	}
+
+	logSecurityEvent("personal_access_token_revoked", userID, "token %s", tokenID)
+	w.WriteHeader(204)
+}}
+
+// accessTokenScopes validates the scopes requested for a new token. A token
+// can only be given scopes the caller holds itself, so a token cannot be used
+// to mint a more powerful one.
+func accessTokenScopes(requested []string, principal auth.Principal) ([]string, error) {
+	if len(requested) == 0 {
+		return nil, errors.New("At least one scope is required")
+	}
+
+	var scopes []string
+	for _, scope := range requested {
+		if !auth.ValidScope(scope) {
+			return nil, fmt.Errorf("Unknown scope %q", scope)
+		}
+		if !principal.HasScope(scope) {
+			return nil, fmt.Errorf("You cannot grant the %s scope", scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// personalAccessTokenPrincipal looks up a personal access token and returns
+// the principal it acts as. The role is filled in by middleWareAuth.
+func personalAccessTokenPrincipal(ctx context.Context, apiCfg *apiConfig, token string) (auth.Principal, error) {
+	accessToken, err := apiCfg.database.GetPersonalAccessTokenByHash(ctx, auth.HashToken(token))
+	if err != nil {
+		return auth.Principal{}, err
+	}
+	if accessToken.RevokedAt.Valid {
+		return auth.Principal{}, errors.New("token has been revoked")
+	}
+	if accessToken.ExpiresAt.Valid && accessToken.ExpiresAt.Time.Before(time.Now()) {
+		return auth.Principal{}, errors.New("token has expired")
+	}
+
+	err = apiCfg.database.TouchPersonalAccessToken(ctx, accessToken.ID)
+	if err != nil {
+		log.Printf("Error updating personal access token last use: %v", err)
+	}
+
+	return auth.Principal{UserID: accessToken.UserID, Scopes: accessToken.Scopes}, nil
+}
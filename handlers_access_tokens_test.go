@@ -0,0 +1,37 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/isotronic/http-go-server/internal/auth"
+)
+
+func TestAccessTokenScopes(t *testing.T) {
+	user := auth.Principal{Role: auth.RoleUser, Scopes: auth.ScopesForRole(auth.RoleUser)}
+	bot := auth.Principal{Role: auth.RoleUser, Scopes: []string{auth.ScopeWrite}}
+
+	tests := []struct {
+		requested []string
+		principal auth.Principal
+		expected []string
+		valid bool
+	}{
+		{[]string{"write", "read", "write"}, user, []string{"write", "read"}, true},
+		{[]string{"account"}, user, []string{"account"}, true},
+		{nil, user, nil, false},
+		{[]string{"delete"}, user, nil, false},
+		{[]string{"admin"}, user, nil, false},
+		{[]string{"read"}, bot, nil, false},
+	}
+
+	for _, test := range tests {
+		scopes, err := accessTokenScopes(test.requested, test.principal)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected valid=%v for %v, but got %v", test.valid, test.requested, err)
+		}
+		if !slices.Equal(scopes, test.expected) {
+			t.Errorf("Expected %v, but got %v", test.expected, scopes)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/mailer"
+)
+
+const (
+	tokenPurposePasswordReset = "password_reset"
+	tokenPurposeEmailVerification = "email_verification"
+
+	passwordResetTTL = time.Hour
+	emailVerificationTTL = 48 * time.Hour
+
+	mailSendTimeout = 30 * time.Second
+)
+
+var errInvalidUserToken = errors.New("invalid or expired token")
+
+func apiRequestPasswordResetHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Email string `json:"email"`
+	}
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil || reqData.Email == "" {
+		respondWithError(w, 400, "No email was provided")
+		return
+	}
+
+	account, ip := lockoutSubjects(reqData.Email, clientIP(r, apiCfg.trustedProxyHops))
+	if passwordResetLimited(w, r, apiCfg, account, ip) {
+		return
+	}
+	recordPasswordResetRequest(r.Context(), apiCfg, account, ip)
+
+	// The response is the same whether or not the account exists, so the
+	// endpoint cannot be used to find out which emails are registered. The
+	// mail is sent after responding so the timing does not tell either.
+	user, err := apiCfg.database.GetUserByEmail(r.Context(), reqData.Email)
+	if err == nil {
+		sendUserTokenInBackground(r.Context(), apiCfg, user, tokenPurposePasswordReset)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error fetching user: %v", err)
+	}
+
+	w.WriteHeader(202)
+}}
+
+func apiConfirmPasswordResetHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Token string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+	if reqData.Password == "" {
+		respondWithError(w, 400, "No password was provided")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	token, err := consumeUserToken(r.Context(), qtx, reqData.Token, tokenPurposePasswordReset)
+	if errors.Is(err, errInvalidUserToken) {
+		respondWithError(w, 400, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error using password reset token: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	user, err := qtx.GetUserById(r.Context(), token.UserID)
+	if err != nil || user.Email != token.Email {
+		respondWithError(w, 400, "Invalid or expired token")
+		return
+	}
+
+	if rejectPassword(w, apiCfg, reqData.Password, user.Email) {
+		return
+	}
+
+	passHash, err := auth.HashPassword(reqData.Password)
+	if err != nil {
+		log.Printf("Error hashing password: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	err = qtx.UpdateUserPassword(r.Context(), database.UpdateUserPasswordParams{ID: user.ID, HashedPassword: passHash})
+	if err != nil {
+		log.Printf("Error updating password: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	err = qtx.InvalidateUserTokens(r.Context(), database.InvalidateUserTokensParams{UserID: user.ID, Purpose: tokenPurposePasswordReset})
+	if err != nil {
+		log.Printf("Error invalidating password reset tokens: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	account, _ := lockoutSubjects(user.Email, "")
+	_, err = qtx.ClearLoginFailures(r.Context(), database.ClearLoginFailuresParams{Kind: lockoutKindAccount, Subject: account})
+	if err != nil {
+		log.Printf("Error clearing login failures: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	// Whoever knew the old password may still hold a session.
+	revoked, err := qtx.RevokeAllUserRefreshTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	// Personal access tokens outlive sessions, so they go too.
+	revokedTokens, err := qtx.RevokeAllUserPersonalAccessTokens(r.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error revoking personal access tokens: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error resetting password")
+		return
+	}
+
+	logSecurityEvent("password_reset", user.ID, "revoked %d refresh tokens and %d personal access tokens", revoked, revokedTokens)
+	w.WriteHeader(204)
+}}
+
+func apiRequestEmailVerificationHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	user, err := apiCfg.database.GetUserById(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error sending verification email")
+		return
+	}
+	if user.EmailVerifiedAt.Valid {
+		respondWithError(w, 409, "Email address is already verified")
+		return
+	}
+
+	err = sendUserToken(r.Context(), apiCfg, user, tokenPurposeEmailVerification)
+	if err != nil {
+		log.Printf("Error sending verification email: %v", err)
+		respondWithError(w, 500, "Error sending verification email")
+		return
+	}
+
+	w.WriteHeader(202)
+}}
+
+func apiConfirmEmailVerificationHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Token string `json:"token"`
+	}
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error verifying email")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	token, err := consumeUserToken(r.Context(), qtx, reqData.Token, tokenPurposeEmailVerification)
+	if errors.Is(err, errInvalidUserToken) {
+		respondWithError(w, 400, "Invalid or expired token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error using verification token: %v", err)
+		respondWithError(w, 500, "Error verifying email")
+		return
+	}
+
+	// The token only verifies the address it was sent to; if the user has
+	// changed their email since, it no longer matches.
+	verified, err := qtx.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{ID: token.UserID, Email: token.Email})
+	if err != nil {
+		log.Printf("Error verifying email: %v", err)
+		respondWithError(w, 500, "Error verifying email")
+		return
+	}
+	if verified == 0 {
+		respondWithError(w, 400, "Invalid or expired token")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error verifying email")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+// sendUserToken replaces any unused token of the given purpose with a new one
+// and mails its link to the user. Only the hash of the token is stored.
+func sendUserToken(ctx context.Context, apiCfg *apiConfig, user database.User, purpose string) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := emailVerificationTTL
+	path := "/verify-email"
+	subject := "Verify your Chirpy email address"
+	body := "Open this link to verify your email address:\n\n%s\n\nThe link expires in 48 hours."
+	if purpose == tokenPurposePasswordReset {
+		ttl = passwordResetTTL
+		path = "/reset-password"
+		subject = "Reset your Chirpy password"
+		body = "Open this link to choose a new password:\n\n%s\n\nThe link expires in one hour. If you did not ask for a password reset, you can ignore this email."
+	}
+
+	err = apiCfg.database.InvalidateUserTokens(ctx, database.InvalidateUserTokensParams{UserID: user.ID, Purpose: purpose})
+	if err != nil {
+		return err
+	}
+	err = apiCfg.database.CreateUserToken(ctx, database.CreateUserTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID: user.ID,
+		Purpose: purpose,
+		Email: user.Email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	link := apiCfg.baseURL + path + "?token=" + url.QueryEscape(token)
+	return apiCfg.mailer.Send(ctx, mailer.Message{
+		To: user.Email,
+		Subject: subject,
+		Body: fmt.Sprintf(body, link),
+	})
+}
+
+// sendUserTokenInBackground sends a token without keeping the request
+// waiting for the mail server.
+func sendUserTokenInBackground(ctx context.Context, apiCfg *apiConfig, user database.User, purpose string) {
+	ctx = context.WithoutCancel(ctx)
+	apiCfg.background.Add(1)
+	go func() {
+		defer apiCfg.background.Done()
+		ctx, cancel := context.WithTimeout(ctx, mailSendTimeout)
+		defer cancel()
+
+		err := sendUserToken(ctx, apiCfg, user, purpose)
+		if err != nil {
+			log.Printf("Error sending %s email: %v", purpose, err)
+		}
+	}()
+}
+
+// consumeUserToken looks up a token for the given purpose, checks that it is
+// unused and not expired, and marks it used. It returns errInvalidUserToken
+// for any token that cannot be used.
+func consumeUserToken(ctx context.Context, q *database.Queries, token, purpose string) (database.UserToken, error) {
+	if token == "" {
+		return database.UserToken{}, errInvalidUserToken
+	}
+
+	userToken, err := q.GetUserTokenForUpdate(ctx, auth.HashToken(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.UserToken{}, errInvalidUserToken
+	}
+	if err != nil {
+		return database.UserToken{}, err
+	}
+	if userToken.Purpose != purpose || userToken.UsedAt.Valid || userToken.ExpiresAt.Before(time.Now()) {
+		return database.UserToken{}, errInvalidUserToken
+	}
+
+	err = q.UseUserToken(ctx, userToken.TokenHash)
+	if err != nil {
+		return database.UserToken{}, err
+	}
+	return userToken, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/mailer"
+)
+
+func TestRequestPasswordReset(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	var mail bytes.Buffer
+	apiCfg.mailer = mailer.NewLogMailer(&mail, "chirpy@localhost")
+	user := database.User{ID: uuid.New(), Email: "user@example.com", Role: auth.RoleUser}
+	failures := fakeLoginFailures(db)
+	db.on("GetUserByEmail", func(args []driver.Value) (any, error) {
+		if args[0] != user.Email {
+			return nil, nil
+		}
+		return user, nil
+	})
+	db.returns("InvalidateUserTokens", nil)
+	db.returns("CreateUserToken", nil)
+
+	send := func(email string) int {
+		r := httptest.NewRequest("POST", "/api/password_reset", strings.NewReader(`{"email": "`+email+`"}`))
+		w := httptest.NewRecorder()
+		apiRequestPasswordResetHandler(apiCfg)(w, r)
+		apiCfg.background.Wait()
+		return w.Code
+	}
+
+	if code := send("nobody@example.com"); code != 202 {
+		t.Errorf("Expected status 202 for an unknown email, but got %d", code)
+	}
+	if mail.Len() != 0 {
+		t.Errorf("Expected no mail for an unknown email, but got %q", mail.String())
+	}
+
+	if code := send(user.Email); code != 202 {
+		t.Errorf("Expected status 202, but got %d", code)
+	}
+	if !strings.Contains(mail.String(), "/reset-password?token=") {
+		t.Errorf("Expected a reset link to be sent, but got %q", mail.String())
+	}
+
+	for range resetAccountLimit.Threshold - 1 {
+		send(user.Email)
+	}
+	if code := send(user.Email); code != 429 {
+		t.Errorf("Expected status 429 once the limit is reached, but got %d", code)
+	}
+	if len(db.calls("CreateUserToken")) != int(resetAccountLimit.Threshold) {
+		t.Errorf("Expected %d links to be sent, but got %d", resetAccountLimit.Threshold, len(db.calls("CreateUserToken")))
+	}
+	if failure := failures[lockoutKindResetIP+" 192.0.2.1"]; failure == nil {
+		t.Errorf("Expected requests to be counted for the IP, but got %v", failures)
+	}
+}
+
+func TestUpdateUserEmailSendsVerification(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		expected bool
+	}{
+		{"same email", "user@example.com", false},
+		{"new email", "new@example.com", true},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		var mail bytes.Buffer
+		apiCfg.mailer = mailer.NewLogMailer(&mail, "chirpy@localhost")
+		user := database.User{ID: uuid.New(), Email: "user@example.com", Role: auth.RoleUser}
+		db.returns("GetUserById", user)
+		db.on("UpdateUser", func(args []driver.Value) (any, error) {
+			updated := user
+			updated.Email = args[1].(string)
+			return updated, nil
+		})
+		db.returns("InvalidateUserTokens", nil)
+		db.returns("CreateUserToken", nil)
+
+		r := httptest.NewRequest("PUT", "/api/users", strings.NewReader(`{"email": "`+test.email+`", "password": "correct horse battery staple"}`))
+		r.Header = bearer(t, apiCfg, user)
+		w := httptest.NewRecorder()
+		apiCfg.middleWareAuth(apiUpdateUserHandler(apiCfg), auth.ScopeAccount).ServeHTTP(w, r)
+		apiCfg.background.Wait()
+
+		if w.Code != 200 {
+			t.Fatalf("%s: expected status 200, but got %d", test.name, w.Code)
+		}
+		sent := strings.Contains(mail.String(), "/verify-email?token=")
+		if sent != test.expected {
+			t.Errorf("%s: expected a verification link to be sent: %v, but got %v", test.name, test.expected, sent)
+		}
+		if sent && !strings.Contains(mail.String(), "To: "+test.email) {
+			t.Errorf("%s: expected the link to go to the new address, but got %q", test.name, mail.String())
+		}
+	}
+}
+
+func TestConfirmPasswordResetRevokesTokens(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Email: "user@example.com", Role: auth.RoleUser}
+	db.returns("GetUserTokenForUpdate", database.UserToken{UserID: user.ID, Purpose: tokenPurposePasswordReset, Email: user.Email, ExpiresAt: time.Now().Add(time.Hour)})
+	db.returns("UseUserToken", nil)
+	db.returns("GetUserById", user)
+	db.returns("UpdateUserPassword", nil)
+	db.returns("InvalidateUserTokens", nil)
+	db.returns("ClearLoginFailures", int64(0))
+	db.returns("RevokeAllUserRefreshTokens", int64(2))
+	db.returns("RevokeAllUserPersonalAccessTokens", int64(1))
+
+	r := httptest.NewRequest("POST", "/api/password_reset/confirm", strings.NewReader(`{"token": "token", "password": "correct horse battery staple"}`))
+	w := httptest.NewRecorder()
+	apiConfirmPasswordResetHandler(apiCfg)(w, r)
+
+	if w.Code != 204 {
+		t.Fatalf("Expected status 204, but got %d: %s", w.Code, w.Body.String())
+	}
+	calls := db.calls("RevokeAllUserPersonalAccessTokens")
+	if len(calls) != 1 || calls[0][0] != user.ID.String() {
+		t.Errorf("Expected the user's personal access tokens to be revoked, but got %v", calls)
+	}
+	if db.commits != 1 {
+		t.Errorf("Expected the reset to be committed, but got %d commits", db.commits)
+	}
+}
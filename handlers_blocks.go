@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+type userRelationAction func(ctx context.Context, userID, targetID uuid.UUID) error
+
+func apiBlockUserHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return userRelationHandler(apiCfg, "blocking user", true, func(ctx context.Context, userID, targetID uuid.UUID) error {
+		tx, err := apiCfg.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+		qtx := apiCfg.database.WithTx(tx)
+
+		err = qtx.BlockUser(ctx, database.BlockUserParams{BlockerID: userID, BlockedID: targetID})
+		if err != nil {
+			return err
+		}
+		err = qtx.DeleteFollowsBetween(ctx, database.DeleteFollowsBetweenParams{FollowerID: userID, FolloweeID: targetID})
+		if err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func apiUnblockUserHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return userRelationHandler(apiCfg, "unblocking user", false, func(ctx context.Context, userID, targetID uuid.UUID) error {
+		return apiCfg.database.UnblockUser(ctx, database.UnblockUserParams{BlockerID: userID, BlockedID: targetID})
+	})
+}
+
+func apiMuteUserHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return userRelationHandler(apiCfg, "muting user", true, func(ctx context.Context, userID, targetID uuid.UUID) error {
+		return apiCfg.database.MuteUser(ctx, database.MuteUserParams{MuterID: userID, MutedID: targetID})
+	})
+}
+
+func apiUnmuteUserHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return userRelationHandler(apiCfg, "unmuting user", false, func(ctx context.Context, userID, targetID uuid.UUID) error {
+		return apiCfg.database.UnmuteUser(ctx, database.UnmuteUserParams{MuterID: userID, MutedID: targetID})
+	})
+}
+
+// userRelationHandler runs action for the authenticated user against the user
+// in the path. When adding, the target has to exist and differ from the caller.
+func userRelationHandler(apiCfg *apiConfig, description string, adding bool, action userRelationAction) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	if adding {
+		if targetID == userID {
+			respondWithError(w, 400, "You cannot do this to yourself")
+			return
+		}
+
+		_, err = apiCfg.database.GetUserById(r.Context(), targetID)
+		if err != nil {
+			respondWithError(w, 404, "User does not exist")
+			return
+		}
+	}
+
+	err = action(r.Context(), userID, targetID)
+	if err != nil {
+		log.Printf("Error %s: %v", description, err)
+		respondWithError(w, 500, "Error "+description)
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+func apiGetBlocksHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.UserBlock, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetBlocksAscending(ctx, database.GetBlocksAscendingParams{
+				UserID: userID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+			})
+		}
+		return apiCfg.database.GetBlocksDescending(ctx, database.GetBlocksDescendingParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+		})
+	}, func(block database.UserBlock) (time.Time, uuid.UUID) {
+		return block.CreatedAt, block.BlockedID
+	})
+	if err != nil {
+		log.Printf("Error fetching blocked users: %v", err)
+		respondWithError(w, 500, "Error fetching blocked users")
+		return
+	}
+
+	relationResponse := make([]UserRelationResponse, len(page.Items))
+	for i, block := range page.Items {
+		relationResponse[i] = UserRelationResponse{UserID: block.BlockedID, CreatedAt: block.CreatedAt}
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, UserRelationPageResponse{
+		Users: relationResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
+
+func apiGetMutesHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.UserMute, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetMutesAscending(ctx, database.GetMutesAscendingParams{
+				UserID: userID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+			})
+		}
+		return apiCfg.database.GetMutesDescending(ctx, database.GetMutesDescendingParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+		})
+	}, func(mute database.UserMute) (time.Time, uuid.UUID) {
+		return mute.CreatedAt, mute.MutedID
+	})
+	if err != nil {
+		log.Printf("Error fetching muted users: %v", err)
+		respondWithError(w, 500, "Error fetching muted users")
+		return
+	}
+
+	relationResponse := make([]UserRelationResponse, len(page.Items))
+	for i, mute := range page.Items {
+		relationResponse[i] = UserRelationResponse{UserID: mute.MutedID, CreatedAt: mute.CreatedAt}
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, UserRelationPageResponse{
+		Users: relationResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+type engagementAction func(ctx context.Context, userID, chirpID uuid.UUID) error
+
+func apiLikeChirpHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return chirpEngagementHandler(apiCfg, "liking chirp", true, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		_, err := apiCfg.database.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+		return err
+	})
+}
+
+func apiUnlikeChirpHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return chirpEngagementHandler(apiCfg, "unliking chirp", false, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		_, err := apiCfg.database.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+		return err
+	})
+}
+
+func apiRechirpHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return chirpEngagementHandler(apiCfg, "rechirping chirp", true, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		_, err := apiCfg.database.Rechirp(ctx, database.RechirpParams{UserID: userID, ChirpID: chirpID})
+		return err
+	})
+}
+
+func apiUndoRechirpHandler(apiCfg *apiConfig) http.HandlerFunc {
+	return chirpEngagementHandler(apiCfg, "undoing rechirp", false, func(ctx context.Context, userID, chirpID uuid.UUID) error {
+		_, err := apiCfg.database.UndoRechirp(ctx, database.UndoRechirpParams{UserID: userID, ChirpID: chirpID})
+		return err
+	})
+}
+
+// chirpEngagementHandler runs action for the authenticated user on the chirp in
+// the path. Adding engagement is refused with a 403 when a block separates the
+// user and the author, in either direction; removing it is always allowed.
+func chirpEngagementHandler(apiCfg *apiConfig, description string, adding bool, action engagementAction) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "ChirpID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	chirp, err := apiCfg.database.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp does not exist")
+		return
+	}
+
+	if adding {
+		rel, err := getRelationship(r.Context(), apiCfg, userID, chirp.UserID)
+		if err != nil {
+			log.Printf("Error fetching relationship: %v", err)
+			respondWithError(w, 500, "Error "+description)
+			return
+		}
+		if !rel.canInteract() {
+			respondWithError(w, 403, "You cannot interact with this user")
+			return
+		}
+	}
+
+	err = action(r.Context(), userID, chirpID)
+	if err != nil {
+		log.Printf("Error %s: %v", description, err)
+		respondWithError(w, 500, "Error "+description)
+		return
+	}
+
+	w.WriteHeader(204)
+}}
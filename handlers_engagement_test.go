@@ -0,0 +1,195 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+// fakeEngagement keeps likes, rechirps and the chirp's counters of a fakeDB in
+// memory, changing a counter only when a row was added or removed, as the
+// queries do.
+func fakeEngagement(db *fakeDB, chirp *database.Chirp) {
+	likes, rechirps := map[string]bool{}, map[string]bool{}
+	change := func(rows map[string]bool, counter *int32, adding bool) func(args []driver.Value) (any, error) {
+		return func(args []driver.Value) (any, error) {
+			key := args[0].(string)
+			if rows[key] == adding {
+				return int64(0), nil
+			}
+			rows[key] = adding
+			if adding {
+				*counter++
+			} else {
+				*counter--
+			}
+			return int64(1), nil
+		}
+	}
+	db.on("LikeChirp", change(likes, &chirp.LikeCount, true))
+	db.on("UnlikeChirp", change(likes, &chirp.LikeCount, false))
+	db.on("Rechirp", change(rechirps, &chirp.RechirpCount, true))
+	db.on("UndoRechirp", change(rechirps, &chirp.RechirpCount, false))
+	db.on("GetChirpById", func(args []driver.Value) (any, error) { return *chirp, nil })
+	db.on("GetViewerEngagement", func(args []driver.Value) (any, error) {
+		viewer := args[0].(string)
+		return []database.GetViewerEngagementRow{{ChirpID: chirp.ID, Liked: likes[viewer], Rechirped: rechirps[viewer]}}, nil
+	})
+	db.returns("GetChirpMentions", nil)
+}
+
+func TestChirpEngagement(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	author := database.User{ID: uuid.New()}
+	fan := database.User{ID: uuid.New()}
+	chirp := &database.Chirp{ID: uuid.New(), UserID: author.ID, Body: "hello"}
+	fakeEngagement(db, chirp)
+	db.returns("GetUserById", fan)
+	db.returns("GetUserRelationship", database.GetUserRelationshipRow{})
+
+	engage := func(handler func(*apiConfig) http.HandlerFunc, method string) {
+		t.Helper()
+		r := withUser(httptest.NewRequest(method, "/", nil), fan.ID)
+		r.SetPathValue("chirpID", chirp.ID.String())
+		w := httptest.NewRecorder()
+		handler(apiCfg)(w, r)
+		if w.Code != 204 {
+			t.Errorf("Expected status 204, but got %d: %s", w.Code, w.Body.String())
+		}
+	}
+	view := func() ChirpResponse {
+		t.Helper()
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetPathValue("chirpID", chirp.ID.String())
+		r.Header = bearer(t, apiCfg, fan)
+		w := httptest.NewRecorder()
+		apiGetChirpByIdHandler(apiCfg)(w, r)
+		var response ChirpResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return response
+	}
+
+	engage(apiUnlikeChirpHandler, "DELETE")
+	engage(apiUndoRechirpHandler, "DELETE")
+	if chirp.LikeCount != 0 || chirp.RechirpCount != 0 {
+		t.Errorf("Expected undoing engagement that was never added to leave the counts at 0, but got %d and %d", chirp.LikeCount, chirp.RechirpCount)
+	}
+
+	engage(apiLikeChirpHandler, "POST")
+	engage(apiLikeChirpHandler, "POST")
+	engage(apiRechirpHandler, "POST")
+	response := view()
+	if response.LikeCount != 1 || response.RechirpCount != 1 {
+		t.Errorf("Expected a duplicate like to count once, but got %d likes and %d rechirps", response.LikeCount, response.RechirpCount)
+	}
+	if !response.Liked || !response.Rechirped {
+		t.Errorf("Expected the viewer's like and rechirp to be marked")
+	}
+
+	engage(apiUnlikeChirpHandler, "DELETE")
+	engage(apiUnlikeChirpHandler, "DELETE")
+	response = view()
+	if response.LikeCount != 0 || response.Liked {
+		t.Errorf("Expected the like to be removed once, but got %d likes", response.LikeCount)
+	}
+}
+
+func TestChirpEngagementRefusedAcrossBlock(t *testing.T) {
+	chirp := &database.Chirp{ID: uuid.New(), UserID: uuid.New(), Body: "hello"}
+
+	tests := []struct {
+		name     string
+		rel      database.GetUserRelationshipRow
+		handler  func(*apiConfig) http.HandlerFunc
+		expected int
+	}{
+		{"like by a blocked user", database.GetUserRelationshipRow{BlockedByAuthor: true}, apiLikeChirpHandler, 403},
+		{"rechirp of a blocked user", database.GetUserRelationshipRow{BlocksAuthor: true}, apiRechirpHandler, 403},
+		{"unlike by a blocked user", database.GetUserRelationshipRow{BlockedByAuthor: true}, apiUnlikeChirpHandler, 204},
+		{"like of a muted user", database.GetUserRelationshipRow{MutesAuthor: true}, apiLikeChirpHandler, 204},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		fakeEngagement(db, chirp)
+		db.returns("GetUserRelationship", test.rel)
+
+		r := withUser(httptest.NewRequest("POST", "/", nil), uuid.New())
+		r.SetPathValue("chirpID", chirp.ID.String())
+		w := httptest.NewRecorder()
+		test.handler(apiCfg)(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d", test.name, test.expected, w.Code)
+		}
+		if test.expected == 403 && len(db.calls("LikeChirp"))+len(db.calls("Rechirp")) != 0 {
+			t.Errorf("%s: expected nothing to be recorded", test.name)
+		}
+	}
+}
+
+// TestEngagementCounterQueries checks the counters the fake above stands in
+// for, including under concurrent likes and when a user is deleted.
+func TestEngagementCounterQueries(t *testing.T) {
+	db, q := newTestDatabase(t)
+	ctx := t.Context()
+	author := createTestUser(t, q, "author@example.com")
+	chirp := createTestChirp(t, q, author.ID, "hello")
+
+	fans := make([]database.User, 10)
+	for i := range fans {
+		fans[i] = createTestUser(t, q, uuid.NewString()+"@example.com")
+	}
+
+	var wg sync.WaitGroup
+	for _, fan := range fans {
+		for range 2 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := q.LikeChirp(ctx, database.LikeChirpParams{UserID: fan.ID, ChirpID: chirp.ID})
+				if err != nil {
+					t.Errorf("Expected no error liking, but got %v", err)
+				}
+			}()
+		}
+	}
+	wg.Wait()
+
+	_, err := q.Rechirp(ctx, database.RechirpParams{UserID: fans[0].ID, ChirpID: chirp.ID})
+	if err != nil {
+		t.Fatalf("Expected no error rechirping, but got %v", err)
+	}
+	unliked, err := q.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: author.ID, ChirpID: chirp.ID})
+	if err != nil || unliked != 0 {
+		t.Errorf("Expected unliking a chirp that was not liked to change nothing, but got %d, %v", unliked, err)
+	}
+
+	counts := func() (int32, int32) {
+		t.Helper()
+		updated, err := q.GetChirpById(ctx, chirp.ID)
+		if err != nil {
+			t.Fatalf("Expected no error fetching the chirp, but got %v", err)
+		}
+		return updated.LikeCount, updated.RechirpCount
+	}
+	likeCount, rechirpCount := counts()
+	if likeCount != 10 || rechirpCount != 1 {
+		t.Errorf("Expected 10 likes and 1 rechirp, but got %d and %d", likeCount, rechirpCount)
+	}
+
+	_, err = db.ExecContext(ctx, "DELETE FROM users WHERE id = $1", fans[0].ID)
+	if err != nil {
+		t.Fatalf("Expected no error deleting a user, but got %v", err)
+	}
+	likeCount, rechirpCount = counts()
+	if likeCount != 9 || rechirpCount != 0 {
+		t.Errorf("Expected the deleted user's engagement to be released, but got %d likes and %d rechirps", likeCount, rechirpCount)
+	}
+}
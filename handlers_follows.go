@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func apiFollowUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	if followeeID == userID {
+		respondWithError(w, 400, "You cannot follow yourself")
+		return
+	}
+
+	_, err = apiCfg.database.GetUserById(r.Context(), followeeID)
+	if err != nil {
+		respondWithError(w, 404, "User does not exist")
+		return
+	}
+
+	rel, err := getRelationship(r.Context(), apiCfg, userID, followeeID)
+	if err != nil {
+		log.Printf("Error fetching relationship: %v", err)
+		respondWithError(w, 500, "Error following user")
+		return
+	}
+	if !rel.canInteract() {
+		respondWithError(w, 403, "You cannot follow this user")
+		return
+	}
+
+	err = apiCfg.database.FollowUser(r.Context(), database.FollowUserParams{FollowerID: userID, FolloweeID: followeeID})
+	if err != nil {
+		log.Printf("Error following user: %v", err)
+		respondWithError(w, 500, "Error following user")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+func apiUnfollowUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	followeeID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	err = apiCfg.database.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: userID, FolloweeID: followeeID})
+	if err != nil {
+		log.Printf("Error unfollowing user: %v", err)
+		respondWithError(w, 500, "Error unfollowing user")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+func apiGetFollowersHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.Follow, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetFollowersAscending(ctx, database.GetFollowersAscendingParams{
+				UserID: userID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+			})
+		}
+		return apiCfg.database.GetFollowersDescending(ctx, database.GetFollowersDescendingParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+		})
+	}, func(follow database.Follow) (time.Time, uuid.UUID) {
+		return follow.CreatedAt, follow.FollowerID
+	})
+	if err != nil {
+		log.Printf("Error fetching followers: %v", err)
+		respondWithError(w, 500, "Error fetching followers")
+		return
+	}
+
+	followResponse := make([]FollowResponse, len(page.Items))
+	for i, follow := range page.Items {
+		followResponse[i] = FollowResponse{UserID: follow.FollowerID, FollowedAt: follow.CreatedAt}
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, FollowPageResponse{
+		Users: followResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
+
+func apiGetFollowingHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.Follow, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetFollowingAscending(ctx, database.GetFollowingAscendingParams{
+				UserID: userID,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+			})
+		}
+		return apiCfg.database.GetFollowingDescending(ctx, database.GetFollowingDescendingParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+		})
+	}, func(follow database.Follow) (time.Time, uuid.UUID) {
+		return follow.CreatedAt, follow.FolloweeID
+	})
+	if err != nil {
+		log.Printf("Error fetching followed users: %v", err)
+		respondWithError(w, 500, "Error fetching followed users")
+		return
+	}
+
+	followResponse := make([]FollowResponse, len(page.Items))
+	for i, follow := range page.Items {
+		followResponse[i] = FollowResponse{UserID: follow.FolloweeID, FollowedAt: follow.CreatedAt}
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, FollowPageResponse{
+		Users: followResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
+
+func apiGetTimelineHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		var chirps []database.Chirp
+		if ascending {
+			rows, err := apiCfg.database.GetTimelineAscending(ctx, database.GetTimelineAscendingParams{
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+				UserID: userID,
+			})
+			for _, row := range rows {
+				chirps = append(chirps, database.Chirp(row))
+			}
+			return chirps, err
+		}
+		rows, err := apiCfg.database.GetTimelineDescending(ctx, database.GetTimelineDescendingParams{
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+			UserID: userID,
+		})
+		for _, row := range rows {
+			chirps = append(chirps, database.Chirp(row))
+		}
+		return chirps, err
+	}, chirpPageKey)
+	if err != nil {
+		log.Printf("Error fetching timeline: %v", err)
+		respondWithError(w, 500, "Error fetching timeline")
+		return
+	}
+
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, userID, page.Items)
+	if err != nil {
+		log.Printf("Error fetching chirp engagement: %v", err)
+		respondWithError(w, 500, "Error fetching timeline")
+		return
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, ChirpPageResponse{
+		Chirps: chirpResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
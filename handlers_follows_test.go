@@ -0,0 +1,187 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+// fakeFollows keeps the follows table of a fakeDB in memory, following what
+// the queries do. Only the descending listings are scripted, as they are the
+// ones the handlers start with.
+func fakeFollows(db *fakeDB) *[]database.Follow {
+	follows := &[]database.Follow{}
+	find := func(followerID, followeeID string) int {
+		return slices.IndexFunc(*follows, func(follow database.Follow) bool {
+			return follow.FollowerID.String() == followerID && follow.FolloweeID.String() == followeeID
+		})
+	}
+
+	db.on("FollowUser", func(args []driver.Value) (any, error) {
+		if find(args[0].(string), args[1].(string)) >= 0 {
+			return int64(0), nil
+		}
+		*follows = append(*follows, database.Follow{FollowerID: uuid.MustParse(args[0].(string)), FolloweeID: uuid.MustParse(args[1].(string)), CreatedAt: time.Now()})
+		return nil, nil
+	})
+	db.on("UnfollowUser", func(args []driver.Value) (any, error) {
+		i := find(args[0].(string), args[1].(string))
+		if i < 0 {
+			return int64(0), nil
+		}
+		*follows = slices.Delete(*follows, i, i+1)
+		return nil, nil
+	})
+	list := func(matches func(follow database.Follow, userID string) bool) func(args []driver.Value) (any, error) {
+		return func(args []driver.Value) (any, error) {
+			var listed []database.Follow
+			for _, follow := range slices.Backward(*follows) {
+				if matches(follow, args[0].(string)) {
+					listed = append(listed, follow)
+				}
+			}
+			return listed, nil
+		}
+	}
+	db.on("GetFollowersDescending", list(func(follow database.Follow, userID string) bool { return follow.FolloweeID.String() == userID }))
+	db.on("GetFollowingDescending", list(func(follow database.Follow, userID string) bool { return follow.FollowerID.String() == userID }))
+	return follows
+}
+
+func TestFollowUser(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	follower := database.User{ID: uuid.New()}
+	followee := database.User{ID: uuid.New()}
+	follows := fakeFollows(db)
+	db.returns("GetUserById", followee)
+	db.returns("GetUserRelationship", database.GetUserRelationshipRow{})
+
+	follow := func(handler http.HandlerFunc, userID uuid.UUID) int {
+		r := withUser(httptest.NewRequest("POST", "/api/users/"+userID.String()+"/follow", nil), follower.ID)
+		r.SetPathValue("userID", userID.String())
+		w := httptest.NewRecorder()
+		handler(w, r)
+		return w.Code
+	}
+	listed := func(handler http.HandlerFunc, userID uuid.UUID) []FollowResponse {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.SetPathValue("userID", userID.String())
+		w := httptest.NewRecorder()
+		handler(w, r)
+		var response FollowPageResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return response.Users
+	}
+
+	code := follow(apiFollowUserHandler(apiCfg), follower.ID)
+	if code != 400 || len(db.calls("FollowUser")) != 0 {
+		t.Errorf("Expected following yourself to be rejected with 400, but got %d", code)
+	}
+
+	for range 2 {
+		code = follow(apiFollowUserHandler(apiCfg), followee.ID)
+		if code != 204 {
+			t.Errorf("Expected status 204, but got %d", code)
+		}
+	}
+	if len(*follows) != 1 {
+		t.Errorf("Expected a duplicate follow to be ignored, but got %d follows", len(*follows))
+	}
+
+	followers := listed(apiGetFollowersHandler(apiCfg), followee.ID)
+	if len(followers) != 1 || followers[0].UserID != follower.ID {
+		t.Errorf("Expected the follower to be listed, but got %+v", followers)
+	}
+	following := listed(apiGetFollowingHandler(apiCfg), follower.ID)
+	if len(following) != 1 || following[0].UserID != followee.ID {
+		t.Errorf("Expected the followed user to be listed, but got %+v", following)
+	}
+
+	code = follow(apiUnfollowUserHandler(apiCfg), followee.ID)
+	if code != 204 || len(*follows) != 0 {
+		t.Errorf("Expected the follow to be removed, but got status %d and %d follows", code, len(*follows))
+	}
+	if len(listed(apiGetFollowersHandler(apiCfg), followee.ID)) != 0 {
+		t.Errorf("Expected no followers after unfollowing")
+	}
+}
+
+func TestGetTimeline(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New()}
+	followed := []uuid.UUID{uuid.New(), uuid.New()}
+	stranger := uuid.New()
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var chirps []database.Chirp
+	for i, author := range []uuid.UUID{followed[0], stranger, followed[1], followed[0], stranger, followed[1]} {
+		chirps = append(chirps, database.Chirp{ID: uuid.New(), UserID: author, Body: "hello", CreatedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+
+	db.on("GetTimelineDescending", func(args []driver.Value) (any, error) {
+		if args[3] != user.ID.String() {
+			t.Errorf("Expected the timeline of %v, but got %v", user.ID, args[3])
+		}
+		cursor, _ := args[0].(time.Time)
+		var timeline []database.Chirp
+		for _, chirp := range slices.Backward(chirps) {
+			if !slices.Contains(followed, chirp.UserID) || (!cursor.IsZero() && !chirp.CreatedAt.Before(cursor)) {
+				continue
+			}
+			if len(timeline) < int(args[2].(int64)) {
+				timeline = append(timeline, chirp)
+			}
+		}
+		return timeline, nil
+	})
+	db.returns("GetChirpMentions", nil)
+	db.returns("GetViewerEngagement", nil)
+
+	var got []database.Chirp
+	query := url.Values{"limit": {"2"}}
+	for pages := 0; ; pages++ {
+		if pages > 3 {
+			t.Fatalf("Expected the timeline to end")
+		}
+		r := withUser(httptest.NewRequest("GET", "/api/timeline?"+query.Encode(), nil), user.ID)
+		w := httptest.NewRecorder()
+		apiGetTimelineHandler(apiCfg)(w, r)
+		if w.Code != 200 {
+			t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+		}
+
+		var response ChirpPageResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		if len(response.Chirps) > 2 {
+			t.Errorf("Expected pages of at most 2 chirps, but got %d", len(response.Chirps))
+		}
+		for _, chirp := range response.Chirps {
+			got = append(got, database.Chirp{ID: chirp.ID, UserID: chirp.UserID})
+		}
+		if response.NextCursor == "" {
+			break
+		}
+		query.Set("cursor", response.NextCursor)
+	}
+
+	expected := []uuid.UUID{chirps[5].ID, chirps[3].ID, chirps[2].ID, chirps[0].ID}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %d chirps from followed users, but got %d", len(expected), len(got))
+	}
+	for i, chirp := range got {
+		if chirp.ID != expected[i] {
+			t.Errorf("Expected chirp %v at position %d, but got %v", expected[i], i, chirp.ID)
+		}
+		if chirp.UserID == stranger {
+			t.Errorf("Expected no chirps from users who are not followed")
+		}
+	}
+}
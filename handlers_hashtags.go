@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+func apiGetHashtagChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	tag := strings.ToLower(strings.TrimPrefix(r.PathValue("tag"), "#"))
+	if tag == "" {
+		respondWithError(w, 400, "No hashtag provided")
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	viewerID := viewerIDFromRequest(r, apiCfg)
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetHashtagChirpsAscending(ctx, database.GetHashtagChirpsAscendingParams{
+				Tag: tag,
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+				ViewerID: nullUUID(viewerID),
+			})
+		}
+		return apiCfg.database.GetHashtagChirpsDescending(ctx, database.GetHashtagChirpsDescendingParams{
+			Tag: tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+			ViewerID: nullUUID(viewerID),
+		})
+	}, chirpPageKey)
+	if err != nil {
+		log.Printf("Error fetching hashtag chirps: %v", err)
+		respondWithError(w, 500, "Error fetching chirps")
+		return
+	}
+
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, viewerID, page.Items)
+	if err != nil {
+		log.Printf("Error building chirp responses: %v", err)
+		respondWithError(w, 500, "Error fetching chirps")
+		return
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, ChirpPageResponse{
+		Chirps: chirpResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
+
+func apiGetTrendingHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	window := defaultTrendingWindow
+	windowQuery := r.URL.Query().Get("window")
+	if windowQuery != "" {
+		var err error
+		window, err = time.ParseDuration(windowQuery)
+		if err != nil || window <= 0 || window > maxTrendingWindow {
+			respondWithError(w, 400, "Window must be a duration between 1s and 168h")
+			return
+		}
+	}
+
+	limit := defaultTrendingLimit
+	limitQuery := r.URL.Query().Get("limit")
+	if limitQuery != "" {
+		var err error
+		limit, err = strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			respondWithError(w, 400, "Limit must be between 1 and 100")
+			return
+		}
+	}
+
+	trending, err := apiCfg.database.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+		Since: time.Now().UTC().Add(-window),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		log.Printf("Error fetching trending hashtags: %v", err)
+		respondWithError(w, 500, "Error fetching trending hashtags")
+		return
+	}
+
+	trendingResponse := make([]TrendingHashtagResponse, len(trending))
+	for i, hashtag := range trending {
+		trendingResponse[i] = TrendingHashtagResponse{Tag: hashtag.Tag, ChirpCount: hashtag.ChirpCount}
+	}
+
+	respondWithJSON(w, 200, trendingResponse)
+}}
@@ -0,0 +1,464 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	totpIssuer = "Chirpy"
+
+	mfaChallengeTTL = 5 * time.Minute
+	mfaChallengeCleanupInterval = 10 * time.Minute
+	maxMFAAttempts = 5
+)
+
+type secondFactorRequest struct {
+	Code string `json:"code"`
+	RecoveryCode string `json:"recovery_code"`
+}
+
+func apiEnrollTOTPHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	user, err := apiCfg.database.GetUserById(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error starting enrollment")
+		return
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		respondWithError(w, 500, "Error starting enrollment")
+		return
+	}
+
+	_, err = apiCfg.database.UpsertUserTotp(r.Context(), database.UpsertUserTotpParams{UserID: userID, Secret: secret})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+	if err != nil {
+		log.Printf("Error saving TOTP secret: %v", err)
+		respondWithError(w, 500, "Error starting enrollment")
+		return
+	}
+
+	respondWithJSON(w, 200, TOTPEnrollmentResponse{
+		Secret: secret,
+		OTPAuthURI: auth.TOTPURI(secret, totpIssuer, user.Email),
+	})
+}}
+
+func apiConfirmTOTPHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	reqData := secondFactorRequest{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	totp, err := apiCfg.database.GetUserTotp(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "No enrollment in progress")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching TOTP settings: %v", err)
+		respondWithError(w, 500, "Error confirming enrollment")
+		return
+	}
+	if totp.ConfirmedAt.Valid {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+
+	step, ok := auth.ValidateTOTP(totp.Secret, reqData.Code, time.Now())
+	if !ok {
+		respondWithError(w, 400, "Invalid code")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error confirming enrollment")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	confirmed, err := qtx.ConfirmUserTotp(r.Context(), database.ConfirmUserTotpParams{UserID: userID, LastUsedStep: step})
+	if err != nil {
+		log.Printf("Error confirming TOTP: %v", err)
+		respondWithError(w, 500, "Error confirming enrollment")
+		return
+	}
+	if confirmed == 0 {
+		respondWithError(w, 409, "Two-factor authentication is already enabled")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(r.Context(), qtx, userID)
+	if err != nil {
+		log.Printf("Error creating recovery codes: %v", err)
+		respondWithError(w, 500, "Error confirming enrollment")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error confirming enrollment")
+		return
+	}
+
+	respondWithJSON(w, 200, RecoveryCodesResponse{RecoveryCodes: codes})
+}}
+
+func apiDisableTOTPHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	reqData := secondFactorRequest{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	totp, err := apiCfg.database.GetUserTotp(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "Two-factor authentication is not enabled")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching TOTP settings: %v", err)
+		respondWithError(w, 500, "Error disabling two-factor authentication")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error disabling two-factor authentication")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	// A pending enrollment can be dropped without a code; an active one needs
+	// proof of the second factor.
+	if totp.ConfirmedAt.Valid {
+		if secondFactorLockedOut(w, r, apiCfg, userID) {
+			return
+		}
+		ok, err := verifySecondFactor(r.Context(), qtx, totp, reqData)
+		if err != nil {
+			log.Printf("Error verifying second factor: %v", err)
+			respondWithError(w, 500, "Error disabling two-factor authentication")
+			return
+		}
+		if !ok {
+			recordSecondFactorFailure(r.Context(), apiCfg, userID)
+			logSecurityEvent("mfa_failed", userID, "disabling two-factor authentication")
+			respondWithError(w, 401, "Invalid code")
+			return
+		}
+	}
+
+	_, err = qtx.DeleteUserTotp(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error deleting TOTP settings: %v", err)
+		respondWithError(w, 500, "Error disabling two-factor authentication")
+		return
+	}
+	err = qtx.DeleteRecoveryCodes(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error deleting recovery codes: %v", err)
+		respondWithError(w, 500, "Error disabling two-factor authentication")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error disabling two-factor authentication")
+		return
+	}
+	clearSecondFactorFailures(r.Context(), apiCfg, userID)
+
+	w.WriteHeader(204)
+}}
+
+func apiRegenerateRecoveryCodesHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	reqData := secondFactorRequest{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	totp, err := apiCfg.database.GetUserTotp(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !totp.ConfirmedAt.Valid) {
+		respondWithError(w, 404, "Two-factor authentication is not enabled")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching TOTP settings: %v", err)
+		respondWithError(w, 500, "Error creating recovery codes")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error creating recovery codes")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	if secondFactorLockedOut(w, r, apiCfg, userID) {
+		return
+	}
+
+	// Only a TOTP code is accepted here, so a leaked recovery code cannot be
+	// used to mint new ones.
+	ok, err := verifySecondFactor(r.Context(), qtx, totp, secondFactorRequest{Code: reqData.Code})
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		respondWithError(w, 500, "Error creating recovery codes")
+		return
+	}
+	if !ok {
+		recordSecondFactorFailure(r.Context(), apiCfg, userID)
+		logSecurityEvent("mfa_failed", userID, "regenerating recovery codes")
+		respondWithError(w, 401, "Invalid code")
+		return
+	}
+
+	codes, err := replaceRecoveryCodes(r.Context(), qtx, userID)
+	if err != nil {
+		log.Printf("Error creating recovery codes: %v", err)
+		respondWithError(w, 500, "Error creating recovery codes")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error creating recovery codes")
+		return
+	}
+	clearSecondFactorFailures(r.Context(), apiCfg, userID)
+
+	respondWithJSON(w, 200, RecoveryCodesResponse{RecoveryCodes: codes})
+}}
+
+func apiLoginMFAHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		MFAToken string `json:"mfa_token"`
+		secondFactorRequest
+	}
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	challenge, err := qtx.GetMfaChallengeForUpdate(r.Context(), auth.HashToken(reqData.MFAToken))
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 401, "Invalid or expired MFA token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching MFA challenge: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if challenge.UsedAt.Valid || challenge.ExpiresAt.Before(time.Now()) || challenge.Attempts >= maxMFAAttempts {
+		respondWithError(w, 401, "Invalid or expired MFA token")
+		return
+	}
+	if secondFactorLockedOut(w, r, apiCfg, challenge.UserID) {
+		return
+	}
+
+	user, err := qtx.GetUserById(r.Context(), challenge.UserID)
+	if err != nil {
+		respondWithError(w, 401, "Invalid or expired MFA token")
+		return
+	}
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, "Your account is suspended")
+		return
+	}
+
+	totp, err := qtx.GetUserTotp(r.Context(), user.ID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !totp.ConfirmedAt.Valid) {
+		respondWithError(w, 401, "Invalid or expired MFA token")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching TOTP settings: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	ok, err := verifySecondFactor(r.Context(), qtx, totp, reqData.secondFactorRequest)
+	if err != nil {
+		log.Printf("Error verifying second factor: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !ok {
+		err = qtx.IncrementMfaChallengeAttempts(r.Context(), challenge.TokenHash)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Error recording failed MFA attempt: %v", err)
+		}
+		recordSecondFactorFailure(r.Context(), apiCfg, user.ID)
+		logSecurityEvent("mfa_failed", user.ID, "attempt %d", challenge.Attempts+1)
+		respondWithError(w, 401, "Invalid code")
+		return
+	}
+
+	err = qtx.UseMfaChallenge(r.Context(), challenge.TokenHash)
+	if err != nil {
+		log.Printf("Error using MFA challenge: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	response, err := issueSession(r, qtx, apiCfg, user)
+	if err != nil {
+		log.Printf("Error issuing session: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	clearSecondFactorFailures(r.Context(), apiCfg, user.ID)
+	clearLoginFailures(r.Context(), apiCfg, user.Email)
+
+	respondWithJSON(w, 200, response)
+}}
+
+// createMFAChallenge stores a short-lived token that stands in for the
+// password check until the second factor is provided.
+func createMFAChallenge(ctx context.Context, q *database.Queries, userID uuid.UUID) (MFAChallengeResponse, error) {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return MFAChallengeResponse{}, err
+	}
+
+	expiresAt := time.Now().Add(mfaChallengeTTL)
+	err = q.CreateMfaChallenge(ctx, database.CreateMfaChallengeParams{
+		TokenHash: auth.HashToken(token),
+		UserID: userID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return MFAChallengeResponse{}, err
+	}
+
+	return MFAChallengeResponse{MFARequired: true, MFAToken: token, ExpiresAt: expiresAt}, nil
+}
+
+// deleteExpiredMFAChallenges removes login challenges that can no longer be
+// answered.
+func deleteExpiredMFAChallenges(ctx context.Context, apiCfg *apiConfig) {
+	_, err := apiCfg.database.DeleteExpiredMfaChallenges(ctx, time.Now())
+	if err != nil {
+		log.Printf("Error deleting expired MFA challenges: %v", err)
+	}
+}
+
+// verifySecondFactor checks a TOTP code, or a recovery code if no TOTP code was
+// sent, and consumes it. A TOTP code is only accepted for a time step later than
+// the last one used, so an observed code cannot be replayed.
+func verifySecondFactor(ctx context.Context, q *database.Queries, totp database.UserTotp, factor secondFactorRequest) (bool, error) {
+	if factor.Code != "" {
+		step, ok := auth.ValidateTOTP(totp.Secret, factor.Code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		used, err := q.UseTotpStep(ctx, database.UseTotpStepParams{UserID: totp.UserID, LastUsedStep: step})
+		if err != nil {
+			return false, err
+		}
+		return used == 1, nil
+	}
+
+	if factor.RecoveryCode != "" {
+		used, err := q.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{UserID: totp.UserID, CodeHash: auth.HashRecoveryCode(factor.RecoveryCode)})
+		if err != nil {
+			return false, err
+		}
+		if used == 0 {
+			return false, nil
+		}
+		remaining, err := q.CountUnusedRecoveryCodes(ctx, totp.UserID)
+		if err != nil {
+			return false, err
+		}
+		logSecurityEvent("recovery_code_used", totp.UserID, "%d recovery codes left", remaining)
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// replaceRecoveryCodes discards the user's recovery codes and stores hashes of
+// a new set. The plain codes are returned to be shown once.
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, userID uuid.UUID) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = q.DeleteRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err = q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{UserID: userID, CodeHash: auth.HashRecoveryCode(code)})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func newTestTOTP(t *testing.T, userID uuid.UUID) database.UserTotp {
+	t.Helper()
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatalf("Expected no error generating a secret, but got %v", err)
+	}
+	return database.UserTotp{UserID: userID, Secret: secret, ConfirmedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+}
+
+func currentTOTPCode(t *testing.T, totp database.UserTotp) string {
+	t.Helper()
+	code, err := auth.TOTPCode(totp.Secret, time.Now().Unix()/30)
+	if err != nil {
+		t.Fatalf("Expected no error making a code, but got %v", err)
+	}
+	return code
+}
+
+// wrongTOTPCode returns a code that is not valid for the secret right now.
+func wrongTOTPCode(totp database.UserTotp) string {
+	for _, code := range []string{"000000", "111111", "222222"} {
+		if _, ok := auth.ValidateTOTP(totp.Secret, code, time.Now()); !ok {
+			return code
+		}
+	}
+	return "333333"
+}
+
+func TestSecondFactorEndpointsLockAfterFailures(t *testing.T) {
+	endpoints := []struct {
+		name    string
+		handler func(*apiConfig) http.HandlerFunc
+		success int
+	}{
+		{"disable", apiDisableTOTPHandler, 204},
+		{"regenerate recovery codes", apiRegenerateRecoveryCodesHandler, 200},
+	}
+
+	for _, endpoint := range endpoints {
+		apiCfg, db := newTestAPIConfig(t)
+		user := database.User{ID: uuid.New(), Email: "user@example.com", Role: auth.RoleUser}
+		totp := newTestTOTP(t, user.ID)
+		failures := fakeLoginFailures(db)
+		db.returns("GetUserById", user)
+		db.returns("GetUserTotp", totp)
+		db.returns("UseTotpStep", int64(1))
+		db.returns("DeleteUserTotp", int64(1))
+		db.returns("DeleteRecoveryCodes", nil)
+		db.returns("CreateRecoveryCode", nil)
+
+		send := func(code string) int {
+			r := httptest.NewRequest("POST", "/api/mfa", strings.NewReader(`{"code": "`+code+`"}`))
+			r.Header = bearer(t, apiCfg, user)
+			w := httptest.NewRecorder()
+			apiCfg.middleWareAuth(endpoint.handler(apiCfg), auth.ScopeAccount).ServeHTTP(w, r)
+			return w.Code
+		}
+
+		// A correct code clears earlier failures.
+		for range mfaLockout.Threshold - 1 {
+			if code := send(wrongTOTPCode(totp)); code != 401 {
+				t.Fatalf("%s: expected status 401 for a wrong code, but got %d", endpoint.name, code)
+			}
+		}
+		if code := send(currentTOTPCode(t, totp)); code != endpoint.success {
+			t.Fatalf("%s: expected status %d for a correct code, but got %d", endpoint.name, endpoint.success, code)
+		}
+		if failures[lockoutKindMFA+" "+user.ID.String()] != nil {
+			t.Errorf("%s: expected failures to be cleared, but got %v", endpoint.name, failures)
+		}
+
+		for range mfaLockout.Threshold {
+			send(wrongTOTPCode(totp))
+		}
+		if code := send(currentTOTPCode(t, totp)); code != 429 {
+			t.Errorf("%s: expected status 429 once locked, even for a correct code, but got %d", endpoint.name, code)
+		}
+		if len(db.calls("UseTotpStep")) != 1 {
+			t.Errorf("%s: expected no code to be checked while locked", endpoint.name)
+		}
+	}
+}
+
+func TestLoginMFALocksAcrossChallenges(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Email: "User@Example.com", Role: auth.RoleUser}
+	totp := newTestTOTP(t, user.ID)
+	failures := fakeLoginFailures(db)
+	db.returns("GetUserById", user)
+	db.returns("GetUserTotp", totp)
+	db.returns("IncrementMfaChallengeAttempts", nil)
+	// Every request presents a fresh challenge, as if the password had been
+	// entered again.
+	db.returns("GetMfaChallengeForUpdate", database.MfaChallenge{TokenHash: "hash", UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)})
+
+	send := func(code string) int {
+		r := httptest.NewRequest("POST", "/api/login/mfa", strings.NewReader(`{"mfa_token": "token", "code": "`+code+`"}`))
+		w := httptest.NewRecorder()
+		apiLoginMFAHandler(apiCfg)(w, r)
+		return w.Code
+	}
+
+	for range mfaLockout.Threshold {
+		if code := send(wrongTOTPCode(totp)); code != 401 {
+			t.Fatalf("Expected status 401 for a wrong code, but got %d", code)
+		}
+	}
+	if code := send(currentTOTPCode(t, totp)); code != 429 {
+		t.Errorf("Expected status 429 once locked, but got %d", code)
+	}
+	failure := failures[lockoutKindMFA+" "+user.ID.String()]
+	if failure == nil || failure.Failures != mfaLockout.Threshold {
+		t.Errorf("Expected %d failures for the user, but got %+v", mfaLockout.Threshold, failure)
+	}
+	// Wrong codes also count against password logins for the account.
+	failure = failures[lockoutKindAccount+" user@example.com"]
+	if failure == nil || failure.Failures != mfaLockout.Threshold {
+		t.Errorf("Expected %d failures for the account, but got %+v", mfaLockout.Threshold, failure)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/moderation"
+)
+
+func adminGetModerationRulesHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	stored, err := apiCfg.database.GetModerationRules(r.Context())
+	if err != nil {
+		log.Printf("Error fetching moderation rules: %v", err)
+		respondWithError(w, 500, "Error fetching moderation rules")
+		return
+	}
+
+	ruleResponse := []ModerationRuleResponse{}
+	for _, rule := range apiCfg.baseModerationRules {
+		ruleResponse = append(ruleResponse, ModerationRuleResponse{Pattern: rule.Pattern, Match: string(rule.Match), Action: string(rule.Action), Source: "config"})
+	}
+	for _, rule := range stored {
+		ruleResponse = append(ruleResponse, newModerationRuleResponse(rule))
+	}
+
+	respondWithJSON(w, 200, ruleResponse)
+}}
+
+func adminCreateModerationRuleHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Pattern string `json:"pattern"`
+		Match moderation.MatchType `json:"match"`
+		Action moderation.Action `json:"action"`
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqData := requestData{Match: moderation.MatchWord, Action: moderation.ActionMask}
+	err := decoder.Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	rule := moderation.Rule{Pattern: strings.TrimSpace(reqData.Pattern), Match: reqData.Match, Action: reqData.Action}
+	err = moderation.ValidateRule(rule)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	stored, err := apiCfg.database.UpsertModerationRule(r.Context(), database.UpsertModerationRuleParams{
+		Pattern: rule.Pattern,
+		MatchType: string(rule.Match),
+		Action: string(rule.Action),
+	})
+	if err != nil {
+		log.Printf("Error saving moderation rule: %v", err)
+		respondWithError(w, 500, "Error saving moderation rule")
+		return
+	}
+
+	err = reloadModerationRules(r.Context(), apiCfg)
+	if err != nil {
+		log.Printf("Error reloading moderation rules: %v", err)
+		respondWithError(w, 500, "Error reloading moderation rules")
+		return
+	}
+
+	respondWithJSON(w, 201, newModerationRuleResponse(stored))
+}}
+
+func adminDeleteModerationRuleHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	ruleID, err := uuid.Parse(r.PathValue("ruleID"))
+	if err != nil {
+		respondWithError(w, 400, "RuleID is invalid")
+		return
+	}
+
+	deleted, err := apiCfg.database.DeleteModerationRule(r.Context(), ruleID)
+	if err != nil {
+		log.Printf("Error deleting moderation rule: %v", err)
+		respondWithError(w, 500, "Error deleting moderation rule")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Moderation rule does not exist")
+		return
+	}
+
+	err = reloadModerationRules(r.Context(), apiCfg)
+	if err != nil {
+		log.Printf("Error reloading moderation rules: %v", err)
+		respondWithError(w, 500, "Error reloading moderation rules")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+func adminGetModerationFlagsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	flags, err := apiCfg.database.GetOpenModerationFlags(r.Context())
+	if err != nil {
+		log.Printf("Error fetching moderation flags: %v", err)
+		respondWithError(w, 500, "Error fetching moderation flags")
+		return
+	}
+
+	flagResponse := make([]ModerationFlagResponse, len(flags))
+	for i, flag := range flags {
+		flagResponse[i] = ModerationFlagResponse{
+			ID: flag.ModerationFlag.ID,
+			ChirpID: flag.ModerationFlag.ChirpID,
+			UserID: flag.UserID,
+			Body: flag.Body,
+			Reason: flag.ModerationFlag.Reason,
+			CreatedAt: flag.ModerationFlag.CreatedAt,
+		}
+	}
+
+	respondWithJSON(w, 200, flagResponse)
+}}
+
+func adminResolveModerationFlagHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	flagID, err := uuid.Parse(r.PathValue("flagID"))
+	if err != nil {
+		respondWithError(w, 400, "FlagID is invalid")
+		return
+	}
+
+	resolved, err := apiCfg.database.ResolveModerationFlag(r.Context(), flagID)
+	if err != nil {
+		log.Printf("Error resolving moderation flag: %v", err)
+		respondWithError(w, 500, "Error resolving moderation flag")
+		return
+	}
+	if resolved == 0 {
+		respondWithError(w, 404, "Open moderation flag does not exist")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+func newModerationRuleResponse(rule database.ModerationRule) ModerationRuleResponse {
+	return ModerationRuleResponse{
+		ID: &rule.ID,
+		Pattern: rule.Pattern,
+		Match: rule.MatchType,
+		Action: rule.Action,
+		Source: "database",
+		CreatedAt: &rule.CreatedAt,
+	}
+}
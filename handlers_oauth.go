@@ -0,0 +1,578 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	maxOAuthClientNameLength = 100
+	maxOAuthRedirectURIs = 10
+)
+
+func newOAuthClientResponse(client database.OauthClient) OAuthClientResponse {
+	return OAuthClientResponse{
+		ClientID: client.ID,
+		Name: client.Name,
+		RedirectURIs: client.RedirectUris,
+		Scopes: client.Scopes,
+		Confidential: client.SecretHash.Valid,
+		CreatedAt: client.CreatedAt,
+	}
+}
+
+func apiCreateOAuthClientHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Name string `json:"name"`
+		RedirectURIs []string `json:"redirect_uris"`
+		Scopes []string `json:"scopes"`
+		Confidential bool `json:"confidential"`
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+	if reqData.Name == "" || len([]rune(reqData.Name)) > maxOAuthClientNameLength {
+		respondWithError(w, 400, fmt.Sprintf("Name must be between 1 and %d characters long", maxOAuthClientNameLength))
+		return
+	}
+	if len(reqData.RedirectURIs) == 0 || len(reqData.RedirectURIs) > maxOAuthRedirectURIs {
+		respondWithError(w, 400, fmt.Sprintf("Between 1 and %d redirect URIs are required", maxOAuthRedirectURIs))
+		return
+	}
+	for _, redirectURI := range reqData.RedirectURIs {
+		err = validRedirectURI(redirectURI)
+		if err != nil {
+			respondWithError(w, 400, err.Error())
+			return
+		}
+	}
+
+	scopes := oauthScopes
+	if len(reqData.Scopes) > 0 {
+		scopes = nil
+		for _, scope := range reqData.Scopes {
+			if !slices.Contains(oauthScopes, scope) {
+				respondWithError(w, 400, fmt.Sprintf("Clients cannot request the %q scope", scope))
+				return
+			}
+			if !slices.Contains(scopes, scope) {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	var secret string
+	var secretHash sql.NullString
+	if reqData.Confidential {
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			log.Printf("Error generating client secret: %v", err)
+			respondWithError(w, 500, "Error creating client")
+			return
+		}
+		secretHash = sql.NullString{String: auth.HashToken(secret), Valid: true}
+	}
+
+	client, err := apiCfg.database.CreateOauthClient(r.Context(), database.CreateOauthClientParams{
+		OwnerID: userID,
+		Name: reqData.Name,
+		SecretHash: secretHash,
+		RedirectUris: reqData.RedirectURIs,
+		Scopes: scopes,
+	})
+	if err != nil {
+		log.Printf("Error creating OAuth client: %v", err)
+		respondWithError(w, 500, "Error creating client")
+		return
+	}
+
+	response := newOAuthClientResponse(client)
+	response.ClientSecret = secret
+	respondWithJSON(w, 201, response)
+}}
+
+func apiGetOAuthClientsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	clients, err := apiCfg.database.GetOauthClientsByOwner(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching OAuth clients: %v", err)
+		respondWithError(w, 500, "Error fetching clients")
+		return
+	}
+
+	response := make([]OAuthClientResponse, len(clients))
+	for i, client := range clients {
+		response[i] = newOAuthClientResponse(client)
+	}
+	respondWithJSON(w, 200, response)
+}}
+
+func apiDeleteOAuthClientHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	clientID, err := uuid.Parse(r.PathValue("clientID"))
+	if err != nil {
+		respondWithError(w, 400, "ClientID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	deleted, err := apiCfg.database.DeleteOauthClient(r.Context(), database.DeleteOauthClientParams{ID: clientID, OwnerID: userID})
+	if err != nil {
+		log.Printf("Error deleting OAuth client: %v", err)
+		respondWithError(w, 500, "Error deleting client")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Client does not exist")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+// oauthAuthorizeHandler is where clients send the user. It checks the request
+// and hands it on to the consent page of the Chirpy front end at
+// OAUTH_CONSENT_URL, which shows it to the signed-in user and submits the
+// decision to apiOAuthAuthorizeHandler.
+func oauthAuthorizeHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	request, err := parseAuthorizationRequest(r.Context(), apiCfg, r.URL.Query())
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		if request.RedirectURI == "" {
+			respondWithError(w, 400, oauthErr.Description)
+			return
+		}
+		http.Redirect(w, r, authorizationRedirect(request.RedirectURI, url.Values{
+			"error": {oauthErr.Code},
+			"error_description": {oauthErr.Description},
+			"state": {request.State},
+		}), http.StatusFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking authorization request: %v", err)
+		respondWithError(w, 500, "Error checking authorization request")
+		return
+	}
+
+	http.Redirect(w, r, consentRedirect(apiCfg.oauthConsentURL, r.URL.RawQuery), http.StatusFound)
+}}
+
+// apiOAuthConsentHandler describes an authorization request for the consent
+// page.
+func apiOAuthConsentHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	request, err := parseAuthorizationRequest(r.Context(), apiCfg, r.URL.Query())
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		respondWithError(w, 400, oauthErr.Description)
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking authorization request: %v", err)
+		respondWithError(w, 500, "Error checking authorization request")
+		return
+	}
+
+	respondWithJSON(w, 200, OAuthConsentResponse{
+		ClientID: request.Client.ID,
+		ClientName: request.Client.Name,
+		RedirectURI: request.RedirectURI,
+		Scopes: request.Scopes,
+	})
+}}
+
+// apiOAuthAuthorizeHandler records the user's decision on an authorization
+// request, passed in the query string as it was sent to /oauth/authorize, and
+// returns where to send the user back to.
+func apiOAuthAuthorizeHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Approved bool `json:"approved"`
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	request, err := parseAuthorizationRequest(r.Context(), apiCfg, r.URL.Query())
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		respondWithError(w, 400, oauthErr.Description)
+		return
+	}
+	if err != nil {
+		log.Printf("Error checking authorization request: %v", err)
+		respondWithError(w, 500, "Error checking authorization request")
+		return
+	}
+
+	if !reqData.Approved {
+		respondWithJSON(w, 200, OAuthRedirectResponse{RedirectTo: authorizationRedirect(request.RedirectURI, url.Values{
+			"error": {"access_denied"},
+			"state": {request.State},
+		})})
+		return
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		log.Printf("Error generating authorization code: %v", err)
+		respondWithError(w, 500, "Error authorizing client")
+		return
+	}
+
+	err = apiCfg.database.CreateOauthAuthorizationCode(r.Context(), database.CreateOauthAuthorizationCodeParams{
+		CodeHash: auth.HashToken(code),
+		ClientID: request.Client.ID,
+		UserID: userID,
+		RedirectUri: request.RedirectURI,
+		Scopes: request.Scopes,
+		CodeChallenge: request.CodeChallenge,
+		ExpiresAt: time.Now().Add(oauthCodeTTL),
+	})
+	if err != nil {
+		log.Printf("Error saving authorization code: %v", err)
+		respondWithError(w, 500, "Error authorizing client")
+		return
+	}
+
+	respondWithJSON(w, 200, OAuthRedirectResponse{RedirectTo: authorizationRedirect(request.RedirectURI, url.Values{
+		"code": {code},
+		"state": {request.State},
+	})})
+}}
+
+// oauthTokenHandler is the token endpoint of RFC 6749. It redeems
+// authorization codes and rotates refresh tokens.
+func oauthTokenHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, 400, &oauthError{"invalid_request", "Invalid form body"})
+		return
+	}
+
+	client, err := authenticateOAuthClient(r, apiCfg)
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		respondWithOAuthError(w, 401, oauthErr)
+		return
+	}
+	if err != nil {
+		log.Printf("Error authenticating OAuth client: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	var response OAuthTokenResponse
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		response, err = redeemAuthorizationCode(r, qtx, apiCfg, client)
+	case "refresh_token":
+		response, err = refreshOAuthTokens(r, qtx, apiCfg, client)
+	default:
+		err = &oauthError{"unsupported_grant_type", "Only the authorization_code and refresh_token grants are supported"}
+	}
+
+	if err != nil && !errors.As(err, &oauthErr) {
+		log.Printf("Error issuing OAuth tokens: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	// A reused code or refresh token revokes its grant, which has to be
+	// committed even though the request fails.
+	commitErr := tx.Commit()
+	if commitErr != nil {
+		log.Printf("Error committing transaction: %v", commitErr)
+		w.WriteHeader(500)
+		return
+	}
+	if err != nil {
+		respondWithOAuthError(w, 400, oauthErr)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, 200, response)
+}}
+
+func redeemAuthorizationCode(r *http.Request, q *database.Queries, apiCfg *apiConfig, client database.OauthClient) (OAuthTokenResponse, error) {
+	invalidGrant := &oauthError{"invalid_grant", "Invalid or expired authorization code"}
+
+	code, err := q.GetOauthAuthorizationCodeForUpdate(r.Context(), auth.HashToken(r.PostForm.Get("code")))
+	if errors.Is(err, sql.ErrNoRows) {
+		return OAuthTokenResponse{}, invalidGrant
+	}
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+
+	if code.UsedAt.Valid {
+		if code.GrantID.Valid {
+			err = revokeOAuthGrant(r.Context(), q, code.GrantID.UUID, code.UserID, "authorization code used twice")
+			if err != nil {
+				return OAuthTokenResponse{}, err
+			}
+		}
+		return OAuthTokenResponse{}, invalidGrant
+	}
+	if code.ClientID != client.ID || code.ExpiresAt.Before(time.Now()) || code.RedirectUri != r.PostForm.Get("redirect_uri") {
+		return OAuthTokenResponse{}, invalidGrant
+	}
+	if !auth.VerifyPKCE(r.PostForm.Get("code_verifier"), code.CodeChallenge) {
+		return OAuthTokenResponse{}, &oauthError{"invalid_grant", "Code verifier does not match"}
+	}
+
+	user, err := q.GetUserById(r.Context(), code.UserID)
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	if user.SuspendedAt.Valid {
+		return OAuthTokenResponse{}, &oauthError{"invalid_grant", "The account is suspended"}
+	}
+
+	grant, err := q.CreateOauthGrant(r.Context(), database.CreateOauthGrantParams{ClientID: client.ID, UserID: user.ID, Scopes: code.Scopes})
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	err = q.UseOauthAuthorizationCode(r.Context(), database.UseOauthAuthorizationCodeParams{CodeHash: code.CodeHash, GrantID: uuid.NullUUID{UUID: grant.ID, Valid: true}})
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+
+	return issueOAuthTokens(r.Context(), q, apiCfg, grant, user, grant.Scopes)
+}
+
+func refreshOAuthTokens(r *http.Request, q *database.Queries, apiCfg *apiConfig, client database.OauthClient) (OAuthTokenResponse, error) {
+	invalidGrant := &oauthError{"invalid_grant", "Invalid or expired refresh token"}
+
+	refresh, err := q.GetOauthRefreshTokenForUpdate(r.Context(), auth.HashToken(r.PostForm.Get("refresh_token")))
+	if errors.Is(err, sql.ErrNoRows) {
+		return OAuthTokenResponse{}, invalidGrant
+	}
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	grant, err := q.GetOauthGrant(r.Context(), refresh.GrantID)
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	if grant.ClientID != client.ID {
+		return OAuthTokenResponse{}, invalidGrant
+	}
+
+	// Refresh tokens are rotated, so a used one coming back means it was
+	// copied; the whole grant goes, as with session refresh tokens.
+	if refresh.UsedAt.Valid {
+		err = revokeOAuthGrant(r.Context(), q, grant.ID, grant.UserID, "rotated refresh token presented again")
+		if err != nil {
+			return OAuthTokenResponse{}, err
+		}
+		return OAuthTokenResponse{}, invalidGrant
+	}
+	if grant.RevokedAt.Valid || refresh.ExpiresAt.Before(time.Now()) {
+		return OAuthTokenResponse{}, invalidGrant
+	}
+
+	scopes := grant.Scopes
+	if scope := r.PostForm.Get("scope"); scope != "" {
+		scopes = strings.Fields(scope)
+		for _, s := range scopes {
+			if !slices.Contains(grant.Scopes, s) {
+				return OAuthTokenResponse{}, &oauthError{"invalid_scope", fmt.Sprintf("The %s scope was not granted", s)}
+			}
+		}
+	}
+
+	user, err := q.GetUserById(r.Context(), grant.UserID)
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	if user.SuspendedAt.Valid {
+		return OAuthTokenResponse{}, &oauthError{"invalid_grant", "The account is suspended"}
+	}
+
+	err = q.UseOauthRefreshToken(r.Context(), refresh.TokenHash)
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	return issueOAuthTokens(r.Context(), q, apiCfg, grant, user, scopes)
+}
+
+// oauthRevokeHandler implements RFC 7009. Revoking either kind of token
+// revokes the grant behind it, so every token issued with it stops working.
+// As the RFC asks, unknown tokens are not reported as errors.
+func oauthRevokeHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, 400, &oauthError{"invalid_request", "Invalid form body"})
+		return
+	}
+
+	client, err := authenticateOAuthClient(r, apiCfg)
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		respondWithOAuthError(w, 401, oauthErr)
+		return
+	}
+	if err != nil {
+		log.Printf("Error authenticating OAuth client: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	grantID := uuid.Nil
+	_, grant, _ := activeOAuthRefreshToken(r.Context(), apiCfg, client, token)
+	if grant.ClientID == client.ID {
+		grantID = grant.ID
+	} else if principal, err := apiCfg.keys.ValidateJWT(token); err == nil && principal.ClientID == client.ID {
+		grantID = principal.GrantID
+	}
+
+	if grantID != uuid.Nil {
+		_, err = apiCfg.database.RevokeOauthGrant(r.Context(), grantID)
+		if err != nil {
+			log.Printf("Error revoking OAuth grant: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+	}
+
+	w.WriteHeader(200)
+}}
+
+// oauthIntrospectHandler implements RFC 7662 for confidential clients, which
+// can only look at their own tokens.
+func oauthIntrospectHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		respondWithOAuthError(w, 400, &oauthError{"invalid_request", "Invalid form body"})
+		return
+	}
+
+	client, err := authenticateOAuthClient(r, apiCfg)
+	var oauthErr *oauthError
+	if errors.As(err, &oauthErr) {
+		respondWithOAuthError(w, 401, oauthErr)
+		return
+	}
+	if err != nil {
+		log.Printf("Error authenticating OAuth client: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	if !client.SecretHash.Valid {
+		respondWithOAuthError(w, 401, &oauthError{"invalid_client", "Only confidential clients can introspect tokens"})
+		return
+	}
+
+	token := r.PostForm.Get("token")
+	response := OAuthIntrospectionResponse{}
+	claims := &auth.Claims{}
+	if apiCfg.keys.Parse(token, claims) == nil {
+		principal, err := apiCfg.keys.ValidateJWT(token)
+		if err == nil && principal.ClientID == client.ID && checkOAuthGrant(r.Context(), apiCfg, principal) == nil {
+			response = OAuthIntrospectionResponse{
+				Active: true,
+				Scope: claims.Scope,
+				ClientID: claims.ClientID,
+				Subject: claims.Subject,
+				TokenType: "Bearer",
+				ExpiresAt: claims.ExpiresAt.Unix(),
+				IssuedAt: claims.IssuedAt.Unix(),
+			}
+		}
+	} else if refresh, grant, active := activeOAuthRefreshToken(r.Context(), apiCfg, client, token); active {
+		response = OAuthIntrospectionResponse{
+			Active: true,
+			Scope: strings.Join(grant.Scopes, " "),
+			ClientID: grant.ClientID.String(),
+			Subject: grant.UserID.String(),
+			TokenType: "refresh_token",
+			ExpiresAt: refresh.ExpiresAt.Unix(),
+			IssuedAt: refresh.CreatedAt.Unix(),
+		}
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, 200, response)
+}}
+
+func apiGetOAuthGrantsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	grants, err := apiCfg.database.GetActiveOauthGrantsByUser(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching OAuth grants: %v", err)
+		respondWithError(w, 500, "Error fetching authorized apps")
+		return
+	}
+
+	response := make([]OAuthGrantResponse, len(grants))
+	for i, grant := range grants {
+		response[i] = OAuthGrantResponse{
+			ID: grant.OauthGrant.ID,
+			ClientID: grant.OauthGrant.ClientID,
+			ClientName: grant.ClientName,
+			Scopes: grant.OauthGrant.Scopes,
+			CreatedAt: grant.OauthGrant.CreatedAt,
+		}
+	}
+	respondWithJSON(w, 200, response)
+}}
+
+func apiRevokeOAuthGrantHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	grantID, err := uuid.Parse(r.PathValue("grantID"))
+	if err != nil {
+		respondWithError(w, 400, "GrantID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	revoked, err := apiCfg.database.RevokeUserOauthGrant(r.Context(), database.RevokeUserOauthGrantParams{ID: grantID, UserID: userID})
+	if err != nil {
+		log.Printf("Error revoking OAuth grant: %v", err)
+		respondWithError(w, 500, "Error revoking authorized app")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Authorized app does not exist")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
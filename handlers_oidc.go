@@ -0,0 +1,248 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/oidc"
+	"github.com/lib/pq"
+)
+
+const (
+	oidcLoginTTL = 10 * time.Minute
+
+	// pqUniqueViolation is the Postgres error code for a unique constraint
+	// violation.
+	pqUniqueViolation = "23505"
+)
+
+var errOIDCNoVerifiedEmail = errors.New("identity provider did not return a verified email address")
+
+// apiStartOIDCLoginHandler starts a sign-in with the identity provider. The
+// front end should keep the returned state and check that the provider sends
+// the same one back before calling apiOIDCLoginHandler.
+func apiStartOIDCLoginHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	if apiCfg.oidc == nil {
+		respondWithError(w, 404, "Single sign-on is not configured")
+		return
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := auth.MakeRefreshToken()
+		if err != nil {
+			log.Printf("Error generating OIDC state: %v", err)
+			respondWithError(w, 500, "Error starting sign-in")
+			return
+		}
+		values[i] = value
+	}
+	state, nonce, verifier := values[0], values[1], values[2]
+
+	err := apiCfg.database.CreateOidcLoginState(r.Context(), database.CreateOidcLoginStateParams{
+		StateHash: auth.HashToken(state),
+		Nonce: nonce,
+		CodeVerifier: verifier,
+		ExpiresAt: time.Now().Add(oidcLoginTTL),
+	})
+	if err != nil {
+		log.Printf("Error saving OIDC state: %v", err)
+		respondWithError(w, 500, "Error starting sign-in")
+		return
+	}
+
+	respondWithJSON(w, 200, OIDCLoginResponse{
+		AuthorizationURL: apiCfg.oidc.AuthCodeURL(state, nonce, verifier),
+		State: state,
+	})
+}}
+
+// apiOIDCLoginHandler completes a sign-in with the code and state the
+// provider sent back, and logs in the user the external account is linked to.
+func apiOIDCLoginHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Code string `json:"code"`
+		State string `json:"state"`
+	}
+
+	if apiCfg.oidc == nil {
+		respondWithError(w, 404, "Single sign-on is not configured")
+		return
+	}
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil || reqData.Code == "" || reqData.State == "" {
+		respondWithError(w, 400, "Code and state are required")
+		return
+	}
+
+	loginState, err := consumeOIDCLoginState(r.Context(), apiCfg, reqData.State)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 400, "Invalid or expired sign-in attempt")
+		return
+	}
+	if err != nil {
+		log.Printf("Error using OIDC state: %v", err)
+		respondWithError(w, 500, "Error signing in")
+		return
+	}
+
+	tokens, err := apiCfg.oidc.Exchange(r.Context(), reqData.Code, loginState.CodeVerifier)
+	if err != nil {
+		log.Printf("Error exchanging OIDC code: %v", err)
+		respondWithError(w, 401, "Sign-in with the identity provider failed")
+		return
+	}
+	idToken, err := apiCfg.oidc.VerifyIDToken(r.Context(), tokens.IDToken, loginState.Nonce)
+	if err != nil {
+		log.Printf("Error verifying ID token: %v", err)
+		respondWithError(w, 401, "Sign-in with the identity provider failed")
+		return
+	}
+
+	user, err := linkOIDCIdentity(r.Context(), apiCfg, idToken)
+	if errors.Is(err, errOIDCNoVerifiedEmail) {
+		respondWithError(w, 400, "The identity provider did not return a verified email address")
+		return
+	}
+	if err != nil {
+		log.Printf("Error linking OIDC identity: %v", err)
+		respondWithError(w, 500, "Error signing in")
+		return
+	}
+
+	respondWithLogin(w, r, apiCfg, user)
+}}
+
+// consumeOIDCLoginState marks a login state used and returns it. States that
+// are unknown, used or expired come back as sql.ErrNoRows.
+func consumeOIDCLoginState(ctx context.Context, apiCfg *apiConfig, state string) (database.OidcLoginState, error) {
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.OidcLoginState{}, err
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	loginState, err := qtx.GetOidcLoginStateForUpdate(ctx, auth.HashToken(state))
+	if err != nil {
+		return database.OidcLoginState{}, err
+	}
+	if loginState.UsedAt.Valid || loginState.ExpiresAt.Before(time.Now()) {
+		return database.OidcLoginState{}, sql.ErrNoRows
+	}
+
+	err = qtx.UseOidcLoginState(ctx, loginState.StateHash)
+	if err != nil {
+		return database.OidcLoginState{}, err
+	}
+	return loginState, tx.Commit()
+}
+
+// linkOIDCIdentity returns the user an external account is linked to. The
+// first time an account signs in, it is linked to the user with the same
+// email address, compared without regard to case, or a new user is created
+// for it. Only emails the provider has verified are trusted for this, since
+// linking by an unverified address would hand the account to whoever typed it
+// in at the provider.
+//
+// Two first sign-ins for the same account can race. The loser's transaction
+// finds the identity, or the new user's email, already taken and is tried
+// again, which then finds the winner's link.
+func linkOIDCIdentity(ctx context.Context, apiCfg *apiConfig, idToken *oidc.IDToken) (database.User, error) {
+	user, err := tryLinkOIDCIdentity(ctx, apiCfg, idToken)
+	if errors.Is(err, errOIDCLinkConflict) {
+		user, err = tryLinkOIDCIdentity(ctx, apiCfg, idToken)
+	}
+	return user, err
+}
+
+var errOIDCLinkConflict = errors.New("identity was linked concurrently")
+
+func tryLinkOIDCIdentity(ctx context.Context, apiCfg *apiConfig, idToken *oidc.IDToken) (database.User, error) {
+	provider := idToken.Issuer
+	email := strings.TrimSpace(idToken.Email)
+
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.User{}, err
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	identity, err := qtx.GetUserIdentity(ctx, database.GetUserIdentityParams{Provider: provider, Subject: idToken.Subject})
+	if err == nil {
+		err = qtx.TouchUserIdentity(ctx, database.TouchUserIdentityParams{Provider: provider, Subject: idToken.Subject, Email: email})
+		if err != nil {
+			return database.User{}, err
+		}
+		user, err := qtx.GetUserById(ctx, identity.UserID)
+		if err != nil {
+			return database.User{}, err
+		}
+		return user, tx.Commit()
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return database.User{}, err
+	}
+
+	if email == "" || !idToken.EmailVerified {
+		return database.User{}, errOIDCNoVerifiedEmail
+	}
+
+	user, err := qtx.GetUserByEmailInsensitive(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		// The user signs in through the provider, so the password is random;
+		// a password reset can set a real one later.
+		password, err := auth.MakeRefreshToken()
+		if err != nil {
+			return database.User{}, err
+		}
+		passHash, err := auth.HashPassword(password)
+		if err != nil {
+			return database.User{}, err
+		}
+		user, err = qtx.CreateUser(ctx, database.CreateUserParams{Email: email, HashedPassword: passHash})
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == pqUniqueViolation {
+			return database.User{}, errOIDCLinkConflict
+		}
+		if err != nil {
+			return database.User{}, err
+		}
+	} else if err != nil {
+		return database.User{}, err
+	}
+
+	_, err = qtx.MarkEmailVerified(ctx, database.MarkEmailVerifiedParams{ID: user.ID, Email: user.Email})
+	if err != nil {
+		return database.User{}, err
+	}
+	created, err := qtx.CreateUserIdentity(ctx, database.CreateUserIdentityParams{Provider: provider, Subject: idToken.Subject, UserID: user.ID, Email: email})
+	if err != nil {
+		return database.User{}, err
+	}
+	if created == 0 {
+		return database.User{}, errOIDCLinkConflict
+	}
+	user, err = qtx.GetUserById(ctx, user.ID)
+	if err != nil {
+		return database.User{}, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return database.User{}, err
+	}
+	logSecurityEvent("oidc_identity_linked", user.ID, "subject %s at %s", idToken.Subject, provider)
+	return user, nil
+}
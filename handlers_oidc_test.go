@@ -0,0 +1,187 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql/driver"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/oidc"
+	"github.com/lib/pq"
+)
+
+const testOIDCNonce = "nonce"
+
+// newTestOIDCProvider starts an identity provider whose token endpoint hands
+// out an ID token for subject and email, whatever the code, and returns a
+// client for it.
+func newTestOIDCProvider(t *testing.T, subject, email string) *oidc.Client {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Expected no error generating a key, but got %v", err)
+	}
+
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidc.Metadata{
+			Issuer:                server.URL,
+			AuthorizationEndpoint: server.URL + "/authorize",
+			TokenEndpoint:         server.URL + "/token",
+			JWKSURI:               server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "key",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"iss":            server.URL,
+			"sub":            subject,
+			"aud":            "chirpy",
+			"exp":            time.Now().Add(time.Hour).Unix(),
+			"iat":            time.Now().Unix(),
+			"nonce":          testOIDCNonce,
+			"email":          email,
+			"email_verified": true,
+		})
+		token.Header["kid"] = "key"
+		idToken, err := token.SignedString(key)
+		if err != nil {
+			t.Errorf("Expected no error signing, but got %v", err)
+		}
+		json.NewEncoder(w).Encode(oidc.Tokens{AccessToken: "access", TokenType: "Bearer", IDToken: idToken})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	client, err := oidc.Discover(t.Context(), server.URL, oidc.Config{ClientID: "chirpy", ClientSecret: "secret", RedirectURL: "http://localhost:8080/oidc/callback"})
+	if err != nil {
+		t.Fatalf("Expected no error discovering the provider, but got %v", err)
+	}
+	return client
+}
+
+func TestOIDCLoginLinksIdentity(t *testing.T) {
+	existing := database.User{ID: uuid.New(), Email: "User@Example.com", Role: auth.RoleUser}
+	winner := database.User{ID: uuid.New(), Email: "new@example.com", Role: auth.RoleUser}
+
+	tests := []struct {
+		name  string
+		email string
+		// script sets up the user lookup and creation and the identity insert.
+		script   func(db *fakeDB)
+		expected uuid.UUID
+	}{
+		{
+			name:  "existing user with differently cased email",
+			email: "user@example.com",
+			script: func(db *fakeDB) {
+				db.on("GetUserByEmailInsensitive", func(args []driver.Value) (any, error) {
+					if !strings.EqualFold(args[0].(string), existing.Email) {
+						return nil, nil
+					}
+					return existing, nil
+				})
+				db.returns("CreateUserIdentity", int64(1))
+			},
+			expected: existing.ID,
+		},
+		{
+			name:  "identity linked by a concurrent sign-in",
+			email: "user@example.com",
+			script: func(db *fakeDB) {
+				db.returns("GetUserByEmailInsensitive", existing)
+				db.returns("CreateUserIdentity", int64(0))
+			},
+			expected: winner.ID,
+		},
+		{
+			name:  "user created by a concurrent sign-in",
+			email: "new@example.com",
+			script: func(db *fakeDB) {
+				db.returns("GetUserByEmailInsensitive", nil)
+				db.on("CreateUser", func(args []driver.Value) (any, error) {
+					return nil, &pq.Error{Code: pqUniqueViolation}
+				})
+			},
+			expected: winner.ID,
+		},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		apiCfg.oidc = newTestOIDCProvider(t, "subject", test.email)
+		db.returns("GetOidcLoginStateForUpdate", database.OidcLoginState{StateHash: "hash", Nonce: testOIDCNonce, CodeVerifier: strings.Repeat("v", 43), ExpiresAt: time.Now().Add(time.Minute)})
+		db.returns("UseOidcLoginState", nil)
+		db.returns("MarkEmailVerified", int64(1))
+		db.returns("GetUserTotp", nil)
+		db.returns("InsertRefreshToken", database.RefreshToken{})
+		db.returns("ClearLoginFailures", int64(0))
+		db.returns("TouchUserIdentity", nil)
+		users := map[string]database.User{existing.ID.String(): existing, winner.ID.String(): winner}
+		db.on("GetUserById", func(args []driver.Value) (any, error) { return users[args[0].(string)], nil })
+		// The identity only shows up once a first attempt has failed, as if
+		// another sign-in had committed it in the meantime.
+		db.on("GetUserIdentity", func(args []driver.Value) (any, error) {
+			if db.rollbacks == 0 {
+				return nil, nil
+			}
+			return database.UserIdentity{Provider: args[0].(string), Subject: args[1].(string), UserID: winner.ID}, nil
+		})
+		test.script(db)
+
+		r := httptest.NewRequest("POST", "/api/login/oidc", strings.NewReader(`{"code": "code", "state": "state"}`))
+		w := httptest.NewRecorder()
+		apiOIDCLoginHandler(apiCfg)(w, r)
+
+		if w.Code != 200 {
+			t.Errorf("%s: expected status 200, but got %d: %s", test.name, w.Code, w.Body.String())
+			continue
+		}
+		var response LoginResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		if response.ID != test.expected {
+			t.Errorf("%s: expected to sign in as %v, but got %v", test.name, test.expected, response.ID)
+		}
+	}
+}
+
+func TestOIDCLinkingQueries(t *testing.T) {
+	_, q := newTestDatabase(t)
+	user := createTestUser(t, q, "User@Example.com")
+
+	found, err := q.GetUserByEmailInsensitive(t.Context(), "user@example.com")
+	if err != nil || found.ID != user.ID {
+		t.Errorf("Expected the lookup to ignore case, but got %v, %v", found.ID, err)
+	}
+
+	params := database.CreateUserIdentityParams{Provider: "https://idp.example.com", Subject: "subject", UserID: user.ID, Email: "user@example.com"}
+	created, err := q.CreateUserIdentity(t.Context(), params)
+	if err != nil || created != 1 {
+		t.Fatalf("Expected the identity to be created, but got %d, %v", created, err)
+	}
+	created, err = q.CreateUserIdentity(t.Context(), params)
+	if err != nil || created != 0 {
+		t.Errorf("Expected a second link to be skipped, but got %d, %v", created, err)
+	}
+}
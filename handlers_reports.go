@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	reportDismiss = "dismiss"
+	reportDeleteChirp = "delete_chirp"
+	reportSuspendUser = "suspend_user"
+	actionUnsuspendUser = "unsuspend_user"
+	actionSetUserRole = "set_role"
+	actionUnlockUser = "unlock_user"
+
+	maxReportReasonLength = 500
+)
+
+func apiReportChirpHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		respondWithError(w, 400, "ChirpID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	reason, err := decodeReportReason(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	chirp, err := apiCfg.database.GetChirpById(r.Context(), chirpID)
+	if err != nil || chirp.DeletedAt.Valid {
+		respondWithError(w, 404, "Chirp does not exist")
+		return
+	}
+
+	if chirp.UserID == userID {
+		respondWithError(w, 400, "You cannot report your own chirp")
+		return
+	}
+
+	report, err := apiCfg.database.CreateReport(r.Context(), database.CreateReportParams{
+		ReporterID: userID,
+		ReportedUserID: chirp.UserID,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+		Reason: reason,
+	})
+	if err != nil {
+		log.Printf("Error creating report: %v", err)
+		respondWithError(w, 500, "Error creating report")
+		return
+	}
+
+	respondWithJSON(w, 201, newReportResponse(report, sql.NullString{}))
+}}
+
+func apiReportUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	reportedUserID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	reason, err := decodeReportReason(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	if reportedUserID == userID {
+		respondWithError(w, 400, "You cannot report yourself")
+		return
+	}
+
+	_, err = apiCfg.database.GetUserById(r.Context(), reportedUserID)
+	if err != nil {
+		respondWithError(w, 404, "User does not exist")
+		return
+	}
+
+	report, err := apiCfg.database.CreateReport(r.Context(), database.CreateReportParams{
+		ReporterID: userID,
+		ReportedUserID: reportedUserID,
+		Reason: reason,
+	})
+	if err != nil {
+		log.Printf("Error creating report: %v", err)
+		respondWithError(w, 500, "Error creating report")
+		return
+	}
+
+	respondWithJSON(w, 201, newReportResponse(report, sql.NullString{}))
+}}
+
+func adminGetReportsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	reports, err := apiCfg.database.GetOpenReports(r.Context())
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		respondWithError(w, 500, "Error fetching reports")
+		return
+	}
+
+	reportResponse := make([]ReportResponse, len(reports))
+	for i, report := range reports {
+		reportResponse[i] = newReportResponse(report.Report, report.ChirpBody)
+	}
+
+	respondWithJSON(w, 200, reportResponse)
+}}
+
+func adminResolveReportHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Action string `json:"action"`
+		Note string `json:"note"`
+	}
+
+	reportID, err := uuid.Parse(r.PathValue("reportID"))
+	if err != nil {
+		respondWithError(w, 400, "ReportID is invalid")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqData := requestData{}
+	err = decoder.Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	if reqData.Action != reportDismiss && reqData.Action != reportDeleteChirp && reqData.Action != reportSuspendUser {
+		respondWithError(w, 400, "Action must be one of dismiss, delete_chirp or suspend_user")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error resolving report")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	report, err := qtx.GetReportByIdForUpdate(r.Context(), reportID)
+	if err != nil {
+		respondWithError(w, 404, "Report does not exist")
+		return
+	}
+
+	if report.ResolvedAt.Valid {
+		respondWithError(w, 409, "Report is already resolved")
+		return
+	}
+
+	switch reqData.Action {
+	case reportDeleteChirp:
+		if !report.ChirpID.Valid {
+			respondWithError(w, 400, "Report does not refer to an existing chirp")
+			return
+		}
+		err = removeChirp(r.Context(), qtx, report.ChirpID.UUID)
+	case reportSuspendUser:
+		_, err = qtx.SuspendUser(r.Context(), report.ReportedUserID)
+	}
+	if err != nil {
+		log.Printf("Error applying report action %s: %v", reqData.Action, err)
+		respondWithError(w, 500, "Error resolving report")
+		return
+	}
+
+	resolved, err := qtx.ResolveReport(r.Context(), database.ResolveReportParams{ID: report.ID, Resolution: sql.NullString{String: reqData.Action, Valid: true}})
+	if err != nil {
+		log.Printf("Error resolving report: %v", err)
+		respondWithError(w, 500, "Error resolving report")
+		return
+	}
+
+	err = recordModerationAction(r.Context(), qtx, principalFromRequest(r).UserID, reqData.Action, report.ID, report.ChirpID, report.ReportedUserID, reqData.Note)
+	if err != nil {
+		log.Printf("Error recording moderation action: %v", err)
+		respondWithError(w, 500, "Error resolving report")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error resolving report")
+		return
+	}
+
+	respondWithJSON(w, 200, newReportResponse(resolved, sql.NullString{}))
+}}
+
+func adminUnsuspendUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Note string `json:"note"`
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	reqData := requestData{}
+	if r.ContentLength != 0 {
+		err = json.NewDecoder(r.Body).Decode(&reqData)
+		if err != nil {
+			respondWithError(w, 400, "Invalid request body")
+			return
+		}
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error unsuspending user")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	_, err = qtx.UnsuspendUser(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "User does not exist")
+		return
+	}
+	if err != nil {
+		log.Printf("Error unsuspending user: %v", err)
+		respondWithError(w, 500, "Error unsuspending user")
+		return
+	}
+
+	err = recordModerationAction(r.Context(), qtx, principalFromRequest(r).UserID, actionUnsuspendUser, uuid.Nil, uuid.NullUUID{}, userID, reqData.Note)
+	if err != nil {
+		log.Printf("Error recording moderation action: %v", err)
+		respondWithError(w, 500, "Error unsuspending user")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error unsuspending user")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+func adminSetUserRoleHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Role string `json:"role"`
+	}
+
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	reqData := requestData{}
+	err = json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+	if !auth.ValidRole(reqData.Role) {
+		respondWithError(w, 400, "Role must be user or admin")
+		return
+	}
+	if userID == principalFromRequest(r).UserID && reqData.Role != auth.RoleAdmin {
+		respondWithError(w, 400, "You cannot remove your own admin role")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error updating role")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	user, err := qtx.SetUserRole(r.Context(), database.SetUserRoleParams{ID: userID, Role: reqData.Role})
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "User does not exist")
+		return
+	}
+	if err != nil {
+		log.Printf("Error updating role: %v", err)
+		respondWithError(w, 500, "Error updating role")
+		return
+	}
+
+	err = recordModerationAction(r.Context(), qtx, principalFromRequest(r).UserID, actionSetUserRole, uuid.Nil, uuid.NullUUID{}, userID, reqData.Role)
+	if err != nil {
+		log.Printf("Error recording moderation action: %v", err)
+		respondWithError(w, 500, "Error updating role")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error updating role")
+		return
+	}
+
+	respondWithJSON(w, 200, UserResponse{
+		ID: user.ID,
+		Email: user.Email,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		IsChirpyRed: user.IsChirpyRed,
+		Role: user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+	})
+}}
+
+func adminUnlockUserHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		respondWithError(w, 400, "UserID is invalid")
+		return
+	}
+
+	user, err := apiCfg.database.GetUserById(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "User does not exist")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error unlocking user")
+		return
+	}
+
+	account, _ := lockoutSubjects(user.Email, "")
+	var cleared int64
+	for kind, subject := range map[string]string{lockoutKindAccount: account, lockoutKindMFA: user.ID.String()} {
+		n, err := apiCfg.database.ClearLoginFailures(r.Context(), database.ClearLoginFailuresParams{Kind: kind, Subject: subject})
+		if err != nil {
+			log.Printf("Error clearing login failures: %v", err)
+			respondWithError(w, 500, "Error unlocking user")
+			return
+		}
+		cleared += n
+	}
+
+	if cleared > 0 {
+		err = recordModerationAction(r.Context(), apiCfg.database, principalFromRequest(r).UserID, actionUnlockUser, uuid.Nil, uuid.NullUUID{}, userID, "")
+		if err != nil {
+			log.Printf("Error recording moderation action: %v", err)
+		}
+	}
+
+	w.WriteHeader(204)
+}}
+
+func adminGetModerationActionsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.ModerationAction, error) {
+		cursorCreatedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetModerationActionsAscending(ctx, database.GetModerationActionsAscendingParams{
+				CursorCreatedAt: cursorCreatedAt,
+				CursorID: cursorID,
+				Limit: limit,
+			})
+		}
+		return apiCfg.database.GetModerationActionsDescending(ctx, database.GetModerationActionsDescendingParams{
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: limit,
+		})
+	}, func(action database.ModerationAction) (time.Time, uuid.UUID) {
+		return action.CreatedAt, action.ID
+	})
+	if err != nil {
+		log.Printf("Error fetching moderation actions: %v", err)
+		respondWithError(w, 500, "Error fetching moderation actions")
+		return
+	}
+
+	actionResponse := make([]ModerationActionResponse, len(page.Items))
+	for i, action := range page.Items {
+		actionResponse[i] = ModerationActionResponse{
+			ID: action.ID,
+			Action: action.Action,
+			ReportID: nullUUIDPtr(action.ReportID),
+			ChirpID: nullUUIDPtr(action.ChirpID),
+			UserID: nullUUIDPtr(action.UserID),
+			Note: action.Note,
+			ActorID: nullUUIDPtr(action.ActorID),
+			CreatedAt: action.CreatedAt,
+		}
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, ModerationActionPageResponse{
+		Actions: actionResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
+
+func decodeReportReason(r *http.Request) (string, error) {
+	type requestData struct {
+		Reason string `json:"reason"`
+	}
+
+	reqData := requestData{}
+	err := json.NewDecoder(r.Body).Decode(&reqData)
+	if err != nil {
+		return "", fmt.Errorf("Invalid request body")
+	}
+
+	reason := strings.TrimSpace(reqData.Reason)
+	if reason == "" {
+		return "", fmt.Errorf("A reason is required")
+	}
+	if utf8.RuneCountInString(reason) > maxReportReasonLength {
+		return "", fmt.Errorf("Reason must be at most %d characters", maxReportReasonLength)
+	}
+	return reason, nil
+}
+
+// recordModerationAction appends an entry to the moderation log, attributed to
+// the admin who took the action. Pass uuid.Nil as reportID for actions taken
+// outside of a report.
+func recordModerationAction(ctx context.Context, q *database.Queries, actorID uuid.UUID, action string, reportID uuid.UUID, chirpID uuid.NullUUID, userID uuid.UUID, note string) error {
+	_, err := q.CreateModerationAction(ctx, database.CreateModerationActionParams{
+		Action: action,
+		ReportID: uuid.NullUUID{UUID: reportID, Valid: reportID != uuid.Nil},
+		ChirpID: chirpID,
+		UserID: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		Note: strings.TrimSpace(note),
+		ActorID: uuid.NullUUID{UUID: actorID, Valid: actorID != uuid.Nil},
+	})
+	return err
+}
+
+func newReportResponse(report database.Report, chirpBody sql.NullString) ReportResponse {
+	response := ReportResponse{
+		ID: report.ID,
+		ReporterID: report.ReporterID,
+		ReportedUserID: report.ReportedUserID,
+		ChirpID: nullUUIDPtr(report.ChirpID),
+		ChirpBody: chirpBody.String,
+		Reason: report.Reason,
+		CreatedAt: report.CreatedAt,
+		Resolution: report.Resolution.String,
+	}
+	if report.ResolvedAt.Valid {
+		response.ResolvedAt = &report.ResolvedAt.Time
+	}
+	return response
+}
+
+func nullUUIDPtr(id uuid.NullUUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	return &id.UUID
+}
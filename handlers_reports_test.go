@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql/driver"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func TestDecodeReportReason(t *testing.T) {
+	tests := []struct {
+		body     string
+		expected string
+		valid    bool
+	}{
+		{`{"reason": "  spam  "}`, "spam", true},
+		{`{"reason": ""}`, "", false},
+		{`{"reason": "   "}`, "", false},
+		{`{"reason": "` + strings.Repeat("a", maxReportReasonLength+1) + `"}`, "", false},
+		{`not json`, "", false},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api/chirps/id/report", strings.NewReader(test.body))
+		reason, err := decodeReportReason(r)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected valid=%v for '%s', but got %v", test.valid, test.body, err)
+		}
+		if reason != test.expected {
+			t.Errorf("Expected '%s', but got '%s'", test.expected, reason)
+		}
+	}
+}
+
+func TestAdminSetUserRoleRecordsActor(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	admin := database.User{ID: uuid.New(), Role: auth.RoleAdmin}
+	target := database.User{ID: uuid.New(), Role: auth.RoleUser}
+
+	db.returns("GetUserById", admin)
+	db.on("SetUserRole", func(args []driver.Value) (any, error) {
+		target.Role = args[1].(string)
+		return target, nil
+	})
+	db.returns("CreateModerationAction", database.ModerationAction{ID: uuid.New()})
+
+	r := httptest.NewRequest("PUT", "/admin/users/"+target.ID.String()+"/role", strings.NewReader(`{"role": "admin"}`))
+	r.SetPathValue("userID", target.ID.String())
+	r.Header = bearer(t, apiCfg, admin)
+	w := httptest.NewRecorder()
+	apiCfg.middleWareAuth(adminSetUserRoleHandler(apiCfg), auth.ScopeAdmin).ServeHTTP(w, r)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	calls := db.calls("CreateModerationAction")
+	if len(calls) != 1 {
+		t.Fatalf("Expected one moderation action, but got %d", len(calls))
+	}
+	// Action, report, chirp, user, note, actor.
+	if calls[0][0] != actionSetUserRole || calls[0][3] != target.ID.String() || calls[0][5] != admin.ID.String() {
+		t.Errorf("Expected a set_role action on the target by the admin, but got %v", calls[0])
+	}
+	if db.commits != 1 {
+		t.Errorf("Expected the transaction to be committed")
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func newScheduledChirpResponse(scheduled database.ScheduledChirp) ScheduledChirpResponse {
+	response := ScheduledChirpResponse{
+		ID: scheduled.ID,
+		Body: scheduled.Body,
+		ParentID: nullUUIDPtr(scheduled.ParentID),
+		PublishAt: scheduled.PublishAt,
+		Status: scheduledChirpPending,
+		CreatedAt: scheduled.CreatedAt,
+	}
+	if scheduled.FailedAt.Valid {
+		response.Status = scheduledChirpFailed
+		response.Error = scheduled.Error.String
+	}
+	return response
+}
+
+func apiCreateScheduledChirpHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	type requestData struct {
+		Body string `json:"body"`
+		ParentID *uuid.UUID `json:"parent_id"`
+		PublishAt time.Time `json:"publish_at"`
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	user, err := apiCfg.database.GetUserById(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error scheduling chirp")
+		return
+	}
+	if apiCfg.requireVerifiedEmail && !user.EmailVerifiedAt.Valid {
+		respondWithError(w, 403, "Verify your email address before posting")
+		return
+	}
+	limits := userLimits(apiCfg, user)
+	if !limits.ScheduleChirps {
+		respondWithError(w, 403, "Your plan does not include scheduling chirps")
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	reqData := requestData{}
+	err = decoder.Decode(&reqData)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+
+	err = validatePublishAt(reqData.PublishAt, time.Now())
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	// The body is stored as written and moderated when it is published, but
+	// anything that would be rejected is turned away now.
+	_, err = cleanChirpBody(apiCfg.moderation, reqData.Body, limits.MaxChirpLength)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	parentID := uuid.NullUUID{}
+	if reqData.ParentID != nil {
+		_, err = validateChirpParent(r.Context(), apiCfg, userID, *reqData.ParentID)
+		var rejection *chirpRejection
+		if errors.As(err, &rejection) {
+			respondWithError(w, rejection.Code, rejection.Message)
+			return
+		}
+		if err != nil {
+			log.Printf("Error checking parent chirp: %v", err)
+			respondWithError(w, 500, "Error scheduling chirp")
+			return
+		}
+		parentID = uuid.NullUUID{UUID: *reqData.ParentID, Valid: true}
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		respondWithError(w, 500, "Error scheduling chirp")
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	// Locking the user keeps concurrent requests from all passing the count.
+	_, err = qtx.GetUserByIdForUpdate(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error locking user: %v", err)
+		respondWithError(w, 500, "Error scheduling chirp")
+		return
+	}
+
+	if limits.MaxScheduledChirps > 0 {
+		pending, err := qtx.CountPendingScheduledChirps(r.Context(), userID)
+		if err != nil {
+			log.Printf("Error counting scheduled chirps: %v", err)
+			respondWithError(w, 500, "Error scheduling chirp")
+			return
+		}
+		if pending >= int64(limits.MaxScheduledChirps) {
+			respondWithError(w, 403, fmt.Sprintf("You can have at most %d scheduled chirps", limits.MaxScheduledChirps))
+			return
+		}
+	}
+
+	scheduled, err := qtx.CreateScheduledChirp(r.Context(), database.CreateScheduledChirpParams{
+		UserID: userID,
+		Body: reqData.Body,
+		ParentID: parentID,
+		PublishAt: reqData.PublishAt.Local(),
+	})
+	if err != nil {
+		log.Printf("Error scheduling chirp: %v", err)
+		respondWithError(w, 500, "Error scheduling chirp")
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		respondWithError(w, 500, "Error scheduling chirp")
+		return
+	}
+
+	respondWithJSON(w, 201, newScheduledChirpResponse(scheduled))
+}}
+
+func apiGetScheduledChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	scheduled, err := apiCfg.database.GetScheduledChirps(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching scheduled chirps: %v", err)
+		respondWithError(w, 500, "Error fetching scheduled chirps")
+		return
+	}
+
+	scheduledResponse := make([]ScheduledChirpResponse, len(scheduled))
+	for i, chirp := range scheduled {
+		scheduledResponse[i] = newScheduledChirpResponse(chirp)
+	}
+
+	respondWithJSON(w, 200, scheduledResponse)
+}}
+
+func apiDeleteScheduledChirpHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	scheduledID, err := uuid.Parse(r.PathValue("scheduledID"))
+	if err != nil {
+		respondWithError(w, 400, "ScheduledID is invalid")
+		return
+	}
+
+	deleted, err := apiCfg.database.DeleteScheduledChirp(r.Context(), database.DeleteScheduledChirpParams{
+		ID: scheduledID,
+		UserID: principalFromRequest(r).UserID,
+	})
+	if err != nil {
+		log.Printf("Error deleting scheduled chirp: %v", err)
+		respondWithError(w, 500, "Error deleting scheduled chirp")
+		return
+	}
+	if deleted == 0 {
+		respondWithError(w, 404, "Scheduled chirp does not exist")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func apiSearchChirpsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		respondWithError(w, 400, "No search query provided")
+		return
+	}
+
+	viewerID := viewerIDFromRequest(r, apiCfg)
+	searchParams := database.SearchChirpsParams{Query: query, ViewerID: nullUUID(viewerID)}
+
+	authorID := r.URL.Query().Get("author_id")
+	if authorID != "" {
+		userID, err := uuid.Parse(authorID)
+		if err != nil {
+			respondWithError(w, 400, "Invalid author ID")
+			return
+		}
+		searchParams.AuthorID = uuid.NullUUID{UUID: userID, Valid: true}
+	}
+
+	var err error
+	searchParams.Since, err = parseTimeParam(r.URL.Query().Get("since"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid since date")
+		return
+	}
+	searchParams.Until, err = parseTimeParam(r.URL.Query().Get("until"))
+	if err != nil {
+		respondWithError(w, 400, "Invalid until date")
+		return
+	}
+
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+	if params.Cursor != nil {
+		searchParams.CursorRank = sql.NullFloat64{Float64: float64(params.Cursor.Rank), Valid: true}
+		searchParams.CursorCreatedAt, searchParams.CursorID = cursorParams(params.Cursor)
+	}
+	searchParams.Limit = params.Limit + 1
+
+	rows, err := searchChirps(r.Context(), apiCfg, searchParams, params.Cursor != nil && params.Cursor.Prev)
+	if err != nil {
+		log.Printf("Error searching chirps: %v", err)
+		respondWithError(w, 500, "Error searching chirps")
+		return
+	}
+
+	rows, nextCursor, prevCursor := searchPage(rows, params)
+
+	chirps := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = row.Chirp
+	}
+
+	chirpResponse, err := chirpResponses(r.Context(), apiCfg, viewerID, chirps)
+	if err != nil {
+		log.Printf("Error fetching chirp engagement: %v", err)
+		respondWithError(w, 500, "Error searching chirps")
+		return
+	}
+
+	setPaginationLinks(w, r, nextCursor, prevCursor)
+	respondWithJSON(w, 200, ChirpPageResponse{
+		Chirps: chirpResponse,
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
+	})
+}}
+
+// searchChirps runs the search forwards from the cursor, or backwards towards
+// more relevant results for a prev cursor. Backward rows come back in reverse
+// order and are put right by searchPage.
+func searchChirps(ctx context.Context, apiCfg *apiConfig, params database.SearchChirpsParams, backwards bool) ([]database.SearchChirpsRow, error) {
+	if !backwards {
+		return apiCfg.database.SearchChirps(ctx, params)
+	}
+	rows, err := apiCfg.database.SearchChirpsBackward(ctx, database.SearchChirpsBackwardParams(params))
+	if err != nil {
+		return nil, err
+	}
+	result := make([]database.SearchChirpsRow, len(rows))
+	for i, row := range rows {
+		result[i] = database.SearchChirpsRow(row)
+	}
+	return result, nil
+}
+
+// searchPage trims rows fetched with one extra to the page limit and works
+// out the cursors the same way fetchPage does, carrying the rank along since
+// results are ordered by relevance first.
+func searchPage(rows []database.SearchChirpsRow, params pageParams) ([]database.SearchChirpsRow, string, string) {
+	backwards := params.Cursor != nil && params.Cursor.Prev
+
+	hasMore := len(rows) > int(params.Limit)
+	if hasMore {
+		rows = rows[:params.Limit]
+	}
+	if backwards {
+		slices.Reverse(rows)
+	}
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	var nextCursor, prevCursor string
+	if hasMore || backwards {
+		last := rows[len(rows)-1]
+		nextCursor = encodeCursor(pageCursor{CreatedAt: last.Chirp.CreatedAt, ID: last.Chirp.ID, Rank: last.Rank})
+	}
+	if params.Cursor != nil && (hasMore || !backwards) {
+		first := rows[0]
+		prevCursor = encodeCursor(pageCursor{CreatedAt: first.Chirp.CreatedAt, ID: first.Chirp.ID, Rank: first.Rank, Prev: true})
+	}
+	return rows, nextCursor, prevCursor
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or a plain date.
+func parseTimeParam(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	for _, layout := range []string{time.RFC3339, time.DateOnly} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return sql.NullTime{Time: t.UTC(), Valid: true}, nil
+		}
+	}
+	return sql.NullTime{}, fmt.Errorf("invalid time %q", value)
+}
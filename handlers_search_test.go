@@ -0,0 +1,79 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func TestParseTimeParam(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected time.Time
+		valid    bool
+	}{
+		{"", time.Time{}, false},
+		{"2025-02-03", time.Date(2025, 2, 3, 0, 0, 0, 0, time.UTC), true},
+		{"2025-02-03T10:00:00+02:00", time.Date(2025, 2, 3, 8, 0, 0, 0, time.UTC), true},
+	}
+
+	for _, test := range tests {
+		result, err := parseTimeParam(test.input)
+		if err != nil {
+			t.Fatalf("Expected no error for '%s', but got %v", test.input, err)
+		}
+		if result.Valid != test.valid || !result.Time.Equal(test.expected) {
+			t.Errorf("Expected '%v', but got '%v'", test.expected, result.Time)
+		}
+	}
+
+	_, err := parseTimeParam("yesterday")
+	if err == nil {
+		t.Errorf("Expected an error for an invalid date")
+	}
+}
+
+func TestSearchPage(t *testing.T) {
+	now := time.Now().UTC()
+	rows := make([]database.SearchChirpsRow, 3)
+	for i := range rows {
+		rows[i] = database.SearchChirpsRow{
+			Chirp: database.Chirp{ID: uuid.New(), CreatedAt: now.Add(-time.Duration(i) * time.Minute)},
+			Rank:  float32(3 - i),
+		}
+	}
+
+	page, next, prev := searchPage(rows, pageParams{Limit: 2})
+	if len(page) != 2 || page[0].Chirp.ID != rows[0].Chirp.ID {
+		t.Fatalf("Expected the first two rows, but got %v", page)
+	}
+	if prev != "" {
+		t.Errorf("Expected no prev cursor on the first page, but got '%s'", prev)
+	}
+	cursor, err := decodeCursor(next)
+	if err != nil || cursor.ID != rows[1].Chirp.ID || cursor.Rank != rows[1].Rank || cursor.Prev {
+		t.Errorf("Expected a next cursor at the second row, but got %+v", cursor)
+	}
+
+	// A backward query returns the rows closest to the cursor first.
+	backward := []database.SearchChirpsRow{rows[1], rows[0]}
+	page, next, prev = searchPage(backward, pageParams{Limit: 2, Cursor: &pageCursor{Prev: true}})
+	if len(page) != 2 || page[0].Chirp.ID != rows[0].Chirp.ID {
+		t.Fatalf("Expected the rows back in relevance order, but got %v", page)
+	}
+	if prev != "" {
+		t.Errorf("Expected no prev cursor at the start of the results, but got '%s'", prev)
+	}
+	cursor, err = decodeCursor(next)
+	if err != nil || cursor.ID != rows[1].Chirp.ID || cursor.Prev {
+		t.Errorf("Expected a next cursor at the last row, but got %+v", cursor)
+	}
+
+	_, _, prev = searchPage(rows[2:], pageParams{Limit: 2, Cursor: &pageCursor{}})
+	cursor, err = decodeCursor(prev)
+	if err != nil || cursor.ID != rows[2].Chirp.ID || cursor.Rank != rows[2].Rank || !cursor.Prev {
+		t.Errorf("Expected a prev cursor at the first row, but got %+v", cursor)
+	}
+}
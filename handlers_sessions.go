@@ -0,0 +1,84 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func apiGetSessionsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	sessions, err := apiCfg.database.GetActiveSessions(r.Context(), userID)
+	if err != nil {
+		log.Printf("Error fetching sessions: %v", err)
+		respondWithError(w, 500, "Error fetching sessions")
+		return
+	}
+
+	sessionResponse := make([]SessionResponse, len(sessions))
+	for i, session := range sessions {
+		sessionResponse[i] = SessionResponse{
+			ID: session.FamilyID,
+			UserAgent: session.UserAgent,
+			IPAddress: session.IpAddress,
+			CreatedAt: session.StartedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt: session.ExpiresAt,
+		}
+	}
+
+	respondWithJSON(w, 200, sessionResponse)
+}}
+
+func apiRevokeSessionHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+	if err != nil {
+		respondWithError(w, 400, "SessionID is invalid")
+		return
+	}
+
+	userID := principalFromRequest(r).UserID
+
+	revoked, err := apiCfg.database.RevokeUserRefreshTokenFamily(r.Context(), database.RevokeUserRefreshTokenFamilyParams{UserID: userID, FamilyID: sessionID})
+	if err != nil {
+		log.Printf("Error revoking session: %v", err)
+		respondWithError(w, 500, "Error revoking session")
+		return
+	}
+	if revoked == 0 {
+		respondWithError(w, 404, "Session does not exist")
+		return
+	}
+
+	w.WriteHeader(204)
+}}
+
+// apiRevokeOtherSessionsHandler takes the refresh token of the session to keep,
+// since access tokens do not identify the session they were issued for.
+func apiRevokeOtherSessionsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	refresh, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		respondWithError(w, 401, err.Error())
+		return
+	}
+
+	refreshEntry, err := apiCfg.database.GetUserFromRefreshToken(r.Context(), refresh)
+	if err != nil || refreshEntry.ExpiresAt.Before(time.Now()) || refreshEntry.RevokedAt.Valid {
+		respondWithError(w, 401, "Invalid or expired token")
+		return
+	}
+
+	revoked, err := apiCfg.database.RevokeOtherRefreshTokenFamilies(r.Context(), database.RevokeOtherRefreshTokenFamiliesParams{UserID: refreshEntry.UserID, FamilyID: refreshEntry.FamilyID})
+	if err != nil {
+		log.Printf("Error revoking sessions: %v", err)
+		respondWithError(w, 500, "Error revoking sessions")
+		return
+	}
+
+	respondWithJSON(w, 200, RevokeSessionsResponse{Revoked: revoked})
+}}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/isotronic/http-go-server/internal/entitlements"
+)
+
+func apiGetSubscriptionHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	userID := principalFromRequest(r).UserID
+
+	sub, err := apiCfg.database.GetSubscription(r.Context(), userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		respondWithError(w, 404, "You do not have a subscription")
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching subscription: %v", err)
+		respondWithError(w, 500, "Error fetching subscription")
+		return
+	}
+
+	response := SubscriptionResponse{
+		Plan: sub.Plan,
+		Status: subscriptionStatus(sub, time.Now()),
+		CurrentPeriodEnd: sub.CurrentPeriodEnd,
+	}
+	if sub.CanceledAt.Valid {
+		response.CanceledAt = &sub.CanceledAt.Time
+	}
+	respondWithJSON(w, 200, response)
+}}
+
+func apiGetEntitlementsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	user, err := apiCfg.database.GetUserById(r.Context(), principalFromRequest(r).UserID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		respondWithError(w, 500, "Error fetching entitlements")
+		return
+	}
+
+	limits := userLimits(apiCfg, user)
+	respondWithJSON(w, 200, EntitlementsResponse{
+		Tier: string(entitlements.TierOf(user.IsChirpyRed)),
+		MaxChirpLength: limits.MaxChirpLength,
+		ChirpsPerHour: limits.ChirpsPerHour,
+		EditChirps: limits.EditChirps,
+		ScheduleChirps: limits.ScheduleChirps,
+		MaxScheduledChirps: limits.MaxScheduledChirps,
+	})
+}}
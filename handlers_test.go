@@ -0,0 +1,298 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/entitlements"
+	"github.com/isotronic/http-go-server/internal/moderation"
+)
+
+func TestUpdateChirp(t *testing.T) {
+	owner := database.User{ID: uuid.New(), IsChirpyRed: true}
+	stranger := database.User{ID: uuid.New(), IsChirpyRed: true}
+	createdAt := time.Now().Add(-time.Hour).UTC()
+	chirp := database.Chirp{ID: uuid.New(), UserID: owner.ID, Body: "first draft", CreatedAt: createdAt, UpdatedAt: createdAt}
+
+	tests := []struct {
+		name     string
+		user     database.User
+		body     string
+		expected int
+		edited   bool
+	}{
+		{"owner", owner, "second draft", 200, true},
+		{"someone else", stranger, "second draft", 403, false},
+		{"too long", owner, strings.Repeat("é", 141), 400, false},
+		{"prohibited language", owner, "fornax", 400, false},
+		{"unchanged", owner, "first draft", 200, false},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		apiCfg.entitlements.Red = entitlements.Limits{MaxChirpLength: 140, EditChirps: true}
+		filter, err := moderation.NewFilter([]moderation.Rule{{Pattern: "fornax", Match: moderation.MatchWord, Action: moderation.ActionReject}})
+		if err != nil {
+			t.Fatalf("Expected no error creating the filter, but got %v", err)
+		}
+		apiCfg.moderation = filter
+
+		db.returns("GetUserById", test.user)
+		db.returns("GetChirpByIdForUpdate", chirp)
+		db.on("CreateChirpRevision", func(args []driver.Value) (any, error) {
+			return database.ChirpRevision{ID: uuid.New(), ChirpID: chirp.ID, Body: args[1].(string), CreatedAt: args[2].(time.Time)}, nil
+		})
+		db.on("UpdateChirpBody", func(args []driver.Value) (any, error) {
+			updated := chirp
+			updated.Body = args[1].(string)
+			return updated, nil
+		})
+		db.returns("DeleteChirpHashtags", nil)
+		db.returns("DeleteChirpMentions", nil)
+		db.returns("GetChirpMentions", nil)
+		db.returns("GetViewerEngagement", nil)
+
+		body, _ := json.Marshal(map[string]string{"body": test.body})
+		r := withUser(httptest.NewRequest("PUT", "/api/chirps/"+chirp.ID.String(), strings.NewReader(string(body))), test.user.ID)
+		r.SetPathValue("chirpID", chirp.ID.String())
+		w := httptest.NewRecorder()
+		apiUpdateChirpHandler(apiCfg)(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d: %s", test.name, test.expected, w.Code, w.Body.String())
+			continue
+		}
+
+		revisions := db.calls("CreateChirpRevision")
+		if !test.edited {
+			if len(revisions) != 0 || len(db.calls("UpdateChirpBody")) != 0 {
+				t.Errorf("%s: expected the chirp to be left alone", test.name)
+			}
+			continue
+		}
+		if len(revisions) != 1 || revisions[0][1] != chirp.Body || !revisions[0][2].(time.Time).Equal(createdAt) {
+			t.Errorf("%s: expected the previous body to be saved as a revision, but got %v", test.name, revisions)
+		}
+		if db.commits != 1 {
+			t.Errorf("%s: expected the edit to be committed", test.name)
+		}
+		var response ChirpResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		if response.Body != test.body {
+			t.Errorf("%s: expected body '%s', but got '%s'", test.name, test.body, response.Body)
+		}
+	}
+}
+
+func TestGetChirpRevisions(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	chirp := database.Chirp{ID: uuid.New(), UserID: uuid.New(), Body: "third draft"}
+	now := time.Now().UTC()
+	revisions := []database.ChirpRevision{
+		{ID: uuid.New(), ChirpID: chirp.ID, Body: "second draft", CreatedAt: now.Add(-time.Minute)},
+		{ID: uuid.New(), ChirpID: chirp.ID, Body: "first draft", CreatedAt: now.Add(-time.Hour)},
+	}
+	db.returns("GetChirpById", chirp)
+	db.returns("GetChirpRevisions", revisions)
+
+	r := httptest.NewRequest("GET", "/api/chirps/"+chirp.ID.String()+"/revisions", nil)
+	r.SetPathValue("chirpID", chirp.ID.String())
+	w := httptest.NewRecorder()
+	apiGetChirpRevisionsHandler(apiCfg)(w, r)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response []ChirpRevisionResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if len(response) != 2 || response[0].Body != "second draft" || response[1].Body != "first draft" {
+		t.Errorf("Expected the revisions newest first, but got %+v", response)
+	}
+}
+
+// fakeRefreshTokens keeps the refresh_tokens table of a fakeDB in memory,
+// following what the queries do.
+func fakeRefreshTokens(db *fakeDB) map[string]*database.RefreshToken {
+	tokens := map[string]*database.RefreshToken{}
+	db.on("GetRefreshTokenForUpdate", func(args []driver.Value) (any, error) {
+		token, ok := tokens[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return *token, nil
+	})
+	db.on("InsertRefreshToken", func(args []driver.Value) (any, error) {
+		token := &database.RefreshToken{
+			Token:     args[0].(string),
+			UserID:    uuid.MustParse(args[1].(string)),
+			ExpiresAt: args[2].(time.Time),
+			FamilyID:  uuid.MustParse(args[3].(string)),
+			CreatedAt: time.Now(),
+		}
+		tokens[token.Token] = token
+		return *token, nil
+	})
+	db.on("RotateRefreshToken", func(args []driver.Value) (any, error) {
+		token := tokens[args[0].(string)]
+		token.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		token.ReplacedBy = sql.NullString{String: args[1].(string), Valid: true}
+		return *token, nil
+	})
+	db.on("RevokeRefreshTokenFamily", func(args []driver.Value) (any, error) {
+		revoked := int64(0)
+		for _, token := range tokens {
+			if token.FamilyID.String() == args[0].(string) && !token.RevokedAt.Valid {
+				token.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+				revoked++
+			}
+		}
+		return revoked, nil
+	})
+	return tokens
+}
+
+func TestRefreshRotation(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New()}
+	db.returns("GetUserById", user)
+	tokens := fakeRefreshTokens(db)
+	expiresAt := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	familyID := uuid.New()
+	tokens["first"] = &database.RefreshToken{Token: "first", UserID: user.ID, FamilyID: familyID, ExpiresAt: expiresAt}
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/api/refresh", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		apiRefreshHandler(apiCfg)(w, r)
+		return w
+	}
+
+	w := refresh("first")
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response RefreshResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	second, ok := tokens[response.RefreshToken]
+	if !ok || response.AccessToken == "" {
+		t.Fatalf("Expected a new access and refresh token, but got %+v", response)
+	}
+	if second.FamilyID != familyID {
+		t.Errorf("Expected the new token to stay in family %v, but got %v", familyID, second.FamilyID)
+	}
+	if !second.ExpiresAt.Equal(expiresAt) {
+		t.Errorf("Expected the new token to keep the family's expiry %v, but got %v", expiresAt, second.ExpiresAt)
+	}
+	if tokens["first"].ReplacedBy.String != second.Token || !tokens["first"].RevokedAt.Valid {
+		t.Errorf("Expected the old token to be revoked and replaced by the new one")
+	}
+
+	commits := db.commits
+	w = refresh("first")
+	if w.Code != 401 {
+		t.Errorf("Expected a reused token to be rejected with 401, but got %d", w.Code)
+	}
+	if !second.RevokedAt.Valid || db.commits != commits+1 {
+		t.Errorf("Expected reusing a rotated token to revoke and commit the whole family")
+	}
+	if refresh(second.Token).Code != 401 {
+		t.Errorf("Expected the rest of the family to be unusable")
+	}
+}
+
+func TestRefreshRejectsInvalidTokens(t *testing.T) {
+	active := database.User{ID: uuid.New()}
+	suspended := database.User{ID: uuid.New(), SuspendedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	later := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name     string
+		token    *database.RefreshToken
+		expected int
+	}{
+		{"unknown", nil, 401},
+		{"expired", &database.RefreshToken{UserID: active.ID, ExpiresAt: time.Now().Add(-time.Minute)}, 401},
+		{"revoked", &database.RefreshToken{UserID: active.ID, ExpiresAt: later, RevokedAt: sql.NullTime{Time: time.Now(), Valid: true}}, 401},
+		{"suspended user", &database.RefreshToken{UserID: suspended.ID, ExpiresAt: later}, 403},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		tokens := fakeRefreshTokens(db)
+		db.on("GetUserById", func(args []driver.Value) (any, error) {
+			if args[0] == suspended.ID.String() {
+				return suspended, nil
+			}
+			return active, nil
+		})
+		if test.token != nil {
+			test.token.Token = "token"
+			test.token.FamilyID = uuid.New()
+			tokens["token"] = test.token
+		}
+
+		r := httptest.NewRequest("POST", "/api/refresh", nil)
+		r.Header.Set("Authorization", "Bearer token")
+		w := httptest.NewRecorder()
+		apiRefreshHandler(apiCfg)(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d", test.name, test.expected, w.Code)
+		}
+		if len(db.calls("InsertRefreshToken")) != 0 || len(db.calls("RotateRefreshToken")) != 0 {
+			t.Errorf("%s: expected no new token", test.name)
+		}
+	}
+}
+
+func TestAdminReset(t *testing.T) {
+	user := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	admin := database.User{ID: uuid.New(), Role: auth.RoleAdmin}
+
+	tests := []struct {
+		name     string
+		user     *database.User
+		platform string
+		expected int
+	}{
+		{"anonymous", nil, "dev", 401},
+		{"user", &user, "dev", 403},
+		{"admin in production", &admin, "prod", 403},
+		{"admin without a platform", &admin, "", 403},
+		{"admin in dev", &admin, "dev", 200},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		apiCfg.platform = test.platform
+		apiCfg.fileServerHits.Store(3)
+		db.returns("ResetUsers", nil)
+		db.returns("ResetChirps", nil)
+		db.returns("ResetRefreshTokens", nil)
+
+		r := httptest.NewRequest("POST", "/admin/reset", nil)
+		if test.user != nil {
+			db.returns("GetUserById", *test.user)
+			r.Header = bearer(t, apiCfg, *test.user)
+		}
+		w := httptest.NewRecorder()
+		apiCfg.middleWareAuth(apiCfg.middleWareMetricsReset(adminResetHandler(apiCfg)).ServeHTTP, auth.ScopeAdmin).ServeHTTP(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d", test.name, test.expected, w.Code)
+		}
+		reset := test.expected == 200
+		if (len(db.calls("ResetUsers")) == 1) != reset || (apiCfg.fileServerHits.Load() == 0) != reset {
+			t.Errorf("%s: expected the reset to happen only when allowed", test.name)
+		}
+	}
+}
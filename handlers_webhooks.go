@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/webhook"
+)
+
+const (
+	polkaSignatureHeader = "Polka-Signature"
+	maxWebhookBodySize = 64 << 10
+)
+
+const (
+	webhookEventPending = "pending"
+	webhookEventProcessed = "processed"
+	webhookEventIgnored = "ignored"
+	webhookEventFailed = "failed"
+)
+
+var webhookEventStatuses = []string{webhookEventPending, webhookEventProcessed, webhookEventIgnored, webhookEventFailed}
+
+// polkaEvent is a webhook delivery from Polka. ID stays the same when Polka
+// retries a delivery.
+type polkaEvent struct {
+	ID string `json:"id"`
+	Event string `json:"event"`
+	Data struct {
+		UserID string `json:"user_id"`
+		Plan string `json:"plan"`
+		CurrentPeriodEnd *time.Time `json:"current_period_end"`
+	} `json:"data"`
+}
+
+// webhookEventError is a problem with the content of an event. Delivering the
+// same event again will not fix it, so it is recorded as failed instead of
+// being rolled back.
+type webhookEventError struct {
+	Code int
+	Message string
+}
+
+func (e *webhookEventError) Error() string {
+	return e.Message
+}
+
+// authenticatePolkaWebhook checks the signature of a delivery. Without a
+// webhook secret it falls back to the static API key, which does not protect
+// against replays.
+func authenticatePolkaWebhook(apiCfg *apiConfig, header http.Header, body []byte) bool {
+	if apiCfg.polkaWebhookSecret != "" {
+		err := webhook.Verify(header.Get(polkaSignatureHeader), body, apiCfg.polkaWebhookSecret, time.Now(), webhook.DefaultTolerance)
+		if err != nil {
+			log.Printf("Rejected Polka webhook: %v", err)
+			return false
+		}
+		return true
+	}
+
+	apiKey, err := auth.GetAPIKey(header)
+	return err == nil && apiKey == apiCfg.polkaKey
+}
+
+// applyPolkaEvent makes the changes an event asks for and reports whether the
+// event type is one Chirpy handles. It only returns a *webhookEventError
+// before it has written anything.
+func applyPolkaEvent(ctx context.Context, q *database.Queries, event polkaEvent) (bool, error) {
+	switch event.Event {
+	case "user.upgraded", "user.downgraded", "subscription.renewed", "subscription.canceled", "subscription.payment_failed":
+	default:
+		return false, nil
+	}
+
+	userID, err := uuid.Parse(event.Data.UserID)
+	if err != nil {
+		return true, &webhookEventError{400, "user_id is invalid"}
+	}
+
+	switch event.Event {
+	case "user.upgraded", "subscription.renewed":
+		periodEnd := time.Now().Add(defaultSubscriptionPeriod)
+		if event.Data.CurrentPeriodEnd != nil {
+			periodEnd = event.Data.CurrentPeriodEnd.Local()
+		}
+		return true, activateSubscription(ctx, q, userID, event.Data.Plan, periodEnd)
+	case "user.downgraded":
+		return true, endSubscription(ctx, q, userID)
+	case "subscription.canceled":
+		return true, changeSubscriptionStatus(ctx, q, userID, subscriptionCanceled)
+	default:
+		return true, changeSubscriptionStatus(ctx, q, userID, subscriptionPastDue)
+	}
+}
+
+func apiPolkaWebhooksHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodySize))
+	if err != nil {
+		respondWithError(w, 400, "Error reading request body")
+		return
+	}
+
+	if !authenticatePolkaWebhook(apiCfg, r.Header, body) {
+		respondWithError(w, 401, "You are not authorized")
+		return
+	}
+
+	event := polkaEvent{}
+	err = json.Unmarshal(body, &event)
+	if err != nil {
+		respondWithError(w, 400, "Invalid request body")
+		return
+	}
+	if event.ID == "" {
+		respondWithError(w, 400, "Event id is missing")
+		return
+	}
+
+	tx, err := apiCfg.db.BeginTx(r.Context(), nil)
+	if err != nil {
+		log.Printf("Error starting transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	// Concurrent deliveries of the same event wait here for the row lock, so
+	// only one of them applies it.
+	received, err := qtx.ReceiveWebhookEvent(r.Context(), database.ReceiveWebhookEventParams{
+		EventID: event.ID,
+		Event: event.Event,
+		Payload: body,
+	})
+	if err != nil {
+		log.Printf("Error recording webhook event: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	if received.Status == webhookEventProcessed || received.Status == webhookEventIgnored {
+		err = tx.Commit()
+		if err != nil {
+			log.Printf("Error committing transaction: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		w.WriteHeader(204)
+		return
+	}
+
+	handled, err := applyPolkaEvent(r.Context(), qtx, event)
+	var eventErr *webhookEventError
+	if err != nil && !errors.As(err, &eventErr) {
+		log.Printf("Error applying webhook event %s: %v", event.ID, err)
+		w.WriteHeader(500)
+		return
+	}
+
+	outcome := database.FinishWebhookEventParams{ID: received.ID, Status: webhookEventProcessed}
+	if !handled {
+		outcome.Status = webhookEventIgnored
+	}
+	if eventErr != nil {
+		outcome.Status = webhookEventFailed
+		outcome.Error = sql.NullString{String: eventErr.Message, Valid: true}
+	}
+	err = qtx.FinishWebhookEvent(r.Context(), outcome)
+	if err != nil {
+		log.Printf("Error recording webhook event outcome: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Printf("Error committing transaction: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	if eventErr != nil {
+		respondWithError(w, eventErr.Code, eventErr.Message)
+		return
+	}
+	w.WriteHeader(204)
+}}
+
+func adminGetWebhookEventsHandler(apiCfg *apiConfig) http.HandlerFunc { return func(w http.ResponseWriter, r *http.Request) {
+	params, err := parsePageParams(r)
+	if err != nil {
+		respondWithError(w, 400, err.Error())
+		return
+	}
+
+	status := sql.NullString{}
+	if statusQuery := r.URL.Query().Get("status"); statusQuery != "" {
+		if !slices.Contains(webhookEventStatuses, statusQuery) {
+			respondWithError(w, 400, "Status must be one of pending, processed, ignored or failed")
+			return
+		}
+		status = sql.NullString{String: statusQuery, Valid: true}
+	}
+
+	page, err := fetchPage(r.Context(), params, true, func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.WebhookEvent, error) {
+		cursorReceivedAt, cursorID := cursorParams(cursor)
+		if ascending {
+			return apiCfg.database.GetWebhookEventsAscending(ctx, database.GetWebhookEventsAscendingParams{
+				CursorReceivedAt: cursorReceivedAt,
+				CursorID: cursorID,
+				Status: status,
+				Limit: limit,
+			})
+		}
+		return apiCfg.database.GetWebhookEventsDescending(ctx, database.GetWebhookEventsDescendingParams{
+			CursorReceivedAt: cursorReceivedAt,
+			CursorID: cursorID,
+			Status: status,
+			Limit: limit,
+		})
+	}, func(event database.WebhookEvent) (time.Time, uuid.UUID) {
+		return event.ReceivedAt, event.ID
+	})
+	if err != nil {
+		log.Printf("Error fetching webhook events: %v", err)
+		respondWithError(w, 500, "Error fetching webhook events")
+		return
+	}
+
+	eventResponse := make([]WebhookEventResponse, len(page.Items))
+	for i, event := range page.Items {
+		eventResponse[i] = WebhookEventResponse{
+			ID: event.ID,
+			EventID: event.EventID,
+			Event: event.Event,
+			Status: event.Status,
+			Attempts: event.Attempts,
+			Payload: event.Payload,
+			ReceivedAt: event.ReceivedAt,
+		}
+		if event.Error.Valid {
+			eventResponse[i].Error = event.Error.String
+		}
+		if event.ProcessedAt.Valid {
+			eventResponse[i].ProcessedAt = &event.ProcessedAt.Time
+		}
+	}
+
+	setPaginationLinks(w, r, page.NextCursor, page.PrevCursor)
+	respondWithJSON(w, 200, WebhookEventPageResponse{
+		Events: eventResponse,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+	})
+}}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/isotronic/http-go-server/internal/webhook"
+)
+
+func TestAuthenticatePolkaWebhook(t *testing.T) {
+	body := []byte(`{"id":"evt_1","event":"user.upgraded","data":{"user_id":"3311741c-680c-4546-99f3-fc9efac2036c"}}`)
+
+	signed := &apiConfig{polkaKey: "f271c81ff7084ee5b99a5091b42d486e", polkaWebhookSecret: "whsec_test"}
+	tests := []struct {
+		name string
+		apiCfg *apiConfig
+		header http.Header
+		want bool
+	}{
+		{"valid signature", signed, http.Header{polkaSignatureHeader: {webhook.Sign("whsec_test", time.Now(), body)}}, true},
+		{"replayed signature", signed, http.Header{polkaSignatureHeader: {webhook.Sign("whsec_test", time.Now().Add(-time.Hour), body)}}, false},
+		{"wrong secret", signed, http.Header{polkaSignatureHeader: {webhook.Sign("whsec_other", time.Now(), body)}}, false},
+		{"api key once a secret is set", signed, http.Header{"Authorization": {"ApiKey f271c81ff7084ee5b99a5091b42d486e"}}, false},
+		{"api key without a secret", &apiConfig{polkaKey: "f271c81ff7084ee5b99a5091b42d486e"}, http.Header{"Authorization": {"ApiKey f271c81ff7084ee5b99a5091b42d486e"}}, true},
+		{"wrong api key", &apiConfig{polkaKey: "f271c81ff7084ee5b99a5091b42d486e"}, http.Header{"Authorization": {"ApiKey nope"}}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := authenticatePolkaWebhook(tc.apiCfg, tc.header, body)
+			if got != tc.want {
+				t.Errorf("Expected %v, but got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestPolkaWebhooksHandler_RejectsBeforeRecording(t *testing.T) {
+	apiCfg := &apiConfig{polkaWebhookSecret: "whsec_test"}
+	handler := apiPolkaWebhooksHandler(apiCfg)
+
+	tests := []struct {
+		name string
+		body string
+		sign bool
+		want int
+	}{
+		{"unsigned", `{"id":"evt_1","event":"user.upgraded"}`, false, 401},
+		{"invalid json", `{"id":`, true, 400},
+		{"missing event id", `{"event":"user.upgraded"}`, true, 400},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/polka/webhooks", strings.NewReader(tc.body))
+			if tc.sign {
+				req.Header.Set(polkaSignatureHeader, webhook.Sign("whsec_test", time.Now(), []byte(tc.body)))
+			}
+			w := httptest.NewRecorder()
+			handler(w, req)
+			if w.Code != tc.want {
+				t.Errorf("Expected status %d, but got %d", tc.want, w.Code)
+			}
+		})
+	}
+}
+
+func TestApplyPolkaEvent_WithoutWrites(t *testing.T) {
+	handled, err := applyPolkaEvent(context.Background(), nil, polkaEvent{Event: "user.created"})
+	if handled || err != nil {
+		t.Errorf("Expected an unknown event to be ignored, but got %v, %v", handled, err)
+	}
+
+	for _, name := range []string{"user.upgraded", "user.downgraded", "subscription.renewed", "subscription.canceled", "subscription.payment_failed"} {
+		event := polkaEvent{Event: name}
+		event.Data.UserID = "not-a-uuid"
+		handled, err = applyPolkaEvent(context.Background(), nil, event)
+		var eventErr *webhookEventError
+		if !handled || !errors.As(err, &eventErr) || eventErr.Code != 400 {
+			t.Errorf("Expected %s with an invalid user_id to fail with 400, but got %v, %v", name, handled, err)
+		}
+	}
+}
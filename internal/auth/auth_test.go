@@ -40,48 +40,37 @@ func TestCheckPasswordHash_InvalidPassword(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestMakeJWT(t *testing.T) {
-	userID := uuid.New()
-	tokenSecret := "mysecret"
-	expiresIn := time.Hour
-
-	token, err := MakeJWT(userID, tokenSecret, expiresIn)
+func TestKeySet_HS256(t *testing.T) {
+	keys, err := NewKeySet(nil, "", "mysecret")
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
-}
 
-func TestValidateJWT(t *testing.T) {
 	userID := uuid.New()
-	tokenSecret := "mysecret"
-	expiresIn := time.Hour
-
-	token, err := MakeJWT(userID, tokenSecret, expiresIn)
+	token, err := keys.MakeJWT(Principal{UserID: userID}, time.Hour)
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
-	validatedUserID, err := ValidateJWT(token, tokenSecret)
+	principal, err := keys.ValidateJWT(token)
 	assert.NoError(t, err)
-	assert.Equal(t, userID, validatedUserID)
-}
+	assert.Equal(t, userID, principal.UserID)
 
-func TestValidateJWT_InvalidToken(t *testing.T) {
-	tokenSecret := "mysecret"
-	invalidToken := "invalidtoken"
+	_, err = keys.ValidateJWT("invalidtoken")
+	assert.Error(t, err)
 
-	_, err := ValidateJWT(invalidToken, tokenSecret)
+	otherKeys, err := NewKeySet(nil, "", "othersecret")
+	assert.NoError(t, err)
+	_, err = otherKeys.ValidateJWT(token)
 	assert.Error(t, err)
 }
 
-func TestValidateJWT_ExpiredToken(t *testing.T) {
-	userID := uuid.New()
-	tokenSecret := "mysecret"
-	expiresIn := -time.Hour // Token already expired
+func TestKeySet_ExpiredToken(t *testing.T) {
+	keys, err := NewKeySet(nil, "", "mysecret")
+	assert.NoError(t, err)
 
-	token, err := MakeJWT(userID, tokenSecret, expiresIn)
+	token, err := keys.MakeJWT(Principal{UserID: uuid.New()}, -time.Hour) // Token already expired
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 
-	_, err = ValidateJWT(token, tokenSecret)
+	_, err = keys.ValidateJWT(token)
 	assert.Error(t, err)
 }
 
@@ -161,3 +150,18 @@ func TestGetAPIKey_MissingAPIKey(t *testing.T) {
 	assert.Error(t, err)
 	assert.Equal(t, "api key missing", err.Error())
 }
+
+func TestMakePersonalAccessToken(t *testing.T) {
+	token, err := MakePersonalAccessToken()
+	assert.NoError(t, err)
+	assert.True(t, IsPersonalAccessToken(token))
+	assert.Len(t, token, len(PersonalAccessTokenPrefix)+64)
+
+	other, err := MakePersonalAccessToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestIsPersonalAccessToken_JWT(t *testing.T) {
+	assert.False(t, IsPersonalAccessToken("eyJhbGciOiJIUzI1NiJ9.e30.signature"))
+}
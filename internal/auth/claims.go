@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
+const (
+	// ScopeRead covers private reads such as the home timeline and block lists.
+	ScopeRead = "read"
+	// ScopeWrite covers posting, engagement, follows, blocks and reports.
+	ScopeWrite = "write"
+	// ScopeAccount covers changes to the account itself and its sessions.
+	ScopeAccount = "account"
+	// ScopeAdmin covers the /admin endpoints and is only granted to admins.
+	ScopeAdmin = "admin"
+)
+
+// Claims are the claims carried by access tokens. Scope is a space-delimited
+// list as in RFC 8693. Tokens issued to an OAuth client also carry its
+// client_id, and the grant they belong to as the token ID.
+type Claims struct {
+	Role     string `json:"role,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	ClientID string `json:"client_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// Principal is the authenticated caller of a request. ClientID and GrantID
+// are only set when an OAuth client acts on the user's behalf.
+type Principal struct {
+	UserID   uuid.UUID
+	Role     string
+	Scopes   []string
+	ClientID uuid.UUID
+	GrantID  uuid.UUID
+}
+
+func ValidRole(role string) bool {
+	return role == RoleUser || role == RoleAdmin
+}
+
+func ValidScope(scope string) bool {
+	return scope == ScopeRead || scope == ScopeWrite || scope == ScopeAccount || scope == ScopeAdmin
+}
+
+// ScopesForRole returns the scopes granted to a login of the given role.
+func ScopesForRole(role string) []string {
+	scopes := []string{ScopeRead, ScopeWrite, ScopeAccount}
+	if role == RoleAdmin {
+		scopes = append(scopes, ScopeAdmin)
+	}
+	return scopes
+}
+
+func (p Principal) HasScope(scope string) bool {
+	return slices.Contains(p.Scopes, scope)
+}
+
+// MissingScope returns the first of scopes the principal lacks, or "".
+func (p Principal) MissingScope(scopes ...string) string {
+	for _, scope := range scopes {
+		if !p.HasScope(scope) {
+			return scope
+		}
+	}
+	return ""
+}
+
+func (ks *KeySet) MakeJWT(principal Principal, expiresIn time.Duration) (string, error) {
+	claims := &Claims{
+		Role:  principal.Role,
+		Scope: strings.Join(principal.Scopes, " "),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    tokenIssuer,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject:   principal.UserID.String(),
+		},
+	}
+	if principal.ClientID != uuid.Nil {
+		claims.ClientID = principal.ClientID.String()
+		claims.ID = principal.GrantID.String()
+	}
+	return ks.Sign(claims)
+}
+
+// ValidateJWT verifies an access token and returns its principal. Tokens
+// issued before scopes existed come back without any scopes.
+func (ks *KeySet) ValidateJWT(tokenString string) (Principal, error) {
+	claims := &Claims{}
+	err := ks.Parse(tokenString, claims)
+	if err != nil {
+		return Principal{}, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return Principal{}, fmt.Errorf("invalid user ID in token: %v", err)
+	}
+	principal := Principal{UserID: userID, Role: claims.Role, Scopes: strings.Fields(claims.Scope)}
+	if claims.ClientID != "" {
+		principal.ClientID, err = uuid.Parse(claims.ClientID)
+		if err != nil {
+			return Principal{}, fmt.Errorf("invalid client ID in token: %v", err)
+		}
+		principal.GrantID, err = uuid.Parse(claims.ID)
+		if err != nil {
+			return Principal{}, fmt.Errorf("invalid grant ID in token: %v", err)
+		}
+	}
+	return principal, nil
+}
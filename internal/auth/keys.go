@@ -0,0 +1,228 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const tokenIssuer = "chirpy"
+
+// Key is a JWT key identified by its kid. Keys without a private half can only
+// verify tokens, which is how retired keys are kept around during a rotation.
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.Signer
+	Public  crypto.PublicKey
+}
+
+// KeySet signs tokens with one active key and verifies them with any key it
+// holds. Tokens carry the kid of their signing key in the header. An HMAC
+// secret, if set, signs tokens when there is no asymmetric key and verifies
+// tokens without a kid, so HS256 tokens keep working while keys are rolled out.
+type KeySet struct {
+	active     *Key
+	keys       map[string]*Key
+	hmacSecret []byte
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKey(id string, key any) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	default:
+		return nil, fmt.Errorf("key %s: unsupported key type %T", id, key)
+	}
+}
+
+// NewKeySet builds a key set that signs with the key called activeID. With no
+// keys, tokens are signed with HS256 using hmacSecret.
+func NewKeySet(keys []*Key, activeID, hmacSecret string) (*KeySet, error) {
+	ks := &KeySet{keys: make(map[string]*Key), hmacSecret: []byte(hmacSecret)}
+	for _, key := range keys {
+		if _, ok := ks.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate key id %s", key.ID)
+		}
+		ks.keys[key.ID] = key
+	}
+
+	if len(keys) == 0 {
+		if hmacSecret == "" {
+			return nil, fmt.Errorf("no signing keys and no HMAC secret")
+		}
+		return ks, nil
+	}
+
+	active, ok := ks.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("active key %s not found", activeID)
+	}
+	if active.Private == nil {
+		return nil, fmt.Errorf("active key %s has no private key", activeID)
+	}
+	ks.active = active
+	return ks, nil
+}
+
+// LoadKeySet reads every .pem file in dir. The file name without extension is
+// the kid. Files may hold a PKCS#8 private key or a PKIX public key; public-only
+// keys are used for verification. activeID picks the signing key and defaults
+// to the last private key in name order, so adding a newer file rotates keys.
+func LoadKeySet(dir, activeID, hmacSecret string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	var keys []*Key
+	defaultActive := ""
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := loadKeyFile(id, path)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		if key.Private != nil {
+			defaultActive = id
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %s", dir)
+	}
+	if activeID == "" {
+		activeID = defaultActive
+	}
+
+	return NewKeySet(keys, activeID, hmacSecret)
+}
+
+func loadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", path)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%s: unsupported PEM block %s", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return NewKey(id, parsed)
+}
+
+// Sign signs claims with the active key, or with the HMAC secret when the set
+// has no asymmetric keys.
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if ks.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.hmacSecret)
+	}
+	token := jwt.NewWithClaims(ks.active.Method, claims)
+	token.Header["kid"] = ks.active.ID
+	return token.SignedString(ks.active.Private)
+}
+
+// Parse verifies tokenString and fills claims.
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, ks.keyFunc, jwt.WithIssuer(tokenIssuer))
+	if err != nil {
+		return err
+	}
+	if !token.Valid {
+		return fmt.Errorf("invalid token")
+	}
+	return nil
+}
+
+func (ks *KeySet) keyFunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok || len(ks.hmacSecret) == 0 {
+			return nil, fmt.Errorf("token has no key id")
+		}
+		return ks.hmacSecret, nil
+	}
+
+	key, ok := ks.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %s", kid)
+	}
+	if t.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method used: %v", t.Header["alg"])
+	}
+	return key.Public, nil
+}
+
+// JWKS returns the public halves of all asymmetric keys in the set.
+func (ks *KeySet) JWKS() JWKS {
+	ids := make([]string, 0, len(ks.keys))
+	for id := range ks.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	jwks := JWKS{Keys: []JWK{}}
+	for _, id := range ids {
+		key := ks.keys[id]
+		jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+	return jwks
+}
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRSAKey(t *testing.T, id string) *Key {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	key, err := NewKey(id, privateKey)
+	assert.NoError(t, err)
+	return key
+}
+
+func newEd25519Key(t *testing.T, id string) *Key {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	key, err := NewKey(id, privateKey)
+	assert.NoError(t, err)
+	return key
+}
+
+func TestKeySet_RS256(t *testing.T) {
+	keys, err := NewKeySet([]*Key{newRSAKey(t, "rsa-1")}, "rsa-1", "")
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	token, err := keys.MakeJWT(Principal{UserID: userID}, time.Hour)
+	assert.NoError(t, err)
+
+	principal, err := keys.ValidateJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, principal.UserID)
+}
+
+func TestKeySet_EdDSA(t *testing.T) {
+	keys, err := NewKeySet([]*Key{newEd25519Key(t, "ed-1")}, "ed-1", "")
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	token, err := keys.MakeJWT(Principal{UserID: userID}, time.Hour)
+	assert.NoError(t, err)
+
+	principal, err := keys.ValidateJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, principal.UserID)
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	oldKey := newEd25519Key(t, "2024-01")
+	newKey := newEd25519Key(t, "2024-06")
+
+	oldKeys, err := NewKeySet([]*Key{oldKey}, "2024-01", "")
+	assert.NoError(t, err)
+	oldToken, err := oldKeys.MakeJWT(Principal{UserID: uuid.New()}, time.Hour)
+	assert.NoError(t, err)
+
+	retired, err := NewKey(oldKey.ID, oldKey.Public)
+	assert.NoError(t, err)
+	keys, err := NewKeySet([]*Key{retired, newKey}, "2024-06", "")
+	assert.NoError(t, err)
+
+	_, err = keys.ValidateJWT(oldToken)
+	assert.NoError(t, err)
+
+	newToken, err := keys.MakeJWT(Principal{UserID: uuid.New()}, time.Hour)
+	assert.NoError(t, err)
+	_, err = oldKeys.ValidateJWT(newToken)
+	assert.Error(t, err)
+}
+
+func TestKeySet_VerifyOnlyActiveKey(t *testing.T) {
+	key := newEd25519Key(t, "ed-1")
+	public, err := NewKey("ed-1", key.Public)
+	assert.NoError(t, err)
+
+	_, err = NewKeySet([]*Key{public}, "ed-1", "")
+	assert.Error(t, err)
+}
+
+func TestKeySet_WrongKey(t *testing.T) {
+	keys, err := NewKeySet([]*Key{newRSAKey(t, "same-id")}, "same-id", "")
+	assert.NoError(t, err)
+	otherKeys, err := NewKeySet([]*Key{newRSAKey(t, "same-id")}, "same-id", "")
+	assert.NoError(t, err)
+
+	token, err := otherKeys.MakeJWT(Principal{UserID: uuid.New()}, time.Hour)
+	assert.NoError(t, err)
+
+	_, err = keys.ValidateJWT(token)
+	assert.Error(t, err)
+}
+
+func TestKeySet_HMACFallback(t *testing.T) {
+	secret := "mysecret"
+	userID := uuid.New()
+	legacyKeys, err := NewKeySet(nil, "", secret)
+	assert.NoError(t, err)
+	legacyToken, err := legacyKeys.MakeJWT(Principal{UserID: userID}, time.Hour)
+	assert.NoError(t, err)
+
+	keys, err := NewKeySet([]*Key{newEd25519Key(t, "ed-1")}, "ed-1", secret)
+	assert.NoError(t, err)
+	principal, err := keys.ValidateJWT(legacyToken)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, principal.UserID)
+
+	keys, err = NewKeySet([]*Key{newEd25519Key(t, "ed-1")}, "ed-1", "")
+	assert.NoError(t, err)
+	_, err = keys.ValidateJWT(legacyToken)
+	assert.Error(t, err)
+}
+
+func TestKeySet_JWKS(t *testing.T) {
+	keys, err := NewKeySet([]*Key{newRSAKey(t, "a"), newEd25519Key(t, "b")}, "b", "")
+	assert.NoError(t, err)
+
+	jwks := keys.JWKS()
+	assert.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "RSA", jwks.Keys[0].Kty)
+	assert.Equal(t, "RS256", jwks.Keys[0].Alg)
+	assert.Equal(t, "AQAB", jwks.Keys[0].E)
+	assert.NotEmpty(t, jwks.Keys[0].N)
+	assert.Equal(t, "OKP", jwks.Keys[1].Kty)
+	assert.Equal(t, "Ed25519", jwks.Keys[1].Crv)
+	assert.Equal(t, "EdDSA", jwks.Keys[1].Alg)
+	assert.NotEmpty(t, jwks.Keys[1].X)
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600)
+	assert.NoError(t, err)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	oldPublic, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(oldPublic)
+	assert.NoError(t, err)
+	writePEM(t, filepath.Join(dir, "2024-01.pem"), "PUBLIC KEY", der)
+
+	for _, id := range []string{"2024-02", "2024-03"} {
+		_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+		assert.NoError(t, err)
+		der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+		assert.NoError(t, err)
+		writePEM(t, filepath.Join(dir, id+".pem"), "PRIVATE KEY", der)
+	}
+
+	keys, err := LoadKeySet(dir, "", "")
+	assert.NoError(t, err)
+	assert.Len(t, keys.JWKS().Keys, 3)
+	assert.Equal(t, "2024-03", keys.active.ID)
+
+	keys, err = LoadKeySet(dir, "2024-02", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "2024-02", keys.active.ID)
+
+	_, err = LoadKeySet(dir, "2024-01", "")
+	assert.Error(t, err)
+
+	_, err = LoadKeySet(t.TempDir(), "", "")
+	assert.Error(t, err)
+}
+
+func TestKeySet_Claims(t *testing.T) {
+	keys, err := NewKeySet([]*Key{newEd25519Key(t, "ed-1")}, "ed-1", "")
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	token, err := keys.MakeJWT(Principal{UserID: userID, Role: RoleAdmin, Scopes: ScopesForRole(RoleAdmin)}, time.Hour)
+	assert.NoError(t, err)
+
+	principal, err := keys.ValidateJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, principal.UserID)
+	assert.Equal(t, RoleAdmin, principal.Role)
+	assert.Equal(t, []string{ScopeRead, ScopeWrite, ScopeAccount, ScopeAdmin}, principal.Scopes)
+	assert.Equal(t, "", principal.MissingScope(ScopeWrite, ScopeAdmin))
+}
+
+func TestKeySet_ClientClaims(t *testing.T) {
+	keys, err := NewKeySet([]*Key{newEd25519Key(t, "ed-1")}, "ed-1", "")
+	assert.NoError(t, err)
+
+	client := Principal{UserID: uuid.New(), Scopes: []string{ScopeRead}, ClientID: uuid.New(), GrantID: uuid.New()}
+	token, err := keys.MakeJWT(client, time.Hour)
+	assert.NoError(t, err)
+
+	principal, err := keys.ValidateJWT(token)
+	assert.NoError(t, err)
+	assert.Equal(t, client, principal)
+}
+
+func TestPrincipal_MissingScope(t *testing.T) {
+	principal := Principal{Role: RoleUser, Scopes: ScopesForRole(RoleUser)}
+
+	assert.True(t, principal.HasScope(ScopeWrite))
+	assert.False(t, principal.HasScope(ScopeAdmin))
+	assert.Equal(t, "", principal.MissingScope(ScopeRead, ScopeWrite))
+	assert.Equal(t, ScopeAdmin, principal.MissingScope(ScopeRead, ScopeAdmin))
+	assert.Equal(t, ScopeRead, Principal{}.MissingScope(ScopeRead))
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordParams are the argon2id parameters new password hashes are made
+// with. Memory is in KiB.
+type PasswordParams struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultPasswordParams follow the OWASP recommendation for argon2id.
+var DefaultPasswordParams = PasswordParams{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+var (
+	passwordParamsMu sync.RWMutex
+	passwordParams   = DefaultPasswordParams
+)
+
+// hashSlots limits how many argon2id hashes run at once. Each one holds
+// Memory KiB for its duration, so without a limit a burst of logins could
+// exhaust memory. Further hashes wait for a slot.
+var (
+	hashSlotsMu sync.RWMutex
+	hashSlots   = make(chan struct{}, runtime.GOMAXPROCS(0))
+)
+
+var ErrPasswordMismatch = errors.New("password does not match")
+
+var phcEncoding = base64.RawStdEncoding
+
+// SetPasswordParams changes the parameters used by HashPassword. Existing
+// hashes keep verifying; NeedsRehash reports them until they are upgraded.
+func SetPasswordParams(params PasswordParams) error {
+	if params.Memory < 8*uint32(params.Parallelism) {
+		return fmt.Errorf("memory must be at least 8 KiB per lane")
+	}
+	if params.Iterations < 1 || params.Parallelism < 1 {
+		return fmt.Errorf("iterations and parallelism must be at least 1")
+	}
+	if params.SaltLength < 8 || params.KeyLength < 16 {
+		return fmt.Errorf("salt must be at least 8 bytes and key at least 16 bytes")
+	}
+
+	passwordParamsMu.Lock()
+	defer passwordParamsMu.Unlock()
+	passwordParams = params
+	return nil
+}
+
+// SetMaxConcurrentHashes changes how many argon2id hashes may run at once.
+// It defaults to GOMAXPROCS. Hashes already waiting keep the old limit.
+func SetMaxConcurrentHashes(n int) error {
+	if n < 1 {
+		return fmt.Errorf("at least one concurrent hash must be allowed")
+	}
+
+	hashSlotsMu.Lock()
+	defer hashSlotsMu.Unlock()
+	hashSlots = make(chan struct{}, n)
+	return nil
+}
+
+// acquireHashSlot blocks until a hash may run and returns the function that
+// gives the slot back.
+func acquireHashSlot() func() {
+	hashSlotsMu.RLock()
+	slots := hashSlots
+	hashSlotsMu.RUnlock()
+
+	slots <- struct{}{}
+	return func() { <-slots }
+}
+
+func argon2Key(password string, salt []byte, params PasswordParams) []byte {
+	release := acquireHashSlot()
+	defer release()
+	return argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+}
+
+func currentPasswordParams() PasswordParams {
+	passwordParamsMu.RLock()
+	defer passwordParamsMu.RUnlock()
+	return passwordParams
+}
+
+// HashPassword hashes password with argon2id and returns it in PHC string
+// format, e.g. $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>.
+func HashPassword(password string) (string, error) {
+	params := currentPasswordParams()
+	salt := make([]byte, params.SaltLength)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return "", err
+	}
+
+	key := argon2Key(password, salt, params)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key)), nil
+}
+
+// CheckPasswordHash verifies password against an argon2id hash or a bcrypt
+// hash from before argon2id was introduced.
+func CheckPasswordHash(password, hash string) error {
+	if isBcryptHash(hash) {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	}
+
+	params, salt, key, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return err
+	}
+	computed := argon2Key(password, salt, params)
+	if subtle.ConstantTimeCompare(computed, key) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+// NeedsRehash reports whether hash was made with another algorithm or other
+// parameters than HashPassword currently uses.
+func NeedsRehash(hash string) bool {
+	if isBcryptHash(hash) {
+		return true
+	}
+	params, _, _, err := decodeArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+	current := currentPasswordParams()
+	return params.Memory != current.Memory ||
+		params.Iterations != current.Iterations ||
+		params.Parallelism != current.Parallelism ||
+		params.SaltLength != current.SaltLength ||
+		params.KeyLength != current.KeyLength
+}
+
+func isBcryptHash(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func decodeArgon2Hash(hash string) (PasswordParams, []byte, []byte, error) {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return PasswordParams{}, nil, nil, fmt.Errorf("unsupported password hash format")
+	}
+
+	var version int
+	_, err := fmt.Sscanf(parts[2], "v=%d", &version)
+	if err != nil || version != argon2.Version {
+		return PasswordParams{}, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
+	}
+
+	params := PasswordParams{}
+	_, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("invalid argon2 parameters %q", parts[3])
+	}
+
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("invalid salt: %v", err)
+	}
+	key, err := phcEncoding.DecodeString(parts[5])
+	if err != nil {
+		return PasswordParams{}, nil, nil, fmt.Errorf("invalid hash: %v", err)
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testPasswordParams = PasswordParams{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func withPasswordParams(t *testing.T, params PasswordParams) {
+	previous := currentPasswordParams()
+	assert.NoError(t, SetPasswordParams(params))
+	t.Cleanup(func() {
+		assert.NoError(t, SetPasswordParams(previous))
+	})
+}
+
+func TestHashPassword_Argon2id(t *testing.T) {
+	withPasswordParams(t, testPasswordParams)
+
+	hash, err := HashPassword("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	assert.NoError(t, CheckPasswordHash("correct horse battery staple", hash))
+	assert.ErrorIs(t, CheckPasswordHash("correct horse battery stapler", hash), ErrPasswordMismatch)
+
+	other, err := HashPassword("correct horse battery staple")
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, other)
+}
+
+func TestHashPassword_LongPassphrase(t *testing.T) {
+	withPasswordParams(t, testPasswordParams)
+
+	prefix := strings.Repeat("a", 72)
+	hash, err := HashPassword(prefix + "b")
+	assert.NoError(t, err)
+
+	assert.NoError(t, CheckPasswordHash(prefix+"b", hash))
+	assert.Error(t, CheckPasswordHash(prefix+"c", hash))
+}
+
+func TestCheckPasswordHash_Bcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("legacy password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	assert.NoError(t, CheckPasswordHash("legacy password", string(hash)))
+	assert.Error(t, CheckPasswordHash("wrong password", string(hash)))
+}
+
+func TestCheckPasswordHash_InvalidHash(t *testing.T) {
+	tests := []string{
+		"",
+		"plaintext",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=x,t=1,p=1$c2FsdHNhbHQ$aGFzaGhhc2g",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!!$aGFzaGhhc2g",
+	}
+
+	for _, hash := range tests {
+		assert.Error(t, CheckPasswordHash("password", hash), hash)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	withPasswordParams(t, testPasswordParams)
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	assert.True(t, NeedsRehash(string(bcryptHash)))
+
+	hash, err := HashPassword("password")
+	assert.NoError(t, err)
+	assert.False(t, NeedsRehash(hash))
+
+	stronger := testPasswordParams
+	stronger.Iterations = 2
+	assert.NoError(t, SetPasswordParams(stronger))
+	assert.True(t, NeedsRehash(hash))
+	assert.NoError(t, CheckPasswordHash("password", hash))
+
+	assert.True(t, NeedsRehash("not a hash"))
+}
+
+func TestSetPasswordParams_Invalid(t *testing.T) {
+	tests := []PasswordParams{
+		{Memory: 4, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 0, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 0, SaltLength: 16, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 4, KeyLength: 32},
+		{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 8},
+	}
+
+	for _, params := range tests {
+		assert.Error(t, SetPasswordParams(params))
+	}
+}
+
+func TestHashPassword_WaitsForSlot(t *testing.T) {
+	withPasswordParams(t, testPasswordParams)
+	assert.NoError(t, SetMaxConcurrentHashes(1))
+	t.Cleanup(func() {
+		assert.NoError(t, SetMaxConcurrentHashes(runtime.GOMAXPROCS(0)))
+	})
+
+	release := acquireHashSlot()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := HashPassword("correct horse battery staple")
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("hash ran while no slot was free")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hash did not run after the slot was freed")
+	}
+}
+
+func TestSetMaxConcurrentHashes_Invalid(t *testing.T) {
+	assert.Error(t, SetMaxConcurrentHashes(0))
+	assert.Error(t, SetMaxConcurrentHashes(-1))
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// PKCEMethodS256 is the only PKCE challenge method accepted; "plain" would
+// let anyone who sees the authorization request redeem the code.
+const PKCEMethodS256 = "S256"
+
+// PKCEChallenge derives the S256 code challenge for a code verifier as in
+// RFC 7636: the unpadded base64url encoding of its SHA-256 hash.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// ValidCodeVerifier reports whether verifier has the length and alphabet
+// RFC 7636 requires: 43 to 128 unreserved URL characters.
+func ValidCodeVerifier(verifier string) bool {
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+	for _, c := range verifier {
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-' || c == '.' || c == '_' || c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// VerifyPKCE reports whether verifier is valid and matches the S256 challenge
+// sent with the authorization request.
+func VerifyPKCE(verifier, challenge string) bool {
+	if !ValidCodeVerifier(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(challenge)) == 1
+}
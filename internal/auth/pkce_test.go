@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testVerifier  = "M25iVXpKU3puUjFaYWg3T1NDTDQtcW1ROUY5YXlwalNoc0hhakxifmZHag"
+	testChallenge = "qjrzSW9gMiUgpUvqgEPE4_-8swvyCtfOVvg55o5S_es"
+)
+
+func TestPKCEChallenge(t *testing.T) {
+	assert.Equal(t, testChallenge, PKCEChallenge(testVerifier))
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	assert.True(t, VerifyPKCE(testVerifier, testChallenge))
+	assert.False(t, VerifyPKCE(testVerifier+"x", testChallenge))
+	assert.False(t, VerifyPKCE(testVerifier, ""))
+}
+
+func TestValidCodeVerifier(t *testing.T) {
+	assert.True(t, ValidCodeVerifier(strings.Repeat("a", 43)))
+	assert.True(t, ValidCodeVerifier(strings.Repeat("~", 128)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 42)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 129)))
+	assert.False(t, ValidCodeVerifier(strings.Repeat("a", 42)+"+"))
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	totpDigits = 6
+	totpPeriod = 30
+	// totpSkew is how many steps before and after the current one are
+	// accepted to allow for clock drift.
+	totpSkew = 1
+
+	recoveryCodeCount = 10
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160-bit secret in base32, the form
+// authenticator apps expect.
+func GenerateTOTPSecret() (string, error) {
+	secret := make([]byte, 20)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+// TOTPURI builds the otpauth:// URI shown as a QR code during enrollment.
+func TOTPURI(secret, issuer, account string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// TOTPStep returns the RFC 6238 time step t falls in.
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// TOTPCode computes the code for a time step as described in RFC 4226.
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %v", err)
+	}
+
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// ValidateTOTP checks code against the steps around t and returns the step it
+// matched. Callers store that step and reject codes for it or any earlier step
+// so a code cannot be replayed.
+func ValidateTOTP(secret, code string, t time.Time) (int64, bool) {
+	code = strings.ReplaceAll(code, " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(t)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes returns single-use codes in the form xxxxx-xxxxx.
+func GenerateRecoveryCodes() ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		raw := make([]byte, 5)
+		_, err := rand.Read(raw)
+		if err != nil {
+			return nil, err
+		}
+		code := hex.EncodeToString(raw)
+		codes[i] = code[:5] + "-" + code[5:]
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode hashes a recovery code for storage. Codes are normalised
+// first so dashes, spaces and case do not matter when they are typed in.
+func HashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	return HashToken(normalized)
+}
+
+// HashToken hashes a random, high-entropy token for storage. A fast hash is
+// enough because such tokens cannot be guessed like passwords can.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
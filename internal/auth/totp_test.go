@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"encoding/base32"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// rfc6238Secret is the SHA-1 test key from RFC 6238 appendix B.
+var rfc6238Secret = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPCode(t *testing.T) {
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, test := range tests {
+		code, err := TOTPCode(rfc6238Secret, TOTPStep(time.Unix(test.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, test.code, code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+
+	step, ok := ValidateTOTP(rfc6238Secret, "081804", now)
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	step, ok = ValidateTOTP(rfc6238Secret, "081 804", now.Add(30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, TOTPStep(now), step)
+
+	_, ok = ValidateTOTP(rfc6238Secret, "081804", now.Add(90*time.Second))
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(rfc6238Secret, "000000", now)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP(rfc6238Secret, "", now)
+	assert.False(t, ok)
+
+	_, ok = ValidateTOTP("not base32!", "081804", now)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	code, err := TOTPCode(secret, TOTPStep(time.Now()))
+	assert.NoError(t, err)
+	_, ok := ValidateTOTP(secret, code, time.Now())
+	assert.True(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := TOTPURI("JBSWY3DPEHPK3PXP", "Chirpy", "user@example.com")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Chirpy:user@example.com?"))
+
+	parsed, err := url.Parse(uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", parsed.Query().Get("secret"))
+	assert.Equal(t, "Chirpy", parsed.Query().Get("issuer"))
+	assert.Equal(t, "6", parsed.Query().Get("digits"))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes()
+	assert.NoError(t, err)
+	assert.Len(t, codes, recoveryCodeCount)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Len(t, code, 11)
+		assert.Equal(t, "-", code[5:6])
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+}
+
+func TestHashRecoveryCode(t *testing.T) {
+	assert.Equal(t, HashRecoveryCode("abcde-12345"), HashRecoveryCode("ABCDE 12345"))
+	assert.Equal(t, HashRecoveryCode("abcde-12345"), HashRecoveryCode("abcde12345"))
+	assert.NotEqual(t, HashRecoveryCode("abcde-12345"), HashRecoveryCode("abcde-12346"))
+}
@@ -7,23 +7,63 @@ package database
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
+const chirpHasReplies = `-- name: ChirpHasReplies :one
+SELECT EXISTS (SELECT 1 FROM chirps WHERE parent_id = $1)
+`
+
+func (q *Queries) ChirpHasReplies(ctx context.Context, parentID uuid.NullUUID) (bool, error) {
+	row := q.db.QueryRowContext(ctx, chirpHasReplies, parentID)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const countRecentChirps = `-- name: CountRecentChirps :one
+SELECT COUNT(*) AS count, MIN(created_at)::timestamp AS oldest FROM chirps
+WHERE user_id = $1 AND created_at > $2
+`
+
+type CountRecentChirpsParams struct {
+	UserID uuid.UUID
+	Since  time.Time
+}
+
+type CountRecentChirpsRow struct {
+	Count  int64
+	Oldest sql.NullTime
+}
+
+func (q *Queries) CountRecentChirps(ctx context.Context, arg CountRecentChirpsParams) (CountRecentChirpsRow, error) {
+	row := q.db.QueryRowContext(ctx, countRecentChirps, arg.UserID, arg.Since)
+	var i CountRecentChirpsRow
+	err := row.Scan(
+		&i.Count,
+		&i.Oldest,
+	)
+	return i, err
+}
+
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, user_id, body)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, user_id, body, created_at, updated_at
+INSERT INTO chirps (id, created_at, updated_at, user_id, body, parent_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
+RETURNING id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count
 `
 
 type CreateChirpParams struct {
-	UserID uuid.UUID
-	Body   string
+	UserID   uuid.UUID
+	Body     string
+	ParentID uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.UserID, arg.Body)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.UserID, arg.Body, arg.ParentID)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -31,6 +71,11 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.Body,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
 	)
 	return i, err
 }
@@ -44,25 +89,50 @@ func (q *Queries) DeleteChirpById(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const getAllChirps = `-- name: GetAllChirps :many
-SELECT id, user_id, body, created_at, updated_at FROM chirps ORDER BY created_at
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+  SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count, 0 AS depth FROM chirps WHERE chirps.id = $1
+  UNION ALL
+  SELECT c.id, c.user_id, c.body, c.created_at, c.updated_at, c.edited_at, c.parent_id, c.deleted_at, c.like_count, c.rechirp_count, a.depth + 1 FROM chirps c JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count, depth FROM ancestors WHERE depth > 0 ORDER BY depth DESC
 `
 
-func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirps)
+type GetChirpAncestorsRow struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Body         string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	EditedAt     sql.NullTime
+	ParentID     uuid.NullUUID
+	DeletedAt    sql.NullTime
+	LikeCount    int32
+	RechirpCount int32
+	Depth        int32
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, id uuid.UUID) ([]GetChirpAncestorsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []Chirp
+	var items []GetChirpAncestorsRow
 	for rows.Next() {
-		var i Chirp
+		var i GetChirpAncestorsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.UserID,
 			&i.Body,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.Depth,
 		); err != nil {
 			return nil, err
 		}
@@ -78,7 +148,7 @@ func (q *Queries) GetAllChirps(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirpById = `-- name: GetChirpById :one
-SELECT id, user_id, body, created_at, updated_at FROM chirps WHERE id = $1
+SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count FROM chirps WHERE id = $1
 `
 
 func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error) {
@@ -90,16 +160,134 @@ func (q *Queries) GetChirpById(ctx context.Context, id uuid.UUID) (Chirp, error)
 		&i.Body,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+	)
+	return i, err
+}
+
+const getChirpByIdForUpdate = `-- name: GetChirpByIdForUpdate :one
+SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count FROM chirps WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetChirpByIdForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpByIdForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
 	)
 	return i, err
 }
 
-const getChirpsByUserId = `-- name: GetChirpsByUserId :many
-SELECT id, user_id, body, created_at, updated_at FROM chirps WHERE user_id = $1 ORDER BY created_at
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+  SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count, 0 AS depth FROM chirps WHERE chirps.id = $1
+  UNION ALL
+  SELECT c.id, c.user_id, c.body, c.created_at, c.updated_at, c.edited_at, c.parent_id, c.deleted_at, c.like_count, c.rechirp_count, d.depth + 1 FROM chirps c JOIN descendants d ON c.parent_id = d.id
+)
+SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count, depth FROM descendants ORDER BY depth, created_at, id
+LIMIT $2
 `
 
-func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getChirpsByUserId, userID)
+type GetChirpDescendantsParams struct {
+	ID    uuid.UUID
+	Limit int32
+}
+
+type GetChirpDescendantsRow struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Body         string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	EditedAt     sql.NullTime
+	ParentID     uuid.NullUUID
+	DeletedAt    sql.NullTime
+	LikeCount    int32
+	RechirpCount int32
+	Depth        int32
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]GetChirpDescendantsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetChirpDescendantsRow
+	for rows.Next() {
+		var i GetChirpDescendantsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+			&i.Depth,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsAscending = `-- name: GetChirpsAscending :many
+SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1)
+AND ($2::timestamp IS NULL OR (created_at, id) > ($2::timestamp, $3::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = $5::uuid)
+  OR (user_blocks.blocker_id = $5::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND ($1::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = $5::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY created_at, id
+LIMIT $4
+`
+
+type GetChirpsAscendingParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+	ViewerID        uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsAscending(ctx context.Context, arg GetChirpsAscendingParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsAscending,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.ViewerID,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -113,6 +301,122 @@ func (q *Queries) GetChirpsByUserId(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.Body,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsDescending = `-- name: GetChirpsDescending :many
+SELECT id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count FROM chirps
+WHERE deleted_at IS NULL
+AND ($1::uuid IS NULL OR user_id = $1)
+AND ($2::timestamp IS NULL OR (created_at, id) < ($2::timestamp, $3::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = $5::uuid)
+  OR (user_blocks.blocker_id = $5::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND ($1::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = $5::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY created_at DESC, id DESC
+LIMIT $4
+`
+
+type GetChirpsDescendingParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+	ViewerID        uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsDescending(ctx context.Context, arg GetChirpsDescendingParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsDescending,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Body,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.EditedAt,
+			&i.ParentID,
+			&i.DeletedAt,
+			&i.LikeCount,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getViewerEngagement = `-- name: GetViewerEngagement :many
+SELECT chirps.id AS chirp_id,
+  EXISTS (SELECT 1 FROM chirp_likes WHERE chirp_likes.chirp_id = chirps.id AND chirp_likes.user_id = $1) AS liked,
+  EXISTS (SELECT 1 FROM chirp_rechirps WHERE chirp_rechirps.chirp_id = chirps.id AND chirp_rechirps.user_id = $1) AS rechirped
+FROM chirps
+WHERE chirps.id = ANY($2::uuid[])
+`
+
+type GetViewerEngagementParams struct {
+	UserID   uuid.UUID
+	ChirpIds []uuid.UUID
+}
+
+type GetViewerEngagementRow struct {
+	ChirpID   uuid.UUID
+	Liked     bool
+	Rechirped bool
+}
+
+func (q *Queries) GetViewerEngagement(ctx context.Context, arg GetViewerEngagementParams) ([]GetViewerEngagementRow, error) {
+	rows, err := q.db.QueryContext(ctx, getViewerEngagement, arg.UserID, pq.Array(arg.ChirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetViewerEngagementRow
+	for rows.Next() {
+		var i GetViewerEngagementRow
+		if err := rows.Scan(
+			&i.ChirpID,
+			&i.Liked,
+			&i.Rechirped,
 		); err != nil {
 			return nil, err
 		}
@@ -135,3 +439,224 @@ func (q *Queries) ResetChirps(ctx context.Context) error {
 	_, err := q.db.ExecContext(ctx, resetChirps)
 	return err
 }
+
+const searchChirps = `-- name: SearchChirps :many
+SELECT chirps.id, chirps.user_id, chirps.body, chirps.created_at, chirps.updated_at, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.like_count, chirps.rechirp_count, ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1))::real AS rank
+FROM chirps
+WHERE deleted_at IS NULL
+AND to_tsvector('english', body) @@ websearch_to_tsquery('english', $1)
+AND ($2::uuid IS NULL OR user_id = $2)
+AND ($3::timestamp IS NULL OR created_at >= $3)
+AND ($4::timestamp IS NULL OR created_at < $4)
+AND ($5::real IS NULL OR (ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1))::real, created_at, id) < ($5::real, $6::timestamp, $7::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = $9::uuid)
+  OR (user_blocks.blocker_id = $9::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND ($2::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = $9::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT $8
+`
+
+type SearchChirpsParams struct {
+	Query           string
+	AuthorID        uuid.NullUUID
+	Since           sql.NullTime
+	Until           sql.NullTime
+	CursorRank      sql.NullFloat64
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+	ViewerID        uuid.NullUUID
+}
+
+type SearchChirpsRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirps(ctx context.Context, arg SearchChirpsParams) ([]SearchChirpsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirps,
+		arg.Query,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.CursorRank,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsRow
+	for rows.Next() {
+		var i SearchChirpsRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.UserID,
+			&i.Chirp.Body,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ParentID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.LikeCount,
+			&i.Chirp.RechirpCount,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const searchChirpsBackward = `-- name: SearchChirpsBackward :many
+SELECT chirps.id, chirps.user_id, chirps.body, chirps.created_at, chirps.updated_at, chirps.edited_at, chirps.parent_id, chirps.deleted_at, chirps.like_count, chirps.rechirp_count, ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1))::real AS rank
+FROM chirps
+WHERE deleted_at IS NULL
+AND to_tsvector('english', body) @@ websearch_to_tsquery('english', $1)
+AND ($2::uuid IS NULL OR user_id = $2)
+AND ($3::timestamp IS NULL OR created_at >= $3)
+AND ($4::timestamp IS NULL OR created_at < $4)
+AND ($5::real IS NULL OR (ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', $1))::real, created_at, id) > ($5::real, $6::timestamp, $7::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = $9::uuid)
+  OR (user_blocks.blocker_id = $9::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND ($2::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = $9::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY rank ASC, created_at ASC, id ASC
+LIMIT $8
+`
+
+type SearchChirpsBackwardParams struct {
+	Query           string
+	AuthorID        uuid.NullUUID
+	Since           sql.NullTime
+	Until           sql.NullTime
+	CursorRank      sql.NullFloat64
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	Limit           int32
+	ViewerID        uuid.NullUUID
+}
+
+type SearchChirpsBackwardRow struct {
+	Chirp Chirp
+	Rank  float32
+}
+
+func (q *Queries) SearchChirpsBackward(ctx context.Context, arg SearchChirpsBackwardParams) ([]SearchChirpsBackwardRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchChirpsBackward,
+		arg.Query,
+		arg.AuthorID,
+		arg.Since,
+		arg.Until,
+		arg.CursorRank,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.Limit,
+		arg.ViewerID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []SearchChirpsBackwardRow
+	for rows.Next() {
+		var i SearchChirpsBackwardRow
+		if err := rows.Scan(
+			&i.Chirp.ID,
+			&i.Chirp.UserID,
+			&i.Chirp.Body,
+			&i.Chirp.CreatedAt,
+			&i.Chirp.UpdatedAt,
+			&i.Chirp.EditedAt,
+			&i.Chirp.ParentID,
+			&i.Chirp.DeletedAt,
+			&i.Chirp.LikeCount,
+			&i.Chirp.RechirpCount,
+			&i.Rank,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const tombstoneChirp = `-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', updated_at = NOW(), deleted_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count
+`
+
+func (q *Queries) TombstoneChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, tombstoneChirp, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+	)
+	return i, err
+}
+
+const updateChirpBody = `-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE id = $1
+RETURNING id, user_id, body, created_at, updated_at, edited_at, parent_id, deleted_at, like_count, rechirp_count
+`
+
+type UpdateChirpBodyParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirpBody(ctx context.Context, arg UpdateChirpBodyParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirpBody, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Body,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EditedAt,
+		&i.ParentID,
+		&i.DeletedAt,
+		&i.LikeCount,
+		&i.RechirpCount,
+	)
+	return i, err
+}
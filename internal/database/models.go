@@ -6,33 +6,284 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
 )
 
 type Chirp struct {
-	ID        uuid.UUID
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Body         string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+	EditedAt     sql.NullTime
+	ParentID     uuid.NullUUID
+	DeletedAt    sql.NullTime
+	LikeCount    int32
+	RechirpCount int32
+}
+
+type ChirpHashtag struct {
+	ChirpID   uuid.UUID
+	Tag       string
+	CreatedAt time.Time
+}
+
+type ChirpLike struct {
 	UserID    uuid.UUID
-	Body      string
+	ChirpID   uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
 }
 
-type RefreshToken struct {
-	Token     string
+type ChirpMention struct {
+	ChirpID uuid.UUID
+	UserID  uuid.UUID
+}
+
+type ChirpRechirp struct {
+	UserID    uuid.UUID
+	ChirpID   uuid.UUID
 	CreatedAt time.Time
-	UpdatedAt time.Time
+}
+
+type ChirpRevision struct {
+	ID        uuid.UUID
+	ChirpID   uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+type Follow struct {
+	FollowerID uuid.UUID
+	FolloweeID uuid.UUID
+	CreatedAt  time.Time
+}
+
+type LoginFailure struct {
+	Kind         string
+	Subject      string
+	Failures     int32
+	LastFailedAt time.Time
+	LockedUntil  sql.NullTime
+}
+
+type MfaChallenge struct {
+	TokenHash string
 	UserID    uuid.UUID
+	Attempts  int32
 	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type ModerationAction struct {
+	ID        uuid.UUID
+	Action    string
+	ReportID  uuid.NullUUID
+	ChirpID   uuid.NullUUID
+	UserID    uuid.NullUUID
+	Note      string
+	CreatedAt time.Time
+	ActorID   uuid.NullUUID
+}
+
+type ModerationFlag struct {
+	ID         uuid.UUID
+	ChirpID    uuid.UUID
+	Reason     string
+	CreatedAt  time.Time
+	ResolvedAt sql.NullTime
+}
+
+type ModerationRule struct {
+	ID        uuid.UUID
+	Pattern   string
+	MatchType string
+	Action    string
+	CreatedAt time.Time
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash      string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectUri   string
+	Scopes        []string
+	CodeChallenge string
+	GrantID       uuid.NullUUID
+	ExpiresAt     time.Time
+	UsedAt        sql.NullTime
+	CreatedAt     time.Time
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	OwnerID      uuid.UUID
+	Name         string
+	SecretHash   sql.NullString
+	RedirectUris []string
+	Scopes       []string
+	CreatedAt    time.Time
+}
+
+type OauthGrant struct {
+	ID        uuid.UUID
+	ClientID  uuid.UUID
+	UserID    uuid.UUID
+	Scopes    []string
+	CreatedAt time.Time
 	RevokedAt sql.NullTime
 }
 
-type User struct {
+type OauthRefreshToken struct {
+	TokenHash string
+	GrantID   uuid.UUID
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type OidcLoginState struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	UsedAt       sql.NullTime
+	CreatedAt    time.Time
+}
+
+type PersonalAccessToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	TokenHash  string
+	Scopes     []string
+	ExpiresAt  sql.NullTime
+	LastUsedAt sql.NullTime
+	RevokedAt  sql.NullTime
+	CreatedAt  time.Time
+}
+
+type RecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type RefreshToken struct {
+	Token      string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+	UserID     uuid.UUID
+	ExpiresAt  time.Time
+	RevokedAt  sql.NullTime
+	FamilyID   uuid.UUID
+	ReplacedBy sql.NullString
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+}
+
+type Report struct {
 	ID             uuid.UUID
-	Email          string
+	ReporterID     uuid.UUID
+	ReportedUserID uuid.UUID
+	ChirpID        uuid.NullUUID
+	Reason         string
 	CreatedAt      time.Time
-	UpdatedAt      time.Time
-	HashedPassword string
-	IsChirpyRed    bool
+	ResolvedAt     sql.NullTime
+	Resolution     sql.NullString
+}
+
+type ScheduledChirp struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	Body          string
+	ParentID      uuid.NullUUID
+	PublishAt     time.Time
+	ChirpID       uuid.NullUUID
+	PublishedAt   sql.NullTime
+	FailedAt      sql.NullTime
+	Error         sql.NullString
+	CreatedAt     time.Time
+	Attempts      int32
+	NextAttemptAt time.Time
+}
+
+type Subscription struct {
+	UserID           uuid.UUID
+	Plan             string
+	Status           string
+	CurrentPeriodEnd time.Time
+	CanceledAt       sql.NullTime
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	EndedPeriodEnd   sql.NullTime
+}
+
+type User struct {
+	ID              uuid.UUID
+	Email           string
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	HashedPassword  string
+	IsChirpyRed     bool
+	SuspendedAt     sql.NullTime
+	Role            string
+	EmailVerifiedAt sql.NullTime
+}
+
+type UserBlock struct {
+	BlockerID uuid.UUID
+	BlockedID uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserIdentity struct {
+	Provider    string
+	Subject     string
+	UserID      uuid.UUID
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+type UserMute struct {
+	MuterID   uuid.UUID
+	MutedID   uuid.UUID
+	CreatedAt time.Time
+}
+
+type UserToken struct {
+	TokenHash string
+	UserID    uuid.UUID
+	Purpose   string
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type UserTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+type WebhookEvent struct {
+	ID          uuid.UUID
+	EventID     string
+	Event       string
+	Payload     json.RawMessage
+	Status      string
+	Error       sql.NullString
+	Attempts    int32
+	ReceivedAt  time.Time
+	ProcessedAt sql.NullTime
 }
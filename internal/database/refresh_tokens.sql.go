@@ -7,13 +7,86 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const getActiveSessions = `-- name: GetActiveSessions :many
+SELECT refresh_tokens.family_id, refresh_tokens.user_agent, refresh_tokens.ip_address, refresh_tokens.last_used_at, refresh_tokens.expires_at,
+  (SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = refresh_tokens.family_id)::timestamptz AS started_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+AND refresh_tokens.revoked_at IS NULL
+AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC
+`
+
+type GetActiveSessionsRow struct {
+	FamilyID   uuid.UUID
+	UserAgent  string
+	IpAddress  string
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+	StartedAt  time.Time
+}
+
+func (q *Queries) GetActiveSessions(ctx context.Context, userID uuid.UUID) ([]GetActiveSessionsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getActiveSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetActiveSessionsRow
+	for rows.Next() {
+		var i GetActiveSessionsRow
+		if err := rows.Scan(
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.IpAddress,
+			&i.LastUsedAt,
+			&i.ExpiresAt,
+			&i.StartedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRefreshTokenForUpdate = `-- name: GetRefreshTokenForUpdate :one
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at FROM refresh_tokens WHERE token = $1 FOR UPDATE
+`
+
+func (q *Queries) GetRefreshTokenForUpdate(ctx context.Context, token string) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, getRefreshTokenForUpdate, token)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
 const getUserFromRefreshToken = `-- name: GetUserFromRefreshToken :one
-SELECT token, created_at, updated_at, user_id, expires_at, revoked_at FROM refresh_tokens WHERE token = $1
+SELECT token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at FROM refresh_tokens WHERE token = $1
 `
 
 func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -26,24 +99,39 @@ func (q *Queries) GetUserFromRefreshToken(ctx context.Context, token string) (Re
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
 
 const insertRefreshToken = `-- name: InsertRefreshToken :one
-INSERT INTO refresh_tokens (token, user_id, expires_at)
-VALUES ($1, $2, $3)
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+INSERT INTO refresh_tokens (token, user_id, expires_at, family_id, user_agent, ip_address, last_used_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at
 `
 
 type InsertRefreshTokenParams struct {
 	Token     string
 	UserID    uuid.UUID
 	ExpiresAt time.Time
+	FamilyID  uuid.UUID
+	UserAgent string
+	IpAddress string
 }
 
 func (q *Queries) InsertRefreshToken(ctx context.Context, arg InsertRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRowContext(ctx, insertRefreshToken, arg.Token, arg.UserID, arg.ExpiresAt)
+	row := q.db.QueryRowContext(ctx, insertRefreshToken,
+		arg.Token,
+		arg.UserID,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpAddress,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.Token,
@@ -52,6 +140,11 @@ func (q *Queries) InsertRefreshToken(ctx context.Context, arg InsertRefreshToken
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -65,11 +158,44 @@ func (q *Queries) ResetRefreshTokens(ctx context.Context) error {
 	return err
 }
 
+const revokeAllUserRefreshTokens = `-- name: RevokeAllUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeAllUserRefreshTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeAllUserRefreshTokens, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeOtherRefreshTokenFamilies = `-- name: RevokeOtherRefreshTokenFamilies :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL
+`
+
+type RevokeOtherRefreshTokenFamiliesParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeOtherRefreshTokenFamilies(ctx context.Context, arg RevokeOtherRefreshTokenFamiliesParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeOtherRefreshTokenFamilies, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const revokeRefreshToken = `-- name: RevokeRefreshToken :one
 UPDATE refresh_tokens
 SET revoked_at = NOW(), updated_at = NOW()
 WHERE token = $1
-RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at
 `
 
 func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) (RefreshToken, error) {
@@ -82,6 +208,75 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, token string) (Refresh
 		&i.UserID,
 		&i.ExpiresAt,
 		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
+	)
+	return i, err
+}
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const revokeUserRefreshTokenFamily = `-- name: RevokeUserRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL
+`
+
+type RevokeUserRefreshTokenFamilyParams struct {
+	UserID   uuid.UUID
+	FamilyID uuid.UUID
+}
+
+func (q *Queries) RevokeUserRefreshTokenFamily(ctx context.Context, arg RevokeUserRefreshTokenFamilyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, revokeUserRefreshTokenFamily, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW(), replaced_by = $2
+WHERE token = $1
+RETURNING token, created_at, updated_at, user_id, expires_at, revoked_at, family_id, replaced_by, user_agent, ip_address, last_used_at
+`
+
+type RotateRefreshTokenParams struct {
+	Token      string
+	ReplacedBy sql.NullString
+}
+
+func (q *Queries) RotateRefreshToken(ctx context.Context, arg RotateRefreshTokenParams) (RefreshToken, error) {
+	row := q.db.QueryRowContext(ctx, rotateRefreshToken, arg.Token, arg.ReplacedBy)
+	var i RefreshToken
+	err := row.Scan(
+		&i.Token,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.UserID,
+		&i.ExpiresAt,
+		&i.RevokedAt,
+		&i.FamilyID,
+		&i.ReplacedBy,
+		&i.UserAgent,
+		&i.IpAddress,
+		&i.LastUsedAt,
 	)
 	return i, err
 }
@@ -9,12 +9,13 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, created_at, updated_at, email, hashed_password)
 VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at
 `
 
 type CreateUserParams struct {
@@ -32,12 +33,15 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red FROM users WHERE email = $1
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -50,10 +54,137 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
+const getUserByEmailInsensitive = `-- name: GetUserByEmailInsensitive :one
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at FROM users
+WHERE lower(email) = lower($1)
+ORDER BY email = $1 DESC, created_at
+LIMIT 1
+`
+
+// GetUserByEmailInsensitive prefers an exact match when addresses differ only
+// in case.
+func (q *Queries) GetUserByEmailInsensitive(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByEmailInsensitive, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserById = `-- name: GetUserById :one
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserById(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserById, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserByIdForUpdate = `-- name: GetUserByIdForUpdate :one
+SELECT id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at FROM users WHERE id = $1 FOR UPDATE
+`
+
+// GetUserByIdForUpdate locks the user so checks of their limits and the
+// insert they guard are not interleaved with another request.
+func (q *Queries) GetUserByIdForUpdate(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, getUserByIdForUpdate, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const getUserIdsByEmails = `-- name: GetUserIdsByEmails :many
+SELECT id, email FROM users WHERE lower(email) = ANY($1::text[])
+`
+
+type GetUserIdsByEmailsRow struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) GetUserIdsByEmails(ctx context.Context, emails []string) ([]GetUserIdsByEmailsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getUserIdsByEmails, pq.Array(emails))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetUserIdsByEmailsRow
+	for rows.Next() {
+		var i GetUserIdsByEmailsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.Email,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markEmailVerified = `-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+`
+
+type MarkEmailVerifiedParams struct {
+	ID    uuid.UUID
+	Email string
+}
+
+func (q *Queries) MarkEmailVerified(ctx context.Context, arg MarkEmailVerifiedParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, markEmailVerified, arg.ID, arg.Email)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const resetUsers = `-- name: ResetUsers :exec
 DELETE FROM users
 `
@@ -63,21 +194,97 @@ func (q *Queries) ResetUsers(ctx context.Context) error {
 	return err
 }
 
-const updateUser = `-- name: UpdateUser :one
+const setUserChirpyRed = `-- name: SetUserChirpyRed :one
 UPDATE users
-SET updated_at = NOW(), email = $2, hashed_password = $3
+SET is_chirpy_red = $2, updated_at = NOW()
 WHERE id = $1
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at
 `
 
-type UpdateUserParams struct {
-	ID             uuid.UUID
-	Email          string
-	HashedPassword string
+type SetUserChirpyRedParams struct {
+	ID          uuid.UUID
+	IsChirpyRed bool
 }
 
-func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
-	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
+func (q *Queries) SetUserChirpyRed(ctx context.Context, arg SetUserChirpyRedParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserChirpyRed, arg.ID, arg.IsChirpyRed)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const setUserRole = `-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at
+`
+
+type SetUserRoleParams struct {
+	ID   uuid.UUID
+	Role string
+}
+
+func (q *Queries) SetUserRole(ctx context.Context, arg SetUserRoleParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, setUserRole, arg.ID, arg.Role)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const suspendUser = `-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at
+`
+
+func (q *Queries) SuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, suspendUser, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.HashedPassword,
+		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
+	)
+	return i, err
+}
+
+const unsuspendUser = `-- name: UnsuspendUser :one
+UPDATE users
+SET suspended_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at
+`
+
+func (q *Queries) UnsuspendUser(ctx context.Context, id uuid.UUID) (User, error) {
+	row := q.db.QueryRowContext(ctx, unsuspendUser, id)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -86,19 +293,29 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
 
-const upgradeUserToChirpyRed = `-- name: UpgradeUserToChirpyRed :one
+const updateUser = `-- name: UpdateUser :one
 UPDATE users
-SET is_chirpy_red = TRUE
+SET updated_at = NOW(), email = $2, hashed_password = $3,
+  email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
 WHERE id = $1
-RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red
+RETURNING id, email, created_at, updated_at, hashed_password, is_chirpy_red, suspended_at, role, email_verified_at
 `
 
-func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (User, error) {
-	row := q.db.QueryRowContext(ctx, upgradeUserToChirpyRed, id)
+type UpdateUserParams struct {
+	ID             uuid.UUID
+	Email          string
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error) {
+	row := q.db.QueryRowContext(ctx, updateUser, arg.ID, arg.Email, arg.HashedPassword)
 	var i User
 	err := row.Scan(
 		&i.ID,
@@ -107,6 +324,25 @@ func (q *Queries) UpgradeUserToChirpyRed(ctx context.Context, id uuid.UUID) (Use
 		&i.UpdatedAt,
 		&i.HashedPassword,
 		&i.IsChirpyRed,
+		&i.SuspendedAt,
+		&i.Role,
+		&i.EmailVerifiedAt,
 	)
 	return i, err
 }
+
+const updateUserPassword = `-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type UpdateUserPasswordParams struct {
+	ID             uuid.UUID
+	HashedPassword string
+}
+
+func (q *Queries) UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error {
+	_, err := q.db.ExecContext(ctx, updateUserPassword, arg.ID, arg.HashedPassword)
+	return err
+}
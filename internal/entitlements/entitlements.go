@@ -0,0 +1,102 @@
+// Package entitlements defines what users of each Chirpy tier may do. The
+// limits live here, or in a file loaded with Load, rather than in handlers so
+// tiers can be tuned without code changes.
+package entitlements
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type Tier string
+
+const (
+	TierFree Tier = "free"
+	TierRed  Tier = "red"
+)
+
+// Limits are the entitlements of one tier. A zero count means no limit.
+type Limits struct {
+	MaxChirpLength     int  `json:"max_chirp_length"`
+	ChirpsPerHour      int  `json:"chirps_per_hour"`
+	EditChirps         bool `json:"edit_chirps"`
+	ScheduleChirps     bool `json:"schedule_chirps"`
+	MaxScheduledChirps int  `json:"max_scheduled_chirps"`
+}
+
+type Tiers struct {
+	Free Limits `json:"free"`
+	Red  Limits `json:"red"`
+}
+
+var Default = Tiers{
+	Free: Limits{
+		MaxChirpLength: 140,
+		ChirpsPerHour:  50,
+	},
+	Red: Limits{
+		MaxChirpLength:     500,
+		ChirpsPerHour:      200,
+		EditChirps:         true,
+		ScheduleChirps:     true,
+		MaxScheduledChirps: 50,
+	},
+}
+
+// TierOf returns the tier of a user.
+func TierOf(isChirpyRed bool) Tier {
+	if isChirpyRed {
+		return TierRed
+	}
+	return TierFree
+}
+
+// For returns the limits of tier.
+func (t Tiers) For(tier Tier) Limits {
+	if tier == TierRed {
+		return t.Red
+	}
+	return t.Free
+}
+
+// Load reads tiers from a JSON file shaped like Tiers. Limits missing from
+// the file keep their values from Default.
+func Load(path string) (Tiers, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Tiers{}, err
+	}
+
+	tiers := Default
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(&tiers)
+	if err != nil {
+		return Tiers{}, fmt.Errorf("%s: %w", path, err)
+	}
+
+	err = tiers.Validate()
+	if err != nil {
+		return Tiers{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return tiers, nil
+}
+
+// Validate checks that every tier can post chirps and no count is negative.
+func (t Tiers) Validate() error {
+	for _, tier := range []Tier{TierFree, TierRed} {
+		limits := t.For(tier)
+		if limits.MaxChirpLength < 1 {
+			return fmt.Errorf("%s: max_chirp_length must be at least 1", tier)
+		}
+		if limits.ChirpsPerHour < 0 {
+			return fmt.Errorf("%s: chirps_per_hour must not be negative", tier)
+		}
+		if limits.MaxScheduledChirps < 0 {
+			return fmt.Errorf("%s: max_scheduled_chirps must not be negative", tier)
+		}
+	}
+	return nil
+}
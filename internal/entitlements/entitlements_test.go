@@ -0,0 +1,57 @@
+package entitlements
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "entitlements.json")
+	err := os.WriteFile(path, []byte(content), 0600)
+	assert.NoError(t, err)
+	return path
+}
+
+func TestDefault(t *testing.T) {
+	assert.NoError(t, Default.Validate())
+	assert.Equal(t, 140, Default.For(TierOf(false)).MaxChirpLength)
+	assert.False(t, Default.For(TierFree).EditChirps)
+	assert.True(t, Default.For(TierOf(true)).EditChirps)
+	assert.Greater(t, Default.Red.MaxChirpLength, Default.Free.MaxChirpLength)
+	assert.Greater(t, Default.Red.ChirpsPerHour, Default.Free.ChirpsPerHour)
+}
+
+func TestLoad(t *testing.T) {
+	path := writeFile(t, `{"free": {"chirps_per_hour": 10}, "red": {"max_chirp_length": 1000, "max_scheduled_chirps": 0}}`)
+
+	tiers, err := Load(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, tiers.Free.ChirpsPerHour)
+	assert.Equal(t, 140, tiers.Free.MaxChirpLength)
+	assert.Equal(t, 1000, tiers.Red.MaxChirpLength)
+	assert.Equal(t, 0, tiers.Red.MaxScheduledChirps)
+	assert.True(t, tiers.Red.ScheduleChirps)
+}
+
+func TestLoad_Rejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"unknown field", `{"red": {"max_chirps": 10}}`},
+		{"unknown tier", `{"gold": {}}`},
+		{"zero length", `{"free": {"max_chirp_length": 0}}`},
+		{"negative rate", `{"red": {"chirps_per_hour": -1}}`},
+		{"not json", `free: 10`},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Load(writeFile(t, tc.content))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends plain text emails.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPMailer delivers mail through an SMTP server. Auth is skipped when no
+// username is configured, which suits local relays.
+type SMTPMailer struct {
+	addr string
+	host string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPMailer(host, port, username, password, from string) *SMTPMailer {
+	m := &SMTPMailer{addr: net.JoinHostPort(host, port), host: host, from: from}
+	if username != "" {
+		m.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return m
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) error {
+	data, err := buildMessage(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// net/smtp does not take a context, so give up waiting once it is done.
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- smtp.SendMail(m.addr, m.auth, m.from, []string{msg.To}, data)
+	}()
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// LogMailer writes messages to w instead of sending them. It is meant for
+// development and tests, where the links in the mails can be read off the log.
+type LogMailer struct {
+	mu   sync.Mutex
+	from string
+	w    io.Writer
+}
+
+func NewLogMailer(w io.Writer, from string) *LogMailer {
+	return &LogMailer{w: w, from: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	data, err := buildMessage(m.from, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err = fmt.Fprintf(m.w, "%s\r\n.\r\n", data)
+	return err
+}
+
+// buildMessage renders msg as an RFC 5322 message. Header values must not
+// contain line breaks, which would let them inject headers of their own.
+func buildMessage(from string, msg Message, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, msg.To, msg.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, fmt.Errorf("header value %q contains a line break", value)
+		}
+	}
+	if msg.To == "" {
+		return nil, fmt.Errorf("message has no recipient")
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "From: %s\r\n", from)
+	fmt.Fprintf(&builder, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&builder, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&builder, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(strings.ReplaceAll(msg.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return []byte(builder.String()), nil
+}
@@ -0,0 +1,51 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildMessage(t *testing.T) {
+	date := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	data, err := buildMessage("chirpy@example.com", Message{
+		To:      "user@example.com",
+		Subject: "Hello",
+		Body:    "Line one\nLine two",
+	}, date)
+	assert.NoError(t, err)
+
+	message := string(data)
+	assert.Contains(t, message, "From: chirpy@example.com\r\n")
+	assert.Contains(t, message, "To: user@example.com\r\n")
+	assert.Contains(t, message, "Subject: Hello\r\n")
+	assert.Contains(t, message, "Date: Wed, 01 May 2024 12:00:00 +0000\r\n")
+	assert.True(t, strings.HasSuffix(message, "\r\n\r\nLine one\r\nLine two"))
+}
+
+func TestBuildMessage_HeaderInjection(t *testing.T) {
+	_, err := buildMessage("chirpy@example.com", Message{To: "user@example.com", Subject: "Hi\r\nBcc: other@example.com"}, time.Now())
+	assert.Error(t, err)
+
+	_, err = buildMessage("chirpy@example.com", Message{To: "user@example.com\nBcc: other@example.com", Subject: "Hi"}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestBuildMessage_NoRecipient(t *testing.T) {
+	_, err := buildMessage("chirpy@example.com", Message{Subject: "Hi"}, time.Now())
+	assert.Error(t, err)
+}
+
+func TestLogMailer(t *testing.T) {
+	var buffer bytes.Buffer
+	m := NewLogMailer(&buffer, "chirpy@example.com")
+
+	err := m.Send(context.Background(), Message{To: "user@example.com", Subject: "Reset", Body: "https://example.com/reset?token=abc"})
+	assert.NoError(t, err)
+	assert.Contains(t, buffer.String(), "To: user@example.com")
+	assert.Contains(t, buffer.String(), "https://example.com/reset?token=abc")
+}
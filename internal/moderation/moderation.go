@@ -0,0 +1,214 @@
+package moderation
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type MatchType string
+
+const (
+	MatchWord      MatchType = "word"
+	MatchSubstring MatchType = "substring"
+)
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionFlag   Action = "flag"
+	ActionReject Action = "reject"
+)
+
+const mask = "****"
+
+type Rule struct {
+	Pattern string    `json:"pattern"`
+	Match   MatchType `json:"match"`
+	Action  Action    `json:"action"`
+}
+
+var DefaultRules = []Rule{
+	{Pattern: "kerfuffle", Match: MatchWord, Action: ActionMask},
+	{Pattern: "sharbert", Match: MatchWord, Action: ActionMask},
+	{Pattern: "fornax", Match: MatchWord, Action: ActionMask},
+}
+
+type Result struct {
+	Text     string
+	Rejected bool
+	Flagged  bool
+	Matches  []Rule
+}
+
+type compiledRule struct {
+	Rule
+	normalized string
+}
+
+type Filter struct {
+	mu    sync.RWMutex
+	rules []compiledRule
+}
+
+func NewFilter(rules []Rule) (*Filter, error) {
+	filter := &Filter{}
+	err := filter.SetRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// SetRules atomically replaces the rules used by the filter.
+func (f *Filter) SetRules(rules []Rule) error {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		err := ValidateRule(rule)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, compiledRule{Rule: rule, normalized: Normalize(rule.Pattern)})
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rules = compiled
+	return nil
+}
+
+func (f *Filter) Rules() []Rule {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	rules := make([]Rule, len(f.rules))
+	for i, rule := range f.rules {
+		rules[i] = rule.Rule
+	}
+	return rules
+}
+
+// Check runs text through the rules. Words matched by a mask rule are replaced
+// in Result.Text; reject and flag rules leave the text as is and set Rejected
+// or Flagged instead. A word matched by several rules gets the strictest action.
+func (f *Filter) Check(text string) Result {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	result := Result{}
+	var builder strings.Builder
+	last := 0
+	for _, token := range tokenize(text) {
+		normalized := Normalize(text[token.start:token.end])
+		var strictest *compiledRule
+		for i := range f.rules {
+			rule := &f.rules[i]
+			if !rule.matches(normalized) {
+				continue
+			}
+			result.Matches = append(result.Matches, rule.Rule)
+			if strictest == nil || severity(rule.Action) > severity(strictest.Action) {
+				strictest = rule
+			}
+		}
+		if strictest == nil {
+			continue
+		}
+
+		switch strictest.Action {
+		case ActionReject:
+			result.Rejected = true
+		case ActionFlag:
+			result.Flagged = true
+		case ActionMask:
+			builder.WriteString(text[last:token.start])
+			builder.WriteString(mask)
+			last = token.end
+		}
+	}
+	builder.WriteString(text[last:])
+	result.Text = builder.String()
+
+	return result
+}
+
+func (r compiledRule) matches(normalized string) bool {
+	if r.Match == MatchSubstring {
+		return strings.Contains(normalized, r.normalized)
+	}
+	return normalized == r.normalized
+}
+
+func severity(action Action) int {
+	switch action {
+	case ActionReject:
+		return 3
+	case ActionFlag:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func ValidateRule(rule Rule) error {
+	if Normalize(rule.Pattern) == "" {
+		return fmt.Errorf("pattern %q has no letters or digits", rule.Pattern)
+	}
+	if strings.ContainsFunc(rule.Pattern, unicode.IsSpace) {
+		return fmt.Errorf("pattern %q must be a single word", rule.Pattern)
+	}
+	if rule.Match != MatchWord && rule.Match != MatchSubstring {
+		return fmt.Errorf("unknown match type %q", rule.Match)
+	}
+	if rule.Action != ActionMask && rule.Action != ActionFlag && rule.Action != ActionReject {
+		return fmt.Errorf("unknown action %q", rule.Action)
+	}
+	return nil
+}
+
+// LoadRulesFile reads one rule per line in the form "pattern [match] [action]".
+// Match defaults to word and action to mask. Blank lines and lines starting
+// with '#' are ignored.
+func LoadRulesFile(path string) ([]Rule, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var rules []Rule
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("%s:%d: too many fields", path, lineNumber)
+		}
+		rule := Rule{Pattern: fields[0], Match: MatchWord, Action: ActionMask}
+		if len(fields) > 1 {
+			rule.Match = MatchType(fields[1])
+		}
+		if len(fields) > 2 {
+			rule.Action = Action(fields[2])
+		}
+		err := ValidateRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, lineNumber, err)
+		}
+		rules = append(rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return rules, nil
+}
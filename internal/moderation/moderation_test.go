@@ -0,0 +1,114 @@
+package moderation
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck_DefaultRules(t *testing.T) {
+	filter, err := NewFilter(DefaultRules)
+	assert.NoError(t, err)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"This is a kerfuffle", "This is a ****"},
+		{"Sharbert is a word", "**** is a word"},
+		{"Fornax is banned", "**** is banned"},
+		{"No banned words here", "No banned words here"},
+		{"Mixed case Kerfuffle", "Mixed case ****"},
+		{"What a Kerfuffle!", "What a ****!"},
+		{"sharbert, fornax.", "****, ****."},
+		{"A  kerfuffle\tand\nfornax", "A  ****\tand\n****"},
+		{"KERFUFFLES are fine", "KERFUFFLES are fine"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, filter.Check(test.input).Text, test.input)
+	}
+}
+
+func TestCheck_Normalization(t *testing.T) {
+	filter, err := NewFilter(DefaultRules)
+	assert.NoError(t, err)
+
+	inputs := []string{
+		"Kérfüffle",
+		"kérfuffle",
+		"k3rfuffl3",
+		"$harbert",
+		"ѕharbert",
+		"ker\u200bfuffle",
+		"ＦＯＲＮＡＸ",
+		"f0rn@x",
+	}
+
+	for _, input := range inputs {
+		assert.Equal(t, "****", filter.Check(input).Text, input)
+	}
+}
+
+func TestCheck_Actions(t *testing.T) {
+	filter, err := NewFilter([]Rule{
+		{Pattern: "darn", Match: MatchSubstring, Action: ActionMask},
+		{Pattern: "spam", Match: MatchWord, Action: ActionFlag},
+		{Pattern: "slur", Match: MatchWord, Action: ActionReject},
+		{Pattern: "spam", Match: MatchSubstring, Action: ActionReject},
+	})
+	assert.NoError(t, err)
+
+	result := filter.Check("Darnit, that's a darn shame")
+	assert.Equal(t, "****, that's a **** shame", result.Text)
+	assert.False(t, result.Rejected)
+	assert.False(t, result.Flagged)
+
+	result = filter.Check("this is a SLUR")
+	assert.True(t, result.Rejected)
+	assert.Equal(t, "this is a SLUR", result.Text)
+
+	result = filter.Check("spam spam")
+	assert.True(t, result.Rejected, "strictest action wins")
+	assert.False(t, result.Flagged)
+	assert.Len(t, result.Matches, 4)
+}
+
+func TestCheck_Flag(t *testing.T) {
+	filter, err := NewFilter([]Rule{{Pattern: "giveaway", Match: MatchWord, Action: ActionFlag}})
+	assert.NoError(t, err)
+
+	result := filter.Check("Free giveaway!")
+	assert.True(t, result.Flagged)
+	assert.False(t, result.Rejected)
+	assert.Equal(t, "Free giveaway!", result.Text)
+	assert.Equal(t, []Rule{{Pattern: "giveaway", Match: MatchWord, Action: ActionFlag}}, result.Matches)
+}
+
+func TestValidateRule(t *testing.T) {
+	assert.NoError(t, ValidateRule(Rule{Pattern: "word", Match: MatchWord, Action: ActionMask}))
+	assert.Error(t, ValidateRule(Rule{Pattern: "!!!", Match: MatchWord, Action: ActionMask}))
+	assert.Error(t, ValidateRule(Rule{Pattern: "two words", Match: MatchWord, Action: ActionMask}))
+	assert.Error(t, ValidateRule(Rule{Pattern: "word", Match: "regex", Action: ActionMask}))
+	assert.Error(t, ValidateRule(Rule{Pattern: "word", Match: MatchWord, Action: "ban"}))
+}
+
+func TestLoadRulesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	content := "# banned words\nkerfuffle\n\nspam substring flag\nslur word reject\n"
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+	rules, err := LoadRulesFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []Rule{
+		{Pattern: "kerfuffle", Match: MatchWord, Action: ActionMask},
+		{Pattern: "spam", Match: MatchSubstring, Action: ActionFlag},
+		{Pattern: "slur", Match: MatchWord, Action: ActionReject},
+	}, rules)
+
+	assert.NoError(t, os.WriteFile(path, []byte("spam sometimes\n"), 0o600))
+	_, err = LoadRulesFile(path)
+	assert.Error(t, err)
+}
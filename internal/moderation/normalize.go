@@ -0,0 +1,109 @@
+package moderation
+
+import (
+	"strings"
+	"unicode"
+)
+
+// confusables maps look-alike characters to the ASCII letter they imitate.
+var confusables = map[rune]string{
+	'0': "o", '1': "i", '3': "e", '4': "a", '5': "s", '7': "t", '@': "a", '$': "s",
+	// Cyrillic
+	'а': "a", 'в': "b", 'е': "e", 'ё': "e", 'к': "k", 'м': "m", 'н': "h", 'о': "o",
+	'р': "p", 'с': "c", 'т': "t", 'у': "y", 'х': "x", 'і': "i", 'ї': "i", 'ј': "j", 'ѕ': "s",
+	// Greek
+	'α': "a", 'β': "b", 'ε': "e", 'η': "n", 'ι': "i", 'κ': "k", 'ν': "v", 'ο': "o",
+	'ρ': "p", 'τ': "t", 'υ': "u", 'χ': "x",
+}
+
+// accents folds precomposed Latin letters to their base letter.
+var accents = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ś': "s", 'š': "s", 'ş': "s", 'ß': "ss", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z", 'ſ': "s",
+}
+
+// Normalize folds a word to the form rules are matched against: lowercase
+// ASCII with accents, fullwidth forms and look-alike characters replaced and
+// invisible characters dropped.
+func Normalize(word string) string {
+	var builder strings.Builder
+	for _, r := range word {
+		r = unicode.ToLower(foldWidth(r))
+		if unicode.Is(unicode.Mn, r) || isInvisible(r) {
+			continue
+		}
+		if folded, ok := accents[r]; ok {
+			builder.WriteString(folded)
+			continue
+		}
+		if folded, ok := confusables[r]; ok {
+			builder.WriteString(folded)
+			continue
+		}
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+type token struct {
+	start int
+	end   int
+}
+
+// tokenize splits text into words and returns their byte ranges. Look-alike
+// symbols and invisible characters count as part of a word so that "$harbert"
+// and words split by zero-width characters are still seen as one word.
+func tokenize(text string) []token {
+	var tokens []token
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			tokens = append(tokens, token{start: start, end: i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		tokens = append(tokens, token{start: start, end: len(text)})
+	}
+	return tokens
+}
+
+func isWordRune(r rune) bool {
+	r = foldWidth(r)
+	if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || isInvisible(r) {
+		return true
+	}
+	_, ok := confusables[r]
+	return ok
+}
+
+func isInvisible(r rune) bool {
+	switch r {
+	case '\u00ad', '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff':
+		return true
+	}
+	return false
+}
+
+// foldWidth maps fullwidth ASCII variants to plain ASCII.
+func foldWidth(r rune) rune {
+	if r >= '\uff01' && r <= '\uff5e' {
+		return r - 0xfee0
+	}
+	return r
+}
@@ -0,0 +1,164 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// minRefreshInterval limits how often an unknown kid makes the cache refetch
+// the provider's keys, so forged tokens cannot be used to hammer it.
+const minRefreshInterval = time.Minute
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type providerKey struct {
+	alg    string
+	public crypto.PublicKey
+}
+
+// keyCache holds the provider's signing keys and refetches them when a token
+// names a kid it does not know, which is how providers rotate keys.
+type keyCache struct {
+	client *http.Client
+	url    string
+
+	mu        sync.Mutex
+	keys      map[string]providerKey
+	fetchedAt time.Time
+}
+
+func newKeyCache(client *http.Client, url string) *keyCache {
+	return &keyCache{client: client, url: url}
+}
+
+func (c *keyCache) key(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key, ok := c.lookup(kid)
+	if !ok && time.Since(c.fetchedAt) >= minRefreshInterval {
+		err := c.refresh(ctx)
+		if err != nil {
+			return nil, err
+		}
+		key, ok = c.lookup(kid)
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if key.alg != alg {
+		return nil, fmt.Errorf("key %q is for %s, not %s", kid, key.alg, alg)
+	}
+	return key.public, nil
+}
+
+// lookup finds a key by kid. Tokens without a kid are accepted only while the
+// provider publishes a single key.
+func (c *keyCache) lookup(kid string) (providerKey, bool) {
+	if kid == "" && len(c.keys) == 1 {
+		for _, key := range c.keys {
+			return key, true
+		}
+	}
+	key, ok := c.keys[kid]
+	return key, ok
+}
+
+func (c *keyCache) refresh(ctx context.Context) error {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := getJSON(ctx, c.client, c.url, &set)
+	if err != nil {
+		return fmt.Errorf("fetching provider keys: %w", err)
+	}
+
+	keys := make(map[string]providerKey)
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := parseJWK(jwk)
+		if err != nil {
+			// Providers may publish key types we do not use; skip them.
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	c.keys = keys
+	c.fetchedAt = time.Now()
+	return nil
+}
+
+func parseJWK(jwk jsonWebKey) (providerKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeBigInt(jwk.N)
+		if err != nil {
+			return providerKey{}, err
+		}
+		e, err := decodeBigInt(jwk.E)
+		if err != nil {
+			return providerKey{}, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return providerKey{}, fmt.Errorf("key %q: invalid exponent", jwk.Kid)
+		}
+		return providerKey{alg: "RS256", public: &rsa.PublicKey{N: n, E: int(e.Int64())}}, nil
+	case "EC":
+		if jwk.Crv != "P-256" {
+			return providerKey{}, fmt.Errorf("key %q: unsupported curve %s", jwk.Kid, jwk.Crv)
+		}
+		x, err := decodeBigInt(jwk.X)
+		if err != nil {
+			return providerKey{}, err
+		}
+		y, err := decodeBigInt(jwk.Y)
+		if err != nil {
+			return providerKey{}, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return providerKey{}, fmt.Errorf("key %q: point is not on the curve", jwk.Kid)
+		}
+		return providerKey{alg: "ES256", public: &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}}, nil
+	case "OKP":
+		if jwk.Crv != "Ed25519" {
+			return providerKey{}, fmt.Errorf("key %q: unsupported curve %s", jwk.Kid, jwk.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return providerKey{}, fmt.Errorf("key %q: invalid public key", jwk.Kid)
+		}
+		return providerKey{alg: "EdDSA", public: ed25519.PublicKey(x)}, nil
+	default:
+		return providerKey{}, fmt.Errorf("key %q: unsupported key type %s", jwk.Kid, jwk.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(data) == 0 {
+		return nil, fmt.Errorf("invalid key parameter %q", s)
+	}
+	return new(big.Int).SetBytes(data), nil
+}
@@ -0,0 +1,209 @@
+// Package oidc signs users in with an external OpenID Connect provider using
+// the authorization code flow.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/isotronic/http-go-server/internal/auth"
+)
+
+// Metadata is the part of a provider's discovery document the client uses.
+type Metadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	// Scopes are requested in addition to openid. Defaults to email and
+	// profile.
+	Scopes []string
+	// HTTPClient is used for every request to the provider. Defaults to a
+	// client with a 10 second timeout.
+	HTTPClient *http.Client
+}
+
+// Client talks to one provider on behalf of one registered client.
+type Client struct {
+	Metadata Metadata
+	config   Config
+	keys     *keyCache
+}
+
+// Tokens is the successful response of the token endpoint.
+type Tokens struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+}
+
+// IDToken holds the verified claims of an ID token.
+type IDToken struct {
+	Email           string `json:"email"`
+	EmailVerified   bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	Nonce           string `json:"nonce"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Discover fetches the provider's discovery document from
+// issuer/.well-known/openid-configuration and checks that it describes issuer.
+func Discover(ctx context.Context, issuer string, config Config) (*Client, error) {
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"email", "profile"}
+	}
+
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	var metadata Metadata
+	err := getJSON(ctx, config.HTTPClient, wellKnown, &metadata)
+	if err != nil {
+		return nil, fmt.Errorf("fetching discovery document: %w", err)
+	}
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q, not %q", metadata.Issuer, issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing an endpoint")
+	}
+
+	return &Client{
+		Metadata: metadata,
+		config:   config,
+		keys:     newKeyCache(config.HTTPClient, metadata.JWKSURI),
+	}, nil
+}
+
+// AuthCodeURL returns the provider URL to send the user to. state and nonce
+// must be random and remembered until the callback; codeVerifier is the PKCE
+// verifier later passed to Exchange.
+func (c *Client) AuthCodeURL(state, nonce, codeVerifier string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {c.config.ClientID},
+		"redirect_uri":          {c.config.RedirectURL},
+		"scope":                 {strings.Join(append([]string{"openid"}, c.config.Scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {auth.PKCEChallenge(codeVerifier)},
+		"code_challenge_method": {auth.PKCEMethodS256},
+	}
+
+	separator := "?"
+	if strings.Contains(c.Metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return c.Metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// Exchange redeems an authorization code at the token endpoint.
+func (c *Client) Exchange(ctx context.Context, code, codeVerifier string) (Tokens, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {c.config.RedirectURL},
+		"code_verifier": {codeVerifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", c.Metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return Tokens{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(c.config.ClientID), url.QueryEscape(c.config.ClientSecret))
+
+	res, err := c.config.HTTPClient.Do(req)
+	if err != nil {
+		return Tokens{}, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return Tokens{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		json.Unmarshal(body, &oauthErr)
+		return Tokens{}, fmt.Errorf("token endpoint returned %d: %s %s", res.StatusCode, oauthErr.Error, oauthErr.Description)
+	}
+
+	var tokens Tokens
+	err = json.Unmarshal(body, &tokens)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("decoding token response: %w", err)
+	}
+	if tokens.IDToken == "" {
+		return Tokens{}, errors.New("token response has no id_token")
+	}
+	return tokens, nil
+}
+
+// VerifyIDToken checks the signature of an ID token against the provider's
+// keys, then its issuer, audience, expiry and nonce.
+func (c *Client) VerifyIDToken(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	claims := &IDToken{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return c.keys.key(ctx, kid, t.Method.Alg())
+	},
+		jwt.WithValidMethods([]string{"RS256", "ES256", "EdDSA"}),
+		jwt.WithIssuer(c.Metadata.Issuer),
+		jwt.WithAudience(c.config.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("ID token has no subject")
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != c.config.ClientID {
+		return nil, errors.New("ID token was issued to another party")
+	}
+	if nonce == "" || claims.Nonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+	return claims, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned %d", url, res.StatusCode)
+	}
+	return json.NewDecoder(io.LimitReader(res.Body, 1<<20)).Decode(v)
+}
@@ -0,0 +1,275 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testClientID     = "chirpy"
+	testClientSecret = "secret"
+	testRedirectURL  = "https://chirpy.example.com/oidc/callback"
+)
+
+// fakeProvider is an in-process OpenID Connect provider. Codes are handed
+// out by authorize, as if the user had signed in, and redeemed at its token
+// endpoint for an ID token signed with the current key.
+type fakeProvider struct {
+	t      *testing.T
+	server *httptest.Server
+
+	mu     sync.Mutex
+	keyID  string
+	key    *rsa.PrivateKey
+	codes  map[string]fakeGrant
+	claims func(claims jwt.MapClaims)
+}
+
+type fakeGrant struct {
+	nonce     string
+	challenge string
+	subject   string
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	p := &fakeProvider{t: t, codes: make(map[string]fakeGrant)}
+	p.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(Metadata{
+			Issuer:                p.server.URL,
+			AuthorizationEndpoint: p.server.URL + "/authorize",
+			TokenEndpoint:         p.server.URL + "/token",
+			JWKSURI:               p.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": p.keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", p.handleToken)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeProvider) rotateKey(id string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keyID = id
+	p.key = key
+}
+
+// authorize stands in for the user signing in at the provider.
+func (p *fakeProvider) authorize(authURL, subject string) (code, state string) {
+	parsed, err := url.Parse(authURL)
+	assert.NoError(p.t, err)
+	query := parsed.Query()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	code = "code-" + subject
+	p.codes[code] = fakeGrant{nonce: query.Get("nonce"), challenge: query.Get("code_challenge"), subject: subject}
+	return code, query.Get("state")
+}
+
+func (p *fakeProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || secret != testClientSecret {
+		w.WriteHeader(401)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	grant, ok := p.codes[r.PostFormValue("code")]
+	delete(p.codes, r.PostFormValue("code"))
+	if !ok || r.PostFormValue("redirect_uri") != testRedirectURL || !auth.VerifyPKCE(r.PostFormValue("code_verifier"), grant.challenge) {
+		w.WriteHeader(400)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            grant.subject,
+		"aud":            testClientID,
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          grant.nonce,
+		"email":          grant.subject + "@example.com",
+		"email_verified": true,
+	}
+	if p.claims != nil {
+		p.claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.keyID
+	idToken, err := token.SignedString(p.key)
+	assert.NoError(p.t, err)
+
+	json.NewEncoder(w).Encode(Tokens{AccessToken: "access", TokenType: "Bearer", IDToken: idToken})
+}
+
+func discover(t *testing.T, p *fakeProvider) *Client {
+	client, err := Discover(context.Background(), p.server.URL, Config{
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURL:  testRedirectURL,
+	})
+	assert.NoError(t, err)
+	return client
+}
+
+// signIn runs the whole flow for subject and returns the verified ID token.
+func signIn(t *testing.T, p *fakeProvider, client *Client, subject string) (*IDToken, error) {
+	verifier := "verifier-0123456789-0123456789-0123456789-0123456789"
+	code, state := p.authorize(client.AuthCodeURL("state-1", "nonce-1", verifier), subject)
+	assert.Equal(t, "state-1", state)
+
+	tokens, err := client.Exchange(context.Background(), code, verifier)
+	if err != nil {
+		return nil, err
+	}
+	return client.VerifyIDToken(context.Background(), tokens.IDToken, "nonce-1")
+}
+
+func TestDiscover(t *testing.T) {
+	p := newFakeProvider(t)
+	client := discover(t, p)
+	assert.Equal(t, p.server.URL+"/token", client.Metadata.TokenEndpoint)
+
+	_, err := Discover(context.Background(), p.server.URL+"/other", Config{ClientID: testClientID})
+	assert.Error(t, err)
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	p := newFakeProvider(t)
+	client := discover(t, p)
+
+	parsed, err := url.Parse(client.AuthCodeURL("state-1", "nonce-1", "verifier"))
+	assert.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, testClientID, query.Get("client_id"))
+	assert.Equal(t, testRedirectURL, query.Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "state-1", query.Get("state"))
+	assert.Equal(t, "nonce-1", query.Get("nonce"))
+	assert.Equal(t, auth.PKCEChallenge("verifier"), query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+}
+
+func TestSignIn(t *testing.T) {
+	p := newFakeProvider(t)
+	client := discover(t, p)
+
+	idToken, err := signIn(t, p, client, "alice")
+	assert.NoError(t, err)
+	assert.Equal(t, "alice", idToken.Subject)
+	assert.Equal(t, "alice@example.com", idToken.Email)
+	assert.True(t, idToken.EmailVerified)
+}
+
+func TestExchange_WrongVerifier(t *testing.T) {
+	p := newFakeProvider(t)
+	client := discover(t, p)
+
+	code, _ := p.authorize(client.AuthCodeURL("state-1", "nonce-1", "verifier-0123456789-0123456789-0123456789-0123456789"), "alice")
+	_, err := client.Exchange(context.Background(), code, "other-verifier-0123456789-0123456789-0123456789")
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestVerifyIDToken_Rejects(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims func(claims jwt.MapClaims)
+	}{
+		{"wrong nonce", func(c jwt.MapClaims) { c["nonce"] = "other" }},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "someone-else" }},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }},
+		{"other authorized party", func(c jwt.MapClaims) {
+			c["aud"] = []string{testClientID, "someone-else"}
+			c["azp"] = "someone-else"
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newFakeProvider(t)
+			p.claims = tc.claims
+			client := discover(t, p)
+
+			_, err := signIn(t, p, client, "alice")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestVerifyIDToken_ForgedSignature(t *testing.T) {
+	p := newFakeProvider(t)
+	client := discover(t, p)
+
+	forger, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   p.server.URL,
+		"sub":   "alice",
+		"aud":   testClientID,
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": "nonce-1",
+	})
+	token.Header["kid"] = "key-1"
+	forged, err := token.SignedString(forger)
+	assert.NoError(t, err)
+
+	_, err = client.VerifyIDToken(context.Background(), forged, "nonce-1")
+	assert.Error(t, err)
+}
+
+func TestVerifyIDToken_KeyRotation(t *testing.T) {
+	p := newFakeProvider(t)
+	client := discover(t, p)
+
+	_, err := signIn(t, p, client, "alice")
+	assert.NoError(t, err)
+
+	// Right after a fetch, an unknown kid does not trigger another one.
+	p.rotateKey("key-2")
+	_, err = signIn(t, p, client, "alice")
+	assert.Error(t, err)
+
+	client.keys.fetchedAt = time.Time{}
+	_, err = signIn(t, p, client, "alice")
+	assert.NoError(t, err)
+}
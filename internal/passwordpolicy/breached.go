@@ -0,0 +1,143 @@
+package passwordpolicy
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const prefixLength = 5
+
+// MaxBreachedFileSize is the largest single file OpenBreachedList loads into
+// memory, about a million hashes. Larger lists, such as the full Pwned
+// Passwords dump, have to be split into a directory of range files.
+const MaxBreachedFileSize = 64 << 20
+
+// BreachedList looks passwords up by SHA-1 in the layout of the Pwned
+// Passwords range API: the first five hex digits of a hash select a range,
+// which lists the remaining 35 digits as "SUFFIX:COUNT" lines. Only the
+// prefix is used to find a range, so the full hash never needs to be handed
+// to anything but the range itself.
+type BreachedList struct {
+	// dir holds one file per range, named by the prefix, read on demand.
+	dir string
+	// ranges holds every range in memory when loaded from a single file.
+	ranges map[string]map[string]int
+}
+
+// OpenBreachedList opens path, which is either a directory of range files
+// named by prefix (optionally with a .txt extension) or a single file with one
+// full "HASH:COUNT" line per password. The count is optional in both. A single
+// file larger than MaxBreachedFileSize is refused rather than loaded.
+func OpenBreachedList(path string) (*BreachedList, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &BreachedList{dir: path}, nil
+	}
+	if info.Size() > MaxBreachedFileSize {
+		return nil, fmt.Errorf("%s: %d bytes is too large to load into memory, use a directory of range files instead", path, info.Size())
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	list := &BreachedList{ranges: make(map[string]map[string]int)}
+	err = readRange(file, func(hash string, count int) error {
+		if len(hash) != sha1.Size*2 {
+			return fmt.Errorf("invalid SHA-1 hash %q", hash)
+		}
+		prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+		if list.ranges[prefix] == nil {
+			list.ranges[prefix] = make(map[string]int)
+		}
+		list.ranges[prefix][suffix] += count
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return list, nil
+}
+
+// Count returns how often password appears in the list.
+func (b *BreachedList) Count(password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:prefixLength], hash[prefixLength:]
+
+	if b.ranges != nil {
+		return b.ranges[prefix][suffix], nil
+	}
+
+	file, err := openRange(b.dir, prefix)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	found := 0
+	err = readRange(file, func(candidate string, count int) error {
+		if candidate == suffix {
+			found = count
+			return io.EOF
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	return found, nil
+}
+
+func openRange(dir, prefix string) (*os.File, error) {
+	file, err := os.Open(filepath.Join(dir, prefix))
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.Open(filepath.Join(dir, prefix+".txt"))
+	}
+	return file, err
+}
+
+// readRange calls fn for every "HASH[:COUNT]" line in r with the hash in
+// upper case. Blank lines are skipped and a missing count counts as one.
+func readRange(r io.Reader, fn func(hash string, count int) error) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, countText, hasCount := strings.Cut(line, ":")
+		count := 1
+		if hasCount {
+			var err error
+			count, err = strconv.Atoi(countText)
+			if err != nil {
+				return fmt.Errorf("invalid count in line %q", line)
+			}
+		}
+
+		err := fn(strings.ToUpper(hash), count)
+		if err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
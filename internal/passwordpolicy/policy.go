@@ -0,0 +1,147 @@
+package passwordpolicy
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	CodeTooShort      = "too_short"
+	CodeTooLong       = "too_long"
+	CodeTooWeak       = "too_weak"
+	CodeContainsEmail = "contains_email"
+	CodeBreached      = "breached"
+)
+
+// Violation is one reason a password was rejected.
+type Violation struct {
+	Code    string
+	Message string
+}
+
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// MinEntropy is the minimum estimated entropy in bits, see Entropy.
+	MinEntropy float64
+	// Breached is consulted last, if set.
+	Breached *BreachedList
+}
+
+var DefaultPolicy = Policy{
+	MinLength:  8,
+	MaxLength:  256,
+	MinEntropy: 35,
+}
+
+// Check returns every rule password breaks, or nil if it is acceptable. The
+// error is only set when the breached list could not be read; the other rules
+// are still applied in that case.
+func (p Policy) Check(password, email string) ([]Violation, error) {
+	var violations []Violation
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations = append(violations, Violation{CodeTooShort, fmt.Sprintf("Password must be at least %d characters long", p.MinLength)})
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations = append(violations, Violation{CodeTooLong, fmt.Sprintf("Password must be at most %d characters long", p.MaxLength)})
+	}
+	if length >= p.MinLength && Entropy(password) < p.MinEntropy {
+		violations = append(violations, Violation{CodeTooWeak, "Password is too easy to guess; use a longer password or mix in other kinds of characters"})
+	}
+	if containsEmail(password, email) {
+		violations = append(violations, Violation{CodeContainsEmail, "Password must not contain your email address"})
+	}
+
+	if p.Breached != nil {
+		count, err := p.Breached.Count(password)
+		if err != nil {
+			return violations, err
+		}
+		if count > 0 {
+			violations = append(violations, Violation{CodeBreached, "Password has appeared in a data breach; choose a different one"})
+		}
+	}
+
+	return violations, nil
+}
+
+// Entropy estimates the entropy of password in bits as the number of
+// effective characters times the bits per character of the character classes
+// used. A character that repeats the previous one or continues a run like
+// "abc" or "321" does not count as effective.
+func Entropy(password string) float64 {
+	var lower, upper, digit, symbol, other bool
+	effective := 0
+	var previous rune
+	var step rune
+	for i, r := range []rune(password) {
+		switch {
+		case r > unicode.MaxASCII:
+			other = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+
+		delta := r - previous
+		switch {
+		case i == 0:
+			effective++
+		case delta == 0:
+		case (delta == 1 || delta == -1) && (i == 1 || delta == step):
+		default:
+			effective++
+		}
+		if i > 0 {
+			step = delta
+		}
+		previous = r
+	}
+
+	pool := 0
+	if lower {
+		pool += 26
+	}
+	if upper {
+		pool += 26
+	}
+	if digit {
+		pool += 10
+	}
+	if symbol {
+		pool += 33
+	}
+	if other {
+		pool += 100
+	}
+	if pool == 0 {
+		return 0
+	}
+	return float64(effective) * math.Log2(float64(pool))
+}
+
+// containsEmail reports whether password is the email address or contains
+// its local part. Local parts shorter than four characters are ignored, since
+// they would match too many unrelated passwords.
+func containsEmail(password, email string) bool {
+	password = strings.ToLower(password)
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	if password == email {
+		return true
+	}
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 4 && strings.Contains(password, local)
+}
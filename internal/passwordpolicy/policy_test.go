@@ -0,0 +1,127 @@
+package passwordpolicy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func codes(violations []Violation) []string {
+	var result []string
+	for _, v := range violations {
+		result = append(result, v.Code)
+	}
+	return result
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		email    string
+		want     []string
+	}{
+		{"acceptable", "correct horse battery", "user@example.com", nil},
+		{"too short", "a1!B", "user@example.com", []string{CodeTooShort}},
+		{"sequence", "12345678", "user@example.com", []string{CodeTooWeak}},
+		{"repeated", "aaaaaaaaaaaa", "user@example.com", []string{CodeTooWeak}},
+		{"email", "Walter@Example.com", "walter@example.com", []string{CodeContainsEmail}},
+		{"local part", "walter-is-great-2024", "walter@example.com", []string{CodeContainsEmail}},
+		{"short local part", "bob-is-great-2024", "bob@example.com", nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			violations, err := DefaultPolicy.Check(tc.password, tc.email)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, codes(violations))
+		})
+	}
+}
+
+func TestCheck_TooLong(t *testing.T) {
+	policy := Policy{MinLength: 1, MaxLength: 4}
+	violations, err := policy.Check("kT9#qL", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{CodeTooLong}, codes(violations))
+}
+
+func TestEntropy(t *testing.T) {
+	assert.Equal(t, 0.0, Entropy(""))
+	assert.Less(t, Entropy("abcdefgh"), Entropy("hgaebfcd"))
+	assert.Less(t, Entropy("aaaaaaaa"), Entropy("abababab"))
+	assert.Less(t, Entropy("kqzvwmxp"), Entropy("kQ7#vWm!"))
+}
+
+// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8.
+const passwordHash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+func TestBreachedList_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	data := "f3bbbd66a63d4bf1747940578ec3d0103530e21d:17\n\n" + passwordHash + ":9545824\n"
+	assert.NoError(t, os.WriteFile(path, []byte(data), 0o644))
+
+	list, err := OpenBreachedList(path)
+	assert.NoError(t, err)
+
+	count, err := list.Count("password")
+	assert.NoError(t, err)
+	assert.Equal(t, 9545824, count)
+
+	count, err = list.Count("hunter2")
+	assert.NoError(t, err)
+	assert.Equal(t, 17, count)
+
+	count, err = list.Count("correct horse battery")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestBreachedList_Dir(t *testing.T) {
+	dir := t.TempDir()
+	data := "0018A45C4D1DEF81644B54AB7F969B88D65:1\n" + passwordHash[5:] + ":9545824\n"
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, passwordHash[:5]+".txt"), []byte(data), 0o644))
+
+	list, err := OpenBreachedList(dir)
+	assert.NoError(t, err)
+
+	count, err := list.Count("password")
+	assert.NoError(t, err)
+	assert.Equal(t, 9545824, count)
+
+	// No range file for this prefix means the password was not found.
+	count, err = list.Count("correct horse battery")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestBreachedList_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte("not-a-hash:1\n"), 0o644))
+
+	_, err := OpenBreachedList(path)
+	assert.Error(t, err)
+}
+
+func TestBreachedList_FileTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(passwordHash+":9545824\n"), 0o644))
+	assert.NoError(t, os.Truncate(path, MaxBreachedFileSize+1))
+
+	_, err := OpenBreachedList(path)
+	assert.ErrorContains(t, err, "directory of range files")
+}
+
+func TestCheck_Breached(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(passwordHash+":1\n"), 0o644))
+	list, err := OpenBreachedList(path)
+	assert.NoError(t, err)
+
+	policy := Policy{MinLength: 1, Breached: list}
+	violations, err := policy.Check("password", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{CodeBreached}, codes(violations))
+}
@@ -0,0 +1,94 @@
+// Package webhook signs and verifies webhook deliveries with HMAC-SHA256.
+//
+// A signed request carries a header of the form
+//
+//	t=1700000000,v1=5257a869e7ecebeda32affa62cdca3fa51cad7e77a0e56ff536d0ce8e108d8bd
+//
+// where t is the Unix time the request was sent and v1 is the hex encoded
+// HMAC-SHA256 of "t.body" under the shared secret. The header may carry more
+// than one v1 value while the sender rotates its secret.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTolerance is how far the signed timestamp may be from the current
+// time. Older deliveries are rejected so a captured request cannot be
+// replayed later.
+const DefaultTolerance = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("webhook: missing signature")
+	ErrMalformedHeader  = errors.New("webhook: malformed signature header")
+	ErrInvalidSignature = errors.New("webhook: signature does not match")
+	ErrExpired          = errors.New("webhook: timestamp outside the tolerance")
+)
+
+// Sign returns the signature header for body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac(secret, t, body))
+}
+
+// Verify checks header against body. The signature is checked before the
+// timestamp, so a forged header never reveals whether its time was right.
+func Verify(header string, body []byte, secret string, now time.Time, tolerance time.Duration) error {
+	if header == "" {
+		return ErrMissingSignature
+	}
+
+	var t string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			return ErrMalformedHeader
+		}
+		switch key {
+		case "t":
+			t = value
+		case "v1":
+			signature, err := hex.DecodeString(value)
+			if err != nil {
+				return ErrMalformedHeader
+			}
+			signatures = append(signatures, signature)
+		}
+	}
+	timestamp, err := strconv.ParseInt(t, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return ErrMalformedHeader
+	}
+
+	expected := mac(secret, t, body)
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal(signature, expected) {
+			valid = true
+		}
+	}
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrExpired
+	}
+	return nil
+}
+
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,62 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "whsec_test"
+
+func TestVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	header := Sign(testSecret, now, body)
+
+	assert.NoError(t, Verify(header, body, testSecret, now, DefaultTolerance))
+	assert.NoError(t, Verify(header, body, testSecret, now.Add(4*time.Minute), DefaultTolerance))
+}
+
+func TestVerify_Rejects(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"evt_1","event":"user.upgraded"}`)
+	header := Sign(testSecret, now, body)
+
+	tests := []struct {
+		name   string
+		header string
+		body   []byte
+		secret string
+		now    time.Time
+		want   error
+	}{
+		{"missing header", "", body, testSecret, now, ErrMissingSignature},
+		{"no timestamp", strings.Split(header, ",")[1], body, testSecret, now, ErrMalformedHeader},
+		{"no signature", strings.Split(header, ",")[0], body, testSecret, now, ErrMalformedHeader},
+		{"bad hex", "t=1700000000,v1=zz", body, testSecret, now, ErrMalformedHeader},
+		{"tampered body", header, []byte(`{"id":"evt_1","event":"user.downgraded"}`), testSecret, now, ErrInvalidSignature},
+		{"wrong secret", header, body, "whsec_other", now, ErrInvalidSignature},
+		{"replayed", header, body, testSecret, now.Add(10 * time.Minute), ErrExpired},
+		{"from the future", header, body, testSecret, now.Add(-10 * time.Minute), ErrExpired},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Verify(tc.header, tc.body, tc.secret, tc.now, DefaultTolerance)
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestVerify_RotatedSecret(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{}`)
+	oldHeader := Sign("whsec_old", now, body)
+	newHeader := Sign(testSecret, now, body)
+	header := newHeader + "," + strings.Split(oldHeader, ",")[1]
+
+	assert.NoError(t, Verify(header, body, testSecret, now, DefaultTolerance))
+	assert.NoError(t, Verify(header, body, "whsec_old", now, DefaultTolerance))
+}
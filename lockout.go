@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	lockoutKindAccount = "account"
+	lockoutKindIP = "ip"
+	lockoutKindMFA = "mfa"
+	lockoutKindResetAccount = "reset_account"
+	lockoutKindResetIP = "reset_ip"
+)
+
+// lockoutPolicy describes how failed logins are throttled. Once failures reach
+// Threshold, every further failure locks logins for Base doubled per failure
+// above the threshold, capped at Max. Counting starts over when no failure
+// happened for Window.
+type lockoutPolicy struct {
+	Threshold int32
+	Base time.Duration
+	Max time.Duration
+	Window time.Duration
+}
+
+var (
+	accountLockout = lockoutPolicy{Threshold: 5, Base: 30 * time.Second, Max: time.Hour, Window: 24 * time.Hour}
+	ipLockout = lockoutPolicy{Threshold: 20, Base: time.Second, Max: 15 * time.Minute, Window: time.Hour}
+	mfaLockout = lockoutPolicy{Threshold: 5, Base: time.Minute, Max: time.Hour, Window: 24 * time.Hour}
+	// Password reset requests are throttled with the same machinery, counting
+	// every request rather than failures.
+	resetAccountLimit = lockoutPolicy{Threshold: 3, Base: 5 * time.Minute, Max: time.Hour, Window: time.Hour}
+	resetIPLimit = lockoutPolicy{Threshold: 10, Base: time.Minute, Max: time.Hour, Window: time.Hour}
+)
+
+// backoff returns how long logins are locked after the given number of
+// consecutive failures.
+func (p lockoutPolicy) backoff(failures int32) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	delay := p.Base
+	for i := p.Threshold; i < failures; i++ {
+		delay *= 2
+		if delay >= p.Max {
+			return p.Max
+		}
+	}
+	return delay
+}
+
+// retryAfterSeconds rounds the time left until the lock ends up to whole
+// seconds for the Retry-After header.
+func retryAfterSeconds(lockedUntil, now time.Time) int {
+	remaining := lockedUntil.Sub(now)
+	if remaining <= 0 {
+		return 0
+	}
+	return int((remaining + time.Second - 1) / time.Second)
+}
+
+// lockoutSubjects returns the keys failures are tracked under: the email as
+// typed, so unknown addresses are throttled like real ones, and the client IP.
+// IPv6 clients are grouped by /64 since a single host usually owns the prefix.
+func lockoutSubjects(email, ip string) (string, string) {
+	account := strings.ToLower(strings.TrimSpace(email))
+	parsed := net.ParseIP(ip)
+	if parsed != nil && parsed.To4() == nil {
+		ip = parsed.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	return account, ip
+}
+
+// loginLockedOut reports whether logins are locked for the client IP or the
+// account and, if so, writes a 429 or 423 response with Retry-After.
+func loginLockedOut(w http.ResponseWriter, r *http.Request, apiCfg *apiConfig, account, ip string) bool {
+	return lockedOut(w, r, apiCfg, lockoutKindIP, ip, 429, "Too many failed login attempts, try again later") ||
+		lockedOut(w, r, apiCfg, lockoutKindAccount, account, 423, "Account is temporarily locked, try again later")
+}
+
+// secondFactorLockedOut reports whether second factor checks are locked for
+// the user and, if so, writes a 429 response with Retry-After. These failures
+// are counted apart from password failures, so knowing the password does not
+// buy a fresh set of guesses.
+func secondFactorLockedOut(w http.ResponseWriter, r *http.Request, apiCfg *apiConfig, userID uuid.UUID) bool {
+	return lockedOut(w, r, apiCfg, lockoutKindMFA, userID.String(), 429, "Too many failed verification attempts, try again later")
+}
+
+func lockedOut(w http.ResponseWriter, r *http.Request, apiCfg *apiConfig, kind, subject string, status int, message string) bool {
+	failure, err := apiCfg.database.GetLoginFailure(r.Context(), database.GetLoginFailureParams{Kind: kind, Subject: subject})
+	if errors.Is(err, sql.ErrNoRows) {
+		return false
+	}
+	if err != nil {
+		log.Printf("Error fetching login failures: %v", err)
+		return false
+	}
+
+	now := time.Now()
+	if failure.LockedUntil.Valid && failure.LockedUntil.Time.After(now) {
+		w.Header().Set("Retry-After", fmt.Sprint(retryAfterSeconds(failure.LockedUntil.Time, now)))
+		respondWithError(w, status, message)
+		return true
+	}
+	return false
+}
+
+// passwordResetLimited reports whether password reset requests are throttled
+// for the client IP or the email address and, if so, writes a 429 response
+// with Retry-After. Like logins, the email is taken as typed so unknown
+// addresses are throttled the same way.
+func passwordResetLimited(w http.ResponseWriter, r *http.Request, apiCfg *apiConfig, account, ip string) bool {
+	return lockedOut(w, r, apiCfg, lockoutKindResetIP, ip, 429, "Too many password reset requests, try again later") ||
+		lockedOut(w, r, apiCfg, lockoutKindResetAccount, account, 429, "Too many password reset requests, try again later")
+}
+
+func recordPasswordResetRequest(ctx context.Context, apiCfg *apiConfig, account, ip string) {
+	recordFailure(ctx, apiCfg, lockoutKindResetAccount, account, resetAccountLimit)
+	recordFailure(ctx, apiCfg, lockoutKindResetIP, ip, resetIPLimit)
+}
+
+// recordLoginFailure counts a failed login against the account and the IP and
+// locks further attempts once a policy's threshold is reached.
+func recordLoginFailure(ctx context.Context, apiCfg *apiConfig, account, ip string) {
+	recordFailure(ctx, apiCfg, lockoutKindAccount, account, accountLockout)
+	recordFailure(ctx, apiCfg, lockoutKindIP, ip, ipLockout)
+}
+
+// recordSecondFactorFailure counts a wrong TOTP or recovery code against the
+// user, both on its own counter and on the account's login failures, so
+// that guessing codes also locks password logins.
+func recordSecondFactorFailure(ctx context.Context, apiCfg *apiConfig, userID uuid.UUID) {
+	recordFailure(ctx, apiCfg, lockoutKindMFA, userID.String(), mfaLockout)
+
+	user, err := apiCfg.database.GetUserById(ctx, userID)
+	if err != nil {
+		log.Printf("Error fetching user: %v", err)
+		return
+	}
+	account, _ := lockoutSubjects(user.Email, "")
+	recordFailure(ctx, apiCfg, lockoutKindAccount, account, accountLockout)
+}
+
+// clearLoginFailures resets the account's login failures once the user has
+// fully signed in.
+func clearLoginFailures(ctx context.Context, apiCfg *apiConfig, email string) {
+	account, _ := lockoutSubjects(email, "")
+	_, err := apiCfg.database.ClearLoginFailures(ctx, database.ClearLoginFailuresParams{Kind: lockoutKindAccount, Subject: account})
+	if err != nil {
+		log.Printf("Error clearing login failures: %v", err)
+	}
+}
+
+func clearSecondFactorFailures(ctx context.Context, apiCfg *apiConfig, userID uuid.UUID) {
+	_, err := apiCfg.database.ClearLoginFailures(ctx, database.ClearLoginFailuresParams{Kind: lockoutKindMFA, Subject: userID.String()})
+	if err != nil {
+		log.Printf("Error clearing second factor failures: %v", err)
+	}
+}
+
+func recordFailure(ctx context.Context, apiCfg *apiConfig, kind, subject string, policy lockoutPolicy) {
+	failure, err := apiCfg.database.RecordLoginFailure(ctx, database.RecordLoginFailureParams{
+		Kind: kind,
+		Subject: subject,
+		ResetBefore: time.Now().Add(-policy.Window),
+	})
+	if err != nil {
+		log.Printf("Error recording login failure: %v", err)
+		return
+	}
+
+	delay := policy.backoff(failure.Failures)
+	if delay == 0 {
+		return
+	}
+	err = apiCfg.database.SetLoginLockout(ctx, database.SetLoginLockoutParams{
+		Kind: kind,
+		Subject: subject,
+		LockedUntil: sql.NullTime{Time: time.Now().Add(delay), Valid: true},
+	})
+	if err != nil {
+		log.Printf("Error locking logins: %v", err)
+		return
+	}
+	log.Printf("Security event login_lockout for %s %s: %d failures, locked for %s", kind, subject, failure.Failures, delay)
+}
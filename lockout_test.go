@@ -0,0 +1,167 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func TestLockoutBackoff(t *testing.T) {
+	policy := lockoutPolicy{Threshold: 3, Base: 10 * time.Second, Max: time.Minute}
+	tests := []struct {
+		failures int32
+		expected time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, 10 * time.Second},
+		{4, 20 * time.Second},
+		{5, 40 * time.Second},
+		{6, time.Minute},
+		{1000, time.Minute},
+	}
+
+	for _, test := range tests {
+		result := policy.backoff(test.failures)
+		if result != test.expected {
+			t.Errorf("Expected %v for %d failures, but got %v", test.expected, test.failures, result)
+		}
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		lockedUntil time.Time
+		expected    int
+	}{
+		{now.Add(30 * time.Second), 30},
+		{now.Add(1500 * time.Millisecond), 2},
+		{now.Add(time.Millisecond), 1},
+		{now, 0},
+		{now.Add(-time.Minute), 0},
+	}
+
+	for _, test := range tests {
+		result := retryAfterSeconds(test.lockedUntil, now)
+		if result != test.expected {
+			t.Errorf("Expected %d, but got %d", test.expected, result)
+		}
+	}
+}
+
+func TestLockoutSubjects(t *testing.T) {
+	tests := []struct {
+		email           string
+		ip              string
+		expectedAccount string
+		expectedIP      string
+	}{
+		{"User@Example.com ", "192.0.2.1", "user@example.com", "192.0.2.1"},
+		{"user@example.com", "2001:db8:1:2:3:4:5:6", "user@example.com", "2001:db8:1:2::/64"},
+		{"user@example.com", "", "user@example.com", ""},
+	}
+
+	for _, test := range tests {
+		account, ip := lockoutSubjects(test.email, test.ip)
+		if account != test.expectedAccount {
+			t.Errorf("Expected '%s', but got '%s'", test.expectedAccount, account)
+		}
+		if ip != test.expectedIP {
+			t.Errorf("Expected '%s', but got '%s'", test.expectedIP, ip)
+		}
+	}
+}
+
+// fakeLoginFailures keeps the login_failures table of a fakeDB in memory.
+func fakeLoginFailures(db *fakeDB) map[string]*database.LoginFailure {
+	failures := map[string]*database.LoginFailure{}
+	key := func(args []driver.Value) string { return args[0].(string) + " " + args[1].(string) }
+	db.on("GetLoginFailure", func(args []driver.Value) (any, error) {
+		failure, ok := failures[key(args)]
+		if !ok {
+			return nil, nil
+		}
+		return *failure, nil
+	})
+	db.on("RecordLoginFailure", func(args []driver.Value) (any, error) {
+		failure, ok := failures[key(args)]
+		if !ok {
+			failure = &database.LoginFailure{Kind: args[0].(string), Subject: args[1].(string)}
+			failures[key(args)] = failure
+		}
+		failure.Failures++
+		failure.LastFailedAt = time.Now()
+		return *failure, nil
+	})
+	db.on("SetLoginLockout", func(args []driver.Value) (any, error) {
+		failures[key(args)].LockedUntil = sql.NullTime{Time: args[2].(time.Time), Valid: true}
+		return nil, nil
+	})
+	db.on("ClearLoginFailures", func(args []driver.Value) (any, error) {
+		if _, ok := failures[key(args)]; !ok {
+			return int64(0), nil
+		}
+		delete(failures, key(args))
+		return int64(1), nil
+	})
+	return failures
+}
+
+func TestLoginIPLockoutIgnoresSpoofedForwardedFor(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	apiCfg.trustedProxyHops = 1
+	failures := fakeLoginFailures(db)
+	db.returns("GetUserByEmail", nil)
+
+	var code int
+	for i := range ipLockout.Threshold + 1 {
+		r := httptest.NewRequest("POST", "/api/login", strings.NewReader(fmt.Sprintf(`{"email": "user%d@example.com", "password": "wrong"}`, i)))
+		// Each attempt claims a new address, but the proxy appends the same one.
+		r.Header.Set("X-Forwarded-For", fmt.Sprintf("203.0.113.%d, 198.51.100.7", i))
+		w := httptest.NewRecorder()
+		apiLoginHandler(apiCfg)(w, r)
+		code = w.Code
+	}
+
+	if code != 429 {
+		t.Errorf("Expected status 429 once the IP is locked, but got %d", code)
+	}
+	if failure := failures[lockoutKindIP+" 198.51.100.7"]; failure == nil || failure.Failures != ipLockout.Threshold {
+		t.Errorf("Expected %d failures for the proxy-reported IP, but got %+v", ipLockout.Threshold, failure)
+	}
+}
+
+func TestLoginWithMFAKeepsAccountFailures(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	passHash, err := auth.HashPassword("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("Expected no error hashing, but got %v", err)
+	}
+	user := database.User{ID: uuid.New(), Email: "user@example.com", HashedPassword: passHash, Role: auth.RoleUser}
+	failures := fakeLoginFailures(db)
+	failures[lockoutKindAccount+" user@example.com"] = &database.LoginFailure{Kind: lockoutKindAccount, Subject: "user@example.com", Failures: 2, LastFailedAt: time.Now()}
+	db.returns("GetUserByEmail", user)
+	db.returns("GetUserTotp", newTestTOTP(t, user.ID))
+	db.returns("CreateMfaChallenge", nil)
+
+	r := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"email": "user@example.com", "password": "correct horse battery staple"}`))
+	w := httptest.NewRecorder()
+	apiLoginHandler(apiCfg)(w, r)
+
+	if w.Code != 200 || !strings.Contains(w.Body.String(), "mfa_token") {
+		t.Fatalf("Expected an MFA challenge, but got %d %s", w.Code, w.Body.String())
+	}
+	// The password alone does not clear the count; the second factor does.
+	if failures[lockoutKindAccount+" user@example.com"] == nil {
+		t.Errorf("Expected the account failures to be kept until the second factor is provided")
+	}
+}
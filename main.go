@@ -1,23 +1,57 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"sync"
 	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
 	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/entitlements"
+	"github.com/isotronic/http-go-server/internal/mailer"
+	"github.com/isotronic/http-go-server/internal/passwordpolicy"
+	"github.com/isotronic/http-go-server/internal/moderation"
+	"github.com/isotronic/http-go-server/internal/oidc"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
+// shutdownTimeout bounds how long the server waits for open requests when it
+// is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
 type apiConfig struct {
 	fileServerHits atomic.Int32
+	db *sql.DB
 	database *database.Queries
 	platform string
-	tokenSecret string
+	keys *auth.KeySet
 	polkaKey string
+	polkaWebhookSecret string
+	trustedProxyHops int
+	requireVerifiedEmail bool
+	baseURL string
+	oauthConsentURL string
+	mailer mailer.Mailer
+	passwordPolicy passwordpolicy.Policy
+	entitlements entitlements.Tiers
+	oidc *oidc.Client
+	moderation *moderation.Filter
+	baseModerationRules []moderation.Rule
+	// background tracks work started by a request that outlives it, such as
+	// sending mail. main waits for it after the server has shut down.
+	background sync.WaitGroup
 }
 
 func main() {
@@ -29,8 +63,25 @@ func main() {
 	server.Handler = mux
 
 	apiCfg.platform = os.Getenv("PLATFORM")
-	apiCfg.tokenSecret = os.Getenv("TOKEN_SECRET")
 	apiCfg.polkaKey = os.Getenv("POLKA_KEY")
+	apiCfg.polkaWebhookSecret = os.Getenv("POLKA_WEBHOOK_SECRET")
+	if apiCfg.polkaWebhookSecret == "" {
+		log.Printf("POLKA_WEBHOOK_SECRET is not set; Polka webhooks are authenticated with POLKA_KEY only and can be replayed")
+	}
+	trustedProxyHops, err := trustedProxyHopsFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXY_HOPS: %v", err)
+	}
+	apiCfg.trustedProxyHops = trustedProxyHops
+	apiCfg.requireVerifiedEmail = os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true"
+	apiCfg.baseURL = os.Getenv("APP_BASE_URL")
+	if apiCfg.baseURL == "" {
+		apiCfg.baseURL = "http://localhost:8080"
+	}
+	apiCfg.oauthConsentURL = os.Getenv("OAUTH_CONSENT_URL")
+	if apiCfg.oauthConsentURL == "" {
+		apiCfg.oauthConsentURL = apiCfg.baseURL + "/oauth/consent"
+	}
 
 	dbURL := os.Getenv("DB_URL")
 	if dbURL == "" {
@@ -41,30 +92,200 @@ func main() {
 	if err != nil {
 		log.Fatalf("Error connecting to database: %v", err)
 	}
+	apiCfg.db = db
 	apiCfg.database = database.New(db)
 
+	passwordParams, err := passwordParamsFromEnv()
+	if err != nil {
+		log.Fatalf("Error reading password hashing parameters: %v", err)
+	}
+	err = auth.SetPasswordParams(passwordParams)
+	if err != nil {
+		log.Fatalf("Invalid password hashing parameters: %v", err)
+	}
+	if value := os.Getenv("ARGON2_MAX_CONCURRENT"); value != "" {
+		maxHashes, err := strconv.Atoi(value)
+		if err == nil {
+			err = auth.SetMaxConcurrentHashes(maxHashes)
+		}
+		if err != nil {
+			log.Fatalf("Invalid ARGON2_MAX_CONCURRENT %q: %v", value, err)
+		}
+	}
+
+	apiCfg.passwordPolicy, err = passwordPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Error configuring password policy: %v", err)
+	}
+
+	apiCfg.keys, err = keySetFromEnv()
+	if err != nil {
+		log.Fatalf("Error loading JWT keys: %v", err)
+	}
+
+	apiCfg.mailer, err = mailerFromEnv(apiCfg.platform)
+	if err != nil {
+		log.Fatalf("Error configuring mail: %v", err)
+	}
+
+	if issuer := os.Getenv("OIDC_ISSUER"); issuer != "" {
+		redirectURL := os.Getenv("OIDC_REDIRECT_URL")
+		if redirectURL == "" {
+			redirectURL = apiCfg.baseURL + "/oidc/callback"
+		}
+		apiCfg.oidc, err = oidc.Discover(context.Background(), issuer, oidc.Config{
+			ClientID: os.Getenv("OIDC_CLIENT_ID"),
+			ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+			RedirectURL: redirectURL,
+		})
+		if err != nil {
+			log.Fatalf("Error discovering OIDC provider: %v", err)
+		}
+	}
+
+	apiCfg.entitlements = entitlements.Default
+	if entitlementsFile := os.Getenv("ENTITLEMENTS_FILE"); entitlementsFile != "" {
+		apiCfg.entitlements, err = entitlements.Load(entitlementsFile)
+		if err != nil {
+			log.Fatalf("Error loading entitlements: %v", err)
+		}
+	}
+
+	apiCfg.baseModerationRules = moderation.DefaultRules
+	if wordsFile := os.Getenv("MODERATION_WORDS_FILE"); wordsFile != "" {
+		apiCfg.baseModerationRules, err = moderation.LoadRulesFile(wordsFile)
+		if err != nil {
+			log.Fatalf("Error loading moderation word list: %v", err)
+		}
+	}
+	apiCfg.moderation, err = moderation.NewFilter(apiCfg.baseModerationRules)
+	if err != nil {
+		log.Fatalf("Error creating moderation filter: %v", err)
+	}
+	err = reloadModerationRules(context.Background(), &apiCfg)
+	if err != nil {
+		log.Fatalf("Error loading moderation rules: %v", err)
+	}
+
 	mux.Handle("/app/", apiCfg.middleWareMetricsInt(http.StripPrefix("/app", http.FileServer(http.Dir(".")))))
 
 	mux.HandleFunc("GET /api/healthz", apiHealthzHandler)
+	mux.HandleFunc("GET /.well-known/jwks.json", jwksHandler(&apiCfg))
 
 	mux.HandleFunc("GET /api/chirps", apiGetAllChirpsHandler(&apiCfg))
 	mux.HandleFunc("GET /api/chirps/{chirpID}", apiGetChirpByIdHandler(&apiCfg))
-	mux.HandleFunc("POST /api/chirps", apiPostChirpsHandler(&apiCfg))
-	mux.HandleFunc("DELETE /api/chirps/{chirpID}", apiDeleteChirpsHandler(&apiCfg))
+	mux.Handle("POST /api/chirps", apiCfg.middleWareAuth(apiPostChirpsHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("PUT /api/chirps/{chirpID}", apiCfg.middleWareAuth(apiUpdateChirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("DELETE /api/chirps/{chirpID}", apiCfg.middleWareAuth(apiDeleteChirpsHandler(&apiCfg), auth.ScopeWrite))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/revisions", apiGetChirpRevisionsHandler(&apiCfg))
+	mux.HandleFunc("GET /api/chirps/{chirpID}/thread", apiGetChirpThreadHandler(&apiCfg))
+	mux.Handle("POST /api/chirps/{chirpID}/like", apiCfg.middleWareAuth(apiLikeChirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("DELETE /api/chirps/{chirpID}/like", apiCfg.middleWareAuth(apiUnlikeChirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("POST /api/chirps/{chirpID}/rechirp", apiCfg.middleWareAuth(apiRechirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("DELETE /api/chirps/{chirpID}/rechirp", apiCfg.middleWareAuth(apiUndoRechirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("POST /api/chirps/{chirpID}/report", apiCfg.middleWareAuth(apiReportChirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("POST /api/scheduled_chirps", apiCfg.middleWareAuth(apiCreateScheduledChirpHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("GET /api/scheduled_chirps", apiCfg.middleWareAuth(apiGetScheduledChirpsHandler(&apiCfg), auth.ScopeRead))
+	mux.Handle("DELETE /api/scheduled_chirps/{scheduledID}", apiCfg.middleWareAuth(apiDeleteScheduledChirpHandler(&apiCfg), auth.ScopeWrite))
+
+	mux.HandleFunc("GET /api/search/chirps", apiSearchChirpsHandler(&apiCfg))
+	mux.HandleFunc("GET /api/hashtags/{tag}/chirps", apiGetHashtagChirpsHandler(&apiCfg))
+	mux.HandleFunc("GET /api/trending", apiGetTrendingHandler(&apiCfg))
 
 	mux.HandleFunc("POST /api/users", apiCreateUserHandler(&apiCfg))
-	mux.HandleFunc("PUT /api/users", apiUpdateUserHandler(&apiCfg))
+	mux.Handle("PUT /api/users", apiCfg.middleWareAuth(apiUpdateUserHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("GET /api/users/me/subscription", apiCfg.middleWareAuth(apiGetSubscriptionHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("GET /api/users/me/entitlements", apiCfg.middleWareAuth(apiGetEntitlementsHandler(&apiCfg), auth.ScopeRead))
+	mux.Handle("POST /api/users/{userID}/follow", apiCfg.middleWareAuth(apiFollowUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("DELETE /api/users/{userID}/follow", apiCfg.middleWareAuth(apiUnfollowUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.HandleFunc("GET /api/users/{userID}/followers", apiGetFollowersHandler(&apiCfg))
+	mux.HandleFunc("GET /api/users/{userID}/following", apiGetFollowingHandler(&apiCfg))
+	mux.Handle("POST /api/users/{userID}/report", apiCfg.middleWareAuth(apiReportUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("POST /api/users/{userID}/block", apiCfg.middleWareAuth(apiBlockUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("DELETE /api/users/{userID}/block", apiCfg.middleWareAuth(apiUnblockUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("POST /api/users/{userID}/mute", apiCfg.middleWareAuth(apiMuteUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("DELETE /api/users/{userID}/mute", apiCfg.middleWareAuth(apiUnmuteUserHandler(&apiCfg), auth.ScopeWrite))
+	mux.Handle("GET /api/blocks", apiCfg.middleWareAuth(apiGetBlocksHandler(&apiCfg), auth.ScopeRead))
+	mux.Handle("GET /api/mutes", apiCfg.middleWareAuth(apiGetMutesHandler(&apiCfg), auth.ScopeRead))
+
+	mux.Handle("GET /api/timeline", apiCfg.middleWareAuth(apiGetTimelineHandler(&apiCfg), auth.ScopeRead))
 
 	mux.HandleFunc("POST /api/login", apiLoginHandler(&apiCfg))
+	mux.HandleFunc("POST /api/login/mfa", apiLoginMFAHandler(&apiCfg))
+	mux.HandleFunc("GET /api/login/oidc", apiStartOIDCLoginHandler(&apiCfg))
+	mux.HandleFunc("POST /api/login/oidc", apiOIDCLoginHandler(&apiCfg))
+	mux.HandleFunc("POST /api/password_reset", apiRequestPasswordResetHandler(&apiCfg))
+	mux.HandleFunc("POST /api/password_reset/confirm", apiConfirmPasswordResetHandler(&apiCfg))
+	mux.Handle("POST /api/email_verification", apiCfg.middleWareAuth(apiRequestEmailVerificationHandler(&apiCfg), auth.ScopeAccount))
+	mux.HandleFunc("POST /api/email_verification/confirm", apiConfirmEmailVerificationHandler(&apiCfg))
 	mux.HandleFunc("POST /api/refresh", apiRefreshHandler(&apiCfg))
 	mux.HandleFunc("POST /api/revoke", apiRevokeHandler(&apiCfg))
+	mux.Handle("GET /api/sessions", apiCfg.middleWareAuth(apiGetSessionsHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("DELETE /api/sessions/{sessionID}", apiCfg.middleWareAuth(apiRevokeSessionHandler(&apiCfg), auth.ScopeAccount))
+	mux.HandleFunc("POST /api/sessions/revoke_others", apiRevokeOtherSessionsHandler(&apiCfg))
+	mux.Handle("POST /api/personal_access_tokens", apiCfg.middleWareAuth(apiCreateAccessTokenHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("GET /api/personal_access_tokens", apiCfg.middleWareAuth(apiGetAccessTokensHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("DELETE /api/personal_access_tokens/{tokenID}", apiCfg.middleWareAuth(apiRevokeAccessTokenHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("POST /api/oauth/clients", apiCfg.middleWareAuth(apiCreateOAuthClientHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("GET /api/oauth/clients", apiCfg.middleWareAuth(apiGetOAuthClientsHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("DELETE /api/oauth/clients/{clientID}", apiCfg.middleWareAuth(apiDeleteOAuthClientHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("GET /api/oauth/authorize", apiCfg.middleWareAuth(apiOAuthConsentHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("POST /api/oauth/authorize", apiCfg.middleWareAuth(apiOAuthAuthorizeHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("GET /api/oauth/grants", apiCfg.middleWareAuth(apiGetOAuthGrantsHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("DELETE /api/oauth/grants/{grantID}", apiCfg.middleWareAuth(apiRevokeOAuthGrantHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("POST /api/mfa/totp", apiCfg.middleWareAuth(apiEnrollTOTPHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("POST /api/mfa/totp/confirm", apiCfg.middleWareAuth(apiConfirmTOTPHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("DELETE /api/mfa/totp", apiCfg.middleWareAuth(apiDisableTOTPHandler(&apiCfg), auth.ScopeAccount))
+	mux.Handle("POST /api/mfa/recovery_codes", apiCfg.middleWareAuth(apiRegenerateRecoveryCodesHandler(&apiCfg), auth.ScopeAccount))
+
+	mux.HandleFunc("GET /oauth/authorize", oauthAuthorizeHandler(&apiCfg))
+	mux.HandleFunc("POST /oauth/token", oauthTokenHandler(&apiCfg))
+	mux.HandleFunc("POST /oauth/revoke", oauthRevokeHandler(&apiCfg))
+	mux.HandleFunc("POST /oauth/introspect", oauthIntrospectHandler(&apiCfg))
 
 	mux.HandleFunc("POST /api/polka/webhooks", apiPolkaWebhooksHandler(&apiCfg))
 
-	mux.HandleFunc("GET /admin/metrics", adminMetricsHandler(&apiCfg))
-	mux.Handle("POST /admin/reset", apiCfg.middleWareMetricsReset(http.HandlerFunc(adminResetHandler(&apiCfg))))
+	mux.Handle("GET /admin/metrics", apiCfg.middleWareAuth(adminMetricsHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/rules", apiCfg.middleWareAuth(adminGetModerationRulesHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("POST /admin/moderation/rules", apiCfg.middleWareAuth(adminCreateModerationRuleHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("DELETE /admin/moderation/rules/{ruleID}", apiCfg.middleWareAuth(adminDeleteModerationRuleHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/flags", apiCfg.middleWareAuth(adminGetModerationFlagsHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("POST /admin/moderation/flags/{flagID}/resolve", apiCfg.middleWareAuth(adminResolveModerationFlagHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("GET /admin/moderation/actions", apiCfg.middleWareAuth(adminGetModerationActionsHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("GET /admin/reports", apiCfg.middleWareAuth(adminGetReportsHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("POST /admin/reports/{reportID}/resolve", apiCfg.middleWareAuth(adminResolveReportHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("PUT /admin/users/{userID}/role", apiCfg.middleWareAuth(adminSetUserRoleHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("POST /admin/users/{userID}/unlock", apiCfg.middleWareAuth(adminUnlockUserHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("POST /admin/users/{userID}/unsuspend", apiCfg.middleWareAuth(adminUnsuspendUserHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("GET /admin/webhooks/events", apiCfg.middleWareAuth(adminGetWebhookEventsHandler(&apiCfg), auth.ScopeAdmin))
+	mux.Handle("POST /admin/reset", apiCfg.middleWareAuth(apiCfg.middleWareMetricsReset(adminResetHandler(&apiCfg)).ServeHTTP, auth.ScopeAdmin))
 
-	server.ListenAndServe()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go runEvery(ctx, subscriptionExpiryInterval, func(ctx context.Context) { expireSubscriptions(ctx, &apiCfg) })
+	go runEvery(ctx, scheduledChirpInterval, func(ctx context.Context) { publishScheduledChirps(ctx, &apiCfg) })
+	go runEvery(ctx, mfaChallengeCleanupInterval, func(ctx context.Context) { deleteExpiredMFAChallenges(ctx, &apiCfg) })
+
+	serverErr := make(chan error, 1)
+	go func() { serverErr <- server.ListenAndServe() }()
+
+	select {
+	case err := <-serverErr:
+		log.Fatalf("Error running server: %v", err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Printf("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	err = server.Shutdown(shutdownCtx)
+	if err != nil {
+		log.Printf("Error shutting down server: %v", err)
+	}
+	// Mail queued by the last requests is sent before exiting.
+	apiCfg.background.Wait()
 }
 
 func (cfg *apiConfig) middleWareMetricsInt(next http.Handler) http.Handler {
@@ -81,4 +302,203 @@ func (cfg *apiConfig) middleWareMetricsReset(next http.HandlerFunc) http.Handler
 		}
 		next.ServeHTTP(w, r)
 	})
-}
\ No newline at end of file
+}
+
+// mailerFromEnv sends mail over SMTP when SMTP_HOST is set. Without it mail is
+// written to MAIL_LOG_FILE or standard output, which is only allowed on the
+// dev platform: the mails hold password reset and verification links, which
+// must not end up in production logs.
+func mailerFromEnv(platform string) (mailer.Mailer, error) {
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "chirpy@localhost"
+	}
+
+	if smtpHost := os.Getenv("SMTP_HOST"); smtpHost != "" {
+		smtpPort := os.Getenv("SMTP_PORT")
+		if smtpPort == "" {
+			smtpPort = "587"
+		}
+		return mailer.NewSMTPMailer(smtpHost, smtpPort, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), mailFrom), nil
+	}
+	if platform != "dev" {
+		return nil, fmt.Errorf("SMTP_HOST must be set unless PLATFORM is dev")
+	}
+	if mailLogFile := os.Getenv("MAIL_LOG_FILE"); mailLogFile != "" {
+		file, err := os.OpenFile(mailLogFile, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("opening mail log file: %v", err)
+		}
+		return mailer.NewLogMailer(file, mailFrom), nil
+	}
+	return mailer.NewLogMailer(os.Stdout, mailFrom), nil
+}
+
+// keySetFromEnv loads the keys access tokens are signed with: the PEM files in
+// JWT_KEYS_DIR, or TOKEN_SECRET when that is not set. Next to key files,
+// tokens signed with TOKEN_SECRET are only accepted when
+// JWT_ACCEPT_HMAC_TOKENS is "true", since anyone holding the secret could keep
+// minting them.
+func keySetFromEnv() (*auth.KeySet, error) {
+	tokenSecret := os.Getenv("TOKEN_SECRET")
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if keysDir == "" {
+		return auth.NewKeySet(nil, "", tokenSecret)
+	}
+
+	hmacSecret := ""
+	if tokenSecret != "" {
+		if os.Getenv("JWT_ACCEPT_HMAC_TOKENS") == "true" {
+			log.Printf("Warning: HS256 access tokens signed with TOKEN_SECRET are still accepted; unset JWT_ACCEPT_HMAC_TOKENS once they have expired")
+			hmacSecret = tokenSecret
+		} else {
+			log.Printf("Warning: TOKEN_SECRET is ignored for access tokens because JWT_KEYS_DIR is set")
+		}
+	}
+	return auth.LoadKeySet(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"), hmacSecret)
+}
+
+// trustedProxyHopsFromEnv reads how many reverse proxies in front of the
+// server append to X-Forwarded-For. TRUST_PROXY_HEADERS="true" is the older
+// way of saying there is one.
+func trustedProxyHopsFromEnv() (int, error) {
+	value := os.Getenv("TRUSTED_PROXY_HOPS")
+	if value == "" {
+		if os.Getenv("TRUST_PROXY_HEADERS") == "true" {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	hops, err := strconv.Atoi(value)
+	if err != nil || hops < 0 {
+		return 0, fmt.Errorf("%q is not a number of hops", value)
+	}
+	return hops, nil
+}
+
+// passwordParamsFromEnv overrides the default argon2id parameters with
+// ARGON2_MEMORY_KIB, ARGON2_ITERATIONS and ARGON2_PARALLELISM when set.
+func passwordParamsFromEnv() (auth.PasswordParams, error) {
+	params := auth.DefaultPasswordParams
+	settings := []struct {
+		name string
+		bits int
+		set func(uint64)
+	}{
+		{"ARGON2_MEMORY_KIB", 32, func(v uint64) { params.Memory = uint32(v) }},
+		{"ARGON2_ITERATIONS", 32, func(v uint64) { params.Iterations = uint32(v) }},
+		{"ARGON2_PARALLELISM", 8, func(v uint64) { params.Parallelism = uint8(v) }},
+	}
+	for _, setting := range settings {
+		value := os.Getenv(setting.name)
+		if value == "" {
+			continue
+		}
+		parsed, err := strconv.ParseUint(value, 10, setting.bits)
+		if err != nil {
+			return params, fmt.Errorf("%s: %v", setting.name, err)
+		}
+		setting.set(parsed)
+	}
+	return params, nil
+}
+
+// passwordPolicyFromEnv overrides the default password policy with
+// PASSWORD_MIN_LENGTH and PASSWORD_MIN_ENTROPY and loads the breached password
+// list from BREACHED_PASSWORDS_PATH when set.
+func passwordPolicyFromEnv() (passwordpolicy.Policy, error) {
+	policy := passwordpolicy.DefaultPolicy
+	if value := os.Getenv("PASSWORD_MIN_LENGTH"); value != "" {
+		minLength, err := strconv.Atoi(value)
+		if err != nil || minLength < 1 {
+			return policy, fmt.Errorf("PASSWORD_MIN_LENGTH: invalid length %q", value)
+		}
+		policy.MinLength = minLength
+	}
+	if value := os.Getenv("PASSWORD_MIN_ENTROPY"); value != "" {
+		minEntropy, err := strconv.ParseFloat(value, 64)
+		if err != nil || minEntropy < 0 {
+			return policy, fmt.Errorf("PASSWORD_MIN_ENTROPY: invalid entropy %q", value)
+		}
+		policy.MinEntropy = minEntropy
+	}
+	if path := os.Getenv("BREACHED_PASSWORDS_PATH"); path != "" {
+		breached, err := passwordpolicy.OpenBreachedList(path)
+		if err != nil {
+			return policy, fmt.Errorf("BREACHED_PASSWORDS_PATH: %v", err)
+		}
+		policy.Breached = breached
+	}
+	return policy, nil
+}
+
+type principalContextKey struct{}
+
+// middleWareAuth requires an access token or personal access token carrying
+// every scope in scopes and stores its principal in the request context. The
+// role is read from the database, so a demoted admin loses the admin scope
+// before the token expires. Access tokens from before scopes existed get the
+// scopes of a login until they expire.
+func (cfg *apiConfig) middleWareAuth(next http.HandlerFunc, scopes ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := auth.GetBearerToken(r.Header)
+		if err != nil {
+			respondWithError(w, 401, err.Error())
+			return
+		}
+
+		principal, user, err := resolvePrincipal(r.Context(), cfg, token)
+		if err != nil {
+			respondWithError(w, 401, "Invalid token")
+			return
+		}
+		if user.SuspendedAt.Valid {
+			respondWithError(w, 403, "Your account is suspended")
+			return
+		}
+
+		if missing := principal.MissingScope(scopes...); missing != "" {
+			respondWithError(w, 403, fmt.Sprintf("Token is missing the %s scope", missing))
+			return
+		}
+		if slices.Contains(scopes, auth.ScopeAdmin) && principal.Role != auth.RoleAdmin {
+			respondWithError(w, 403, "Forbidden")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), principalContextKey{}, principal)))
+	})
+}
+
+// resolvePrincipal validates an access token or personal access token and
+// loads the user behind it. The principal carries the user's current role.
+func resolvePrincipal(ctx context.Context, cfg *apiConfig, token string) (auth.Principal, database.User, error) {
+	var principal auth.Principal
+	var err error
+	legacyToken := false
+	if auth.IsPersonalAccessToken(token) {
+		principal, err = personalAccessTokenPrincipal(ctx, cfg, token)
+	} else {
+		principal, err = cfg.keys.ValidateJWT(token)
+		// Logins always get scopes now, and OAuth tokens name their client,
+		// so a token with neither was issued before scopes existed.
+		legacyToken = len(principal.Scopes) == 0 && principal.ClientID == uuid.Nil
+	}
+	if err == nil && principal.GrantID != uuid.Nil {
+		err = checkOAuthGrant(ctx, cfg, principal)
+	}
+	if err != nil {
+		return auth.Principal{}, database.User{}, err
+	}
+
+	user, err := cfg.database.GetUserById(ctx, principal.UserID)
+	if err != nil {
+		return auth.Principal{}, database.User{}, err
+	}
+
+	principal.Role = user.Role
+	if legacyToken {
+		principal.Scopes = auth.ScopesForRole(user.Role)
+	}
+	return principal, user, nil
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/mailer"
+)
+
+func TestMiddleWareAuth(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	admin := database.User{ID: uuid.New(), Role: auth.RoleAdmin}
+	demoted := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	suspended := database.User{ID: uuid.New(), Role: auth.RoleUser, SuspendedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	users := map[string]database.User{}
+	for _, u := range []database.User{user, admin, demoted, suspended} {
+		users[u.ID.String()] = u
+	}
+	db.on("GetUserById", func(args []driver.Value) (any, error) {
+		u, ok := users[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return u, nil
+	})
+
+	token := func(principal auth.Principal) string {
+		jwt, err := apiCfg.keys.MakeJWT(principal, time.Hour)
+		if err != nil {
+			t.Fatalf("Expected no error making a token, but got %v", err)
+		}
+		return jwt
+	}
+	pat := auth.PersonalAccessTokenPrefix + "readonly"
+	db.on("GetPersonalAccessTokenByHash", func(args []driver.Value) (any, error) {
+		if args[0] != auth.HashToken(pat) {
+			return nil, nil
+		}
+		return database.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, Scopes: []string{auth.ScopeRead}}, nil
+	})
+	db.returns("TouchPersonalAccessToken", nil)
+
+	tests := []struct {
+		name     string
+		token    string
+		scopes   []string
+		expected int
+	}{
+		{"no token", "", []string{auth.ScopeWrite}, 401},
+		{"invalid token", "not-a-token", []string{auth.ScopeWrite}, 401},
+		{"unknown user", token(userPrincipal(database.User{ID: uuid.New(), Role: auth.RoleUser})), nil, 401},
+		{"login token", token(userPrincipal(user)), []string{auth.ScopeWrite}, 200},
+		{"missing scope", token(auth.Principal{UserID: user.ID, Role: auth.RoleUser, Scopes: []string{auth.ScopeRead}}), []string{auth.ScopeWrite}, 403},
+		{"user on admin route", token(userPrincipal(user)), []string{auth.ScopeAdmin}, 403},
+		{"admin on admin route", token(userPrincipal(admin)), []string{auth.ScopeAdmin}, 200},
+		// The token still carries the admin scope, but the role was taken away.
+		{"demoted admin", token(auth.Principal{UserID: demoted.ID, Role: auth.RoleAdmin, Scopes: auth.ScopesForRole(auth.RoleAdmin)}), []string{auth.ScopeAdmin}, 403},
+		{"suspended user", token(userPrincipal(suspended)), []string{auth.ScopeRead}, 403},
+		{"legacy token", token(auth.Principal{UserID: user.ID}), []string{auth.ScopeWrite, auth.ScopeAccount}, 200},
+		{"legacy token on admin route", token(auth.Principal{UserID: user.ID}), []string{auth.ScopeAdmin}, 403},
+		{"legacy admin token", token(auth.Principal{UserID: admin.ID}), []string{auth.ScopeAdmin}, 200},
+		{"personal access token", pat, []string{auth.ScopeRead}, 200},
+		{"personal access token missing scope", pat, []string{auth.ScopeWrite}, 403},
+	}
+
+	for _, test := range tests {
+		var principal auth.Principal
+		handler := apiCfg.middleWareAuth(func(w http.ResponseWriter, r *http.Request) {
+			principal = principalFromRequest(r)
+		}, test.scopes...)
+
+		r := httptest.NewRequest("GET", "/api/test", nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d", test.name, test.expected, w.Code)
+			continue
+		}
+		if w.Code == 200 {
+			for _, scope := range test.scopes {
+				if !slices.Contains(principal.Scopes, scope) {
+					t.Errorf("%s: expected the principal to hold %s, but got %v", test.name, scope, principal.Scopes)
+				}
+			}
+		}
+	}
+}
+
+func TestMailerFromEnv(t *testing.T) {
+	tests := []struct {
+		smtpHost string
+		platform string
+		expected string
+	}{
+		{"smtp.example.com", "", "smtp"},
+		{"smtp.example.com", "dev", "smtp"},
+		{"", "dev", "log"},
+		{"", "", ""},
+		{"", "prod", ""},
+	}
+
+	for _, test := range tests {
+		t.Setenv("SMTP_HOST", test.smtpHost)
+		t.Setenv("MAIL_LOG_FILE", "")
+		result, err := mailerFromEnv(test.platform)
+
+		var kind string
+		switch result.(type) {
+		case *mailer.SMTPMailer:
+			kind = "smtp"
+		case *mailer.LogMailer:
+			kind = "log"
+		}
+		if kind != test.expected {
+			t.Errorf("Expected a %q mailer for SMTP_HOST=%q and PLATFORM=%q, but got %q", test.expected, test.smtpHost, test.platform, kind)
+		}
+		if (err == nil) != (test.expected != "") {
+			t.Errorf("Expected an error only without a mailer, but got %v", err)
+		}
+	}
+}
+
+func TestTrustedProxyHopsFromEnv(t *testing.T) {
+	tests := []struct {
+		hops     string
+		trust    string
+		expected int
+		valid    bool
+	}{
+		{"", "", 0, true},
+		{"", "true", 1, true},
+		{"2", "", 2, true},
+		{"0", "true", 0, true},
+		{"-1", "", 0, false},
+		{"two", "", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Setenv("TRUSTED_PROXY_HOPS", test.hops)
+		t.Setenv("TRUST_PROXY_HEADERS", test.trust)
+		result, err := trustedProxyHopsFromEnv()
+		if (err == nil) != test.valid {
+			t.Errorf("Expected valid %v for %q, but got error %v", test.valid, test.hops, err)
+			continue
+		}
+		if result != test.expected {
+			t.Errorf("Expected %d for %q, but got %d", test.expected, test.hops, result)
+		}
+	}
+}
+
+func TestKeySetFromEnv(t *testing.T) {
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("Expected no error generating a key, but got %v", err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		t.Fatalf("Expected no error encoding the key, but got %v", err)
+	}
+	keysDir := t.TempDir()
+	err = os.WriteFile(filepath.Join(keysDir, "ed-1.pem"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+	if err != nil {
+		t.Fatalf("Expected no error writing the key, but got %v", err)
+	}
+
+	legacyKeys, err := auth.NewKeySet(nil, "", "secret")
+	if err != nil {
+		t.Fatalf("Expected no error creating keys, but got %v", err)
+	}
+	legacyToken, err := legacyKeys.MakeJWT(auth.Principal{UserID: uuid.New()}, time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error making a token, but got %v", err)
+	}
+
+	tests := []struct {
+		keysDir    string
+		acceptHMAC string
+		accepted   bool
+	}{
+		{"", "", true},
+		{keysDir, "", false},
+		{keysDir, "true", true},
+	}
+
+	for _, test := range tests {
+		t.Setenv("TOKEN_SECRET", "secret")
+		t.Setenv("JWT_KEYS_DIR", test.keysDir)
+		t.Setenv("JWT_SIGNING_KEY_ID", "")
+		t.Setenv("JWT_ACCEPT_HMAC_TOKENS", test.acceptHMAC)
+
+		keys, err := keySetFromEnv()
+		if err != nil {
+			t.Fatalf("Expected no error loading keys, but got %v", err)
+		}
+		_, err = keys.ValidateJWT(legacyToken)
+		if (err == nil) != test.accepted {
+			t.Errorf("Expected HS256 tokens accepted to be %v with JWT_KEYS_DIR=%q and JWT_ACCEPT_HMAC_TOKENS=%q, but got %v", test.accepted, test.keysDir, test.acceptHMAC, err)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,10 +15,13 @@ type LoginResponse struct {
 	AccessToken 	string 		`json:"token"`
 	RefreshToken 	string 		`json:"refresh_token"`
 	IsChirpyRed 	bool 			`json:"is_chirpy_red"`
+	Role 					string 		`json:"role"`
+	EmailVerified bool 			`json:"email_verified"`
 }
 
 type RefreshResponse struct {
 	AccessToken 	string `json:"token"`
+	RefreshToken 	string `json:"refresh_token"`
 }
 
 type UserResponse struct {
@@ -26,6 +30,8 @@ type UserResponse struct {
 	CreatedAt 	time.Time `json:"created_at"`
 	UpdatedAt 	time.Time `json:"updated_at"`
 	IsChirpyRed bool 			`json:"is_chirpy_red"`
+	Role 				string 		`json:"role"`
+	EmailVerified bool 		`json:"email_verified"`
 }
 
 type ChirpResponse struct {
@@ -34,4 +40,267 @@ type ChirpResponse struct {
 	Body      string 		`json:"body"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	EditedAt  *time.Time `json:"edited_at"`
+	ParentID  *uuid.UUID `json:"parent_id"`
+	Deleted   bool 			`json:"deleted,omitempty"`
+	Hidden    bool 			`json:"hidden,omitempty"`
+	LikeCount    int32 `json:"like_count"`
+	RechirpCount int32 `json:"rechirp_count"`
+	Liked        bool  `json:"liked"`
+	Rechirped    bool  `json:"rechirped"`
+	Entities     ChirpEntities `json:"entities"`
+}
+
+type ChirpEntities struct {
+	Hashtags []HashtagEntity `json:"hashtags"`
+	Mentions []MentionEntity `json:"mentions"`
+}
+
+type HashtagEntity struct {
+	Tag   string `json:"tag"`
+	Start int    `json:"start"`
+	End   int    `json:"end"`
+}
+
+type MentionEntity struct {
+	UserID uuid.UUID `json:"user_id"`
+	Email  string    `json:"email"`
+	Start  int       `json:"start"`
+	End    int       `json:"end"`
+}
+
+type TrendingHashtagResponse struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+type ChirpThreadNode struct {
+	ChirpResponse
+	Replies []ChirpThreadNode `json:"replies"`
+}
+
+type ChirpThreadResponse struct {
+	Ancestors []ChirpResponse `json:"ancestors"`
+	Chirp     ChirpThreadNode `json:"chirp"`
+}
+
+type ChirpRevisionResponse struct {
+	ID        uuid.UUID `json:"id"`
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	Body      string 		`json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChirpPageResponse struct {
+	Chirps     	[]ChirpResponse `json:"chirps"`
+	NextCursor 	string          `json:"next_cursor,omitempty"`
+	PrevCursor 	string          `json:"prev_cursor,omitempty"`
+}
+
+type FollowResponse struct {
+	UserID     	uuid.UUID `json:"user_id"`
+	FollowedAt 	time.Time `json:"followed_at"`
+}
+
+type FollowPageResponse struct {
+	Users      	[]FollowResponse `json:"users"`
+	NextCursor 	string           `json:"next_cursor,omitempty"`
+	PrevCursor 	string           `json:"prev_cursor,omitempty"`
+}
+
+type ModerationRuleResponse struct {
+	ID        	*uuid.UUID `json:"id,omitempty"`
+	Pattern   	string     `json:"pattern"`
+	Match     	string     `json:"match"`
+	Action    	string     `json:"action"`
+	Source    	string     `json:"source"`
+	CreatedAt 	*time.Time `json:"created_at,omitempty"`
+}
+
+type ModerationFlagResponse struct {
+	ID        	uuid.UUID `json:"id"`
+	ChirpID   	uuid.UUID `json:"chirp_id"`
+	UserID    	uuid.UUID `json:"user_id"`
+	Body      	string    `json:"body"`
+	Reason    	string    `json:"reason"`
+	CreatedAt 	time.Time `json:"created_at"`
+}
+
+type ReportResponse struct {
+	ID             	uuid.UUID  `json:"id"`
+	ReporterID     	uuid.UUID  `json:"reporter_id"`
+	ReportedUserID 	uuid.UUID  `json:"reported_user_id"`
+	ChirpID        	*uuid.UUID `json:"chirp_id,omitempty"`
+	ChirpBody      	string     `json:"chirp_body,omitempty"`
+	Reason         	string     `json:"reason"`
+	CreatedAt      	time.Time  `json:"created_at"`
+	ResolvedAt     	*time.Time `json:"resolved_at,omitempty"`
+	Resolution     	string     `json:"resolution,omitempty"`
+}
+
+type ModerationActionResponse struct {
+	ID        	uuid.UUID  `json:"id"`
+	Action    	string     `json:"action"`
+	ReportID  	*uuid.UUID `json:"report_id,omitempty"`
+	ChirpID   	*uuid.UUID `json:"chirp_id,omitempty"`
+	UserID    	*uuid.UUID `json:"user_id,omitempty"`
+	Note      	string     `json:"note"`
+	ActorID   	*uuid.UUID `json:"actor_id,omitempty"`
+	CreatedAt 	time.Time  `json:"created_at"`
+}
+
+type ModerationActionPageResponse struct {
+	Actions    	[]ModerationActionResponse `json:"actions"`
+	NextCursor 	string                     `json:"next_cursor,omitempty"`
+	PrevCursor 	string                     `json:"prev_cursor,omitempty"`
+}
+
+type UserRelationResponse struct {
+	UserID    	uuid.UUID `json:"user_id"`
+	CreatedAt 	time.Time `json:"created_at"`
+}
+
+type UserRelationPageResponse struct {
+	Users      	[]UserRelationResponse `json:"users"`
+	NextCursor 	string                 `json:"next_cursor,omitempty"`
+	PrevCursor 	string                 `json:"prev_cursor,omitempty"`
+}
+
+type SessionResponse struct {
+	ID         	uuid.UUID `json:"id"`
+	UserAgent  	string    `json:"user_agent"`
+	IPAddress  	string    `json:"ip_address"`
+	CreatedAt  	time.Time `json:"created_at"`
+	LastUsedAt 	time.Time `json:"last_used_at"`
+	ExpiresAt  	time.Time `json:"expires_at"`
+}
+
+type RevokeSessionsResponse struct {
+	Revoked 	int64 `json:"revoked"`
+}
+
+type MFAChallengeResponse struct {
+	MFARequired 	bool      `json:"mfa_required"`
+	MFAToken    	string    `json:"mfa_token"`
+	ExpiresAt   	time.Time `json:"expires_at"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret     	string `json:"secret"`
+	OTPAuthURI 	string `json:"otpauth_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes 	[]string `json:"recovery_codes"`
+}
+
+type FieldError struct {
+	Code    	string `json:"code"`
+	Message 	string `json:"message"`
+}
+
+type PersonalAccessTokenResponse struct {
+	ID         	uuid.UUID  `json:"id"`
+	Name       	string     `json:"name"`
+	Scopes     	[]string   `json:"scopes"`
+	CreatedAt  	time.Time  `json:"created_at"`
+	ExpiresAt  	*time.Time `json:"expires_at"`
+	LastUsedAt 	*time.Time `json:"last_used_at"`
+	Token      	string     `json:"token,omitempty"`
+}
+
+type OAuthClientResponse struct {
+	ClientID     	uuid.UUID `json:"client_id"`
+	Name         	string    `json:"name"`
+	RedirectURIs 	[]string  `json:"redirect_uris"`
+	Scopes       	[]string  `json:"scopes"`
+	Confidential 	bool      `json:"confidential"`
+	CreatedAt    	time.Time `json:"created_at"`
+	ClientSecret 	string    `json:"client_secret,omitempty"`
+}
+
+type OAuthConsentResponse struct {
+	ClientID    	uuid.UUID `json:"client_id"`
+	ClientName  	string    `json:"client_name"`
+	RedirectURI 	string    `json:"redirect_uri"`
+	Scopes      	[]string  `json:"scopes"`
+}
+
+type OAuthRedirectResponse struct {
+	RedirectTo 	string `json:"redirect_to"`
+}
+
+type OAuthTokenResponse struct {
+	AccessToken  	string `json:"access_token"`
+	TokenType    	string `json:"token_type"`
+	ExpiresIn    	int    `json:"expires_in"`
+	RefreshToken 	string `json:"refresh_token"`
+	Scope        	string `json:"scope"`
+}
+
+type OAuthIntrospectionResponse struct {
+	Active    	bool   `json:"active"`
+	Scope     	string `json:"scope,omitempty"`
+	ClientID  	string `json:"client_id,omitempty"`
+	Subject   	string `json:"sub,omitempty"`
+	TokenType 	string `json:"token_type,omitempty"`
+	ExpiresAt 	int64  `json:"exp,omitempty"`
+	IssuedAt  	int64  `json:"iat,omitempty"`
+}
+
+type OAuthGrantResponse struct {
+	ID         	uuid.UUID `json:"id"`
+	ClientID   	uuid.UUID `json:"client_id"`
+	ClientName 	string    `json:"client_name"`
+	Scopes     	[]string  `json:"scopes"`
+	CreatedAt  	time.Time `json:"created_at"`
+}
+
+type OIDCLoginResponse struct {
+	AuthorizationURL 	string `json:"authorization_url"`
+	State            	string `json:"state"`
+}
+
+type WebhookEventResponse struct {
+	ID          	uuid.UUID       `json:"id"`
+	EventID     	string          `json:"event_id"`
+	Event       	string          `json:"event"`
+	Status      	string          `json:"status"`
+	Error       	string          `json:"error,omitempty"`
+	Attempts    	int32           `json:"attempts"`
+	Payload     	json.RawMessage `json:"payload"`
+	ReceivedAt  	time.Time       `json:"received_at"`
+	ProcessedAt 	*time.Time      `json:"processed_at"`
+}
+
+type WebhookEventPageResponse struct {
+	Events     	[]WebhookEventResponse `json:"events"`
+	NextCursor 	string                 `json:"next_cursor,omitempty"`
+	PrevCursor 	string                 `json:"prev_cursor,omitempty"`
+}
+
+type SubscriptionResponse struct {
+	Plan             	string     `json:"plan"`
+	Status           	string     `json:"status"`
+	CurrentPeriodEnd 	time.Time  `json:"current_period_end"`
+	CanceledAt       	*time.Time `json:"canceled_at,omitempty"`
+}
+
+type ScheduledChirpResponse struct {
+	ID        	uuid.UUID  `json:"id"`
+	Body      	string     `json:"body"`
+	ParentID  	*uuid.UUID `json:"parent_id,omitempty"`
+	PublishAt 	time.Time  `json:"publish_at"`
+	Status    	string     `json:"status"`
+	Error     	string     `json:"error,omitempty"`
+	CreatedAt 	time.Time  `json:"created_at"`
+}
+
+type EntitlementsResponse struct {
+	Tier               	string `json:"tier"`
+	MaxChirpLength     	int    `json:"max_chirp_length"`
+	ChirpsPerHour      	int    `json:"chirps_per_hour"`
+	EditChirps         	bool   `json:"edit_chirps"`
+	ScheduleChirps     	bool   `json:"schedule_chirps"`
+	MaxScheduledChirps 	int    `json:"max_scheduled_chirps"`
 }
\ No newline at end of file
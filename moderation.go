@@ -0,0 +1,47 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/moderation"
+)
+
+func moderationRuleFromDB(rule database.ModerationRule) moderation.Rule {
+	return moderation.Rule{Pattern: rule.Pattern, Match: moderation.MatchType(rule.MatchType), Action: moderation.Action(rule.Action)}
+}
+
+// reloadModerationRules rebuilds the filter from the base rules (the word list
+// file or the built-in defaults) and the rules stored in the database.
+func reloadModerationRules(ctx context.Context, apiCfg *apiConfig) error {
+	stored, err := apiCfg.database.GetModerationRules(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules := slices.Clone(apiCfg.baseModerationRules)
+	for _, rule := range stored {
+		rules = append(rules, moderationRuleFromDB(rule))
+	}
+	return apiCfg.moderation.SetRules(rules)
+}
+
+// saveModerationFlags queues a chirp for review once per flag rule it matched.
+func saveModerationFlags(ctx context.Context, q *database.Queries, chirpID uuid.UUID, result moderation.Result) error {
+	seen := make(map[moderation.Rule]bool)
+	for _, rule := range result.Matches {
+		if rule.Action != moderation.ActionFlag || seen[rule] {
+			continue
+		}
+		seen[rule] = true
+		reason := fmt.Sprintf("Matched %s rule %q", rule.Match, rule.Pattern)
+		err := q.CreateModerationFlag(ctx, database.CreateModerationFlagParams{ChirpID: chirpID, Reason: reason})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const oauthCodeTTL = 10 * time.Minute
+
+// oauthScopes are the scopes third-party clients may ask for. Account and
+// admin access stay with the user's own logins.
+var oauthScopes = []string{auth.ScopeRead, auth.ScopeWrite}
+
+// oauthError is an error response as defined by RFC 6749, either returned
+// from the token endpoints or sent back to the client's redirect URI.
+type oauthError struct {
+	Code string
+	Description string
+}
+
+func (e *oauthError) Error() string {
+	return e.Description
+}
+
+func respondWithOAuthError(w http.ResponseWriter, code int, err *oauthError) {
+	type response struct {
+		Error string `json:"error"`
+		Description string `json:"error_description,omitempty"`
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	respondWithJSON(w, code, response{Error: err.Code, Description: err.Description})
+}
+
+// authorizationRequest is a validated request to /oauth/authorize.
+type authorizationRequest struct {
+	Client database.OauthClient
+	RedirectURI string
+	Scopes []string
+	State string
+	CodeChallenge string
+}
+
+// parseAuthorizationRequest validates the query of an authorization request.
+// Errors found before the client and redirect URI are known must be shown to
+// the user; once RedirectURI is set, an *oauthError can be sent back to the
+// client instead. Other errors are database failures.
+func parseAuthorizationRequest(ctx context.Context, apiCfg *apiConfig, query url.Values) (authorizationRequest, error) {
+	request := authorizationRequest{State: query.Get("state")}
+
+	clientID, err := uuid.Parse(query.Get("client_id"))
+	if err != nil {
+		return request, &oauthError{"invalid_request", "client_id is missing or invalid"}
+	}
+	request.Client, err = apiCfg.database.GetOauthClient(ctx, clientID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return request, &oauthError{"invalid_request", "Unknown client"}
+	}
+	if err != nil {
+		return request, err
+	}
+
+	redirectURI := query.Get("redirect_uri")
+	if redirectURI == "" && len(request.Client.RedirectUris) == 1 {
+		redirectURI = request.Client.RedirectUris[0]
+	}
+	if !slices.Contains(request.Client.RedirectUris, redirectURI) {
+		return request, &oauthError{"invalid_request", "redirect_uri is not registered for this client"}
+	}
+	request.RedirectURI = redirectURI
+
+	if query.Get("response_type") != "code" {
+		return request, &oauthError{"unsupported_response_type", "Only the code response type is supported"}
+	}
+	request.CodeChallenge = query.Get("code_challenge")
+	if query.Get("code_challenge_method") != auth.PKCEMethodS256 || len(request.CodeChallenge) != 43 {
+		return request, &oauthError{"invalid_request", "PKCE with the S256 method is required"}
+	}
+
+	request.Scopes, err = clientScopes(request.Client, query.Get("scope"))
+	if err != nil {
+		return request, err
+	}
+	return request, nil
+}
+
+// clientScopes parses a space-delimited scope parameter and checks it against
+// the scopes the client was registered with. An empty parameter asks for all
+// of them.
+func clientScopes(client database.OauthClient, scope string) ([]string, error) {
+	requested := strings.Fields(scope)
+	if len(requested) == 0 {
+		return client.Scopes, nil
+	}
+
+	var scopes []string
+	for _, scope := range requested {
+		if !slices.Contains(client.Scopes, scope) {
+			return nil, &oauthError{"invalid_scope", fmt.Sprintf("The client may not request the %s scope", scope)}
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// authorizationRedirect adds params to the query of the client's redirect URI.
+func authorizationRedirect(redirectURI string, params url.Values) string {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+	query := parsed.Query()
+	for key, values := range params {
+		for _, value := range values {
+			if value != "" {
+				query.Add(key, value)
+			}
+		}
+	}
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// consentRedirect passes the query of an authorization request on to the
+// consent page, which may have a query of its own.
+func consentRedirect(consentURL, rawQuery string) string {
+	if strings.Contains(consentURL, "?") {
+		return consentURL + "&" + rawQuery
+	}
+	return consentURL + "?" + rawQuery
+}
+
+// validRedirectURI checks a redirect URI a client wants to register. Plain
+// http is only allowed for loopback addresses, for apps running locally;
+// native apps may use their own URI scheme.
+func validRedirectURI(redirectURI string) error {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil || parsed.Scheme == "" || parsed.Opaque != "" {
+		return fmt.Errorf("Redirect URI %q must be an absolute URI", redirectURI)
+	}
+	if parsed.Fragment != "" || strings.Contains(redirectURI, "#") {
+		return fmt.Errorf("Redirect URI %q must not contain a fragment", redirectURI)
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "https":
+		if parsed.Host == "" {
+			return fmt.Errorf("Redirect URI %q has no host", redirectURI)
+		}
+	case "http":
+		host := parsed.Hostname()
+		ip := net.ParseIP(host)
+		if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return fmt.Errorf("Redirect URI %q must use https", redirectURI)
+		}
+	case "javascript", "data", "file", "vbscript":
+		return fmt.Errorf("Redirect URI %q uses a forbidden scheme", redirectURI)
+	}
+	return nil
+}
+
+// authenticateOAuthClient identifies the client calling a token endpoint from
+// HTTP Basic credentials or the client_id and client_secret form fields.
+// Confidential clients must send their secret; public clients only send their
+// ID.
+func authenticateOAuthClient(r *http.Request, apiCfg *apiConfig) (database.OauthClient, error) {
+	clientID, secret, hasBasic := r.BasicAuth()
+	if hasBasic {
+		var err error
+		clientID, err = url.QueryUnescape(clientID)
+		if err == nil {
+			secret, err = url.QueryUnescape(secret)
+		}
+		if err != nil {
+			return database.OauthClient{}, &oauthError{"invalid_client", "Malformed client credentials"}
+		}
+	} else {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return database.OauthClient{}, &oauthError{"invalid_client", "Unknown client"}
+	}
+	client, err := apiCfg.database.GetOauthClient(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.OauthClient{}, &oauthError{"invalid_client", "Unknown client"}
+	}
+	if err != nil {
+		return database.OauthClient{}, err
+	}
+
+	if client.SecretHash.Valid != (secret != "") {
+		return database.OauthClient{}, &oauthError{"invalid_client", "Client authentication failed"}
+	}
+	if client.SecretHash.Valid && subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash.String)) != 1 {
+		return database.OauthClient{}, &oauthError{"invalid_client", "Client authentication failed"}
+	}
+	return client, nil
+}
+
+// issueOAuthTokens returns a new access token and refresh token for grant. The
+// access token may carry fewer scopes than the grant when the client asked
+// for less on refresh.
+func issueOAuthTokens(ctx context.Context, q *database.Queries, apiCfg *apiConfig, grant database.OauthGrant, user database.User, scopes []string) (OAuthTokenResponse, error) {
+	refresh, err := auth.MakeRefreshToken()
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+	err = q.CreateOauthRefreshToken(ctx, database.CreateOauthRefreshTokenParams{
+		TokenHash: auth.HashToken(refresh),
+		GrantID: grant.ID,
+		ExpiresAt: time.Now().Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+
+	jwt, err := apiCfg.keys.MakeJWT(auth.Principal{
+		UserID: user.ID,
+		Role: user.Role,
+		Scopes: scopes,
+		ClientID: grant.ClientID,
+		GrantID: grant.ID,
+	}, accessTokenTTL)
+	if err != nil {
+		return OAuthTokenResponse{}, err
+	}
+
+	return OAuthTokenResponse{
+		AccessToken: jwt,
+		TokenType: "Bearer",
+		ExpiresIn: int(accessTokenTTL.Seconds()),
+		RefreshToken: refresh,
+		Scope: strings.Join(scopes, " "),
+	}, nil
+}
+
+// revokeOAuthGrant revokes a grant after one of its codes or refresh tokens
+// was presented a second time, which means someone else holds a copy.
+func revokeOAuthGrant(ctx context.Context, q *database.Queries, grantID uuid.UUID, userID uuid.UUID, reason string) error {
+	revoked, err := q.RevokeOauthGrant(ctx, grantID)
+	if err != nil {
+		return err
+	}
+	if revoked > 0 {
+		logSecurityEvent("oauth_grant_revoked", userID, "grant %s: %s", grantID, reason)
+	}
+	return nil
+}
+
+// checkOAuthGrant makes sure the grant behind a client's access token has not
+// been revoked since the token was issued.
+func checkOAuthGrant(ctx context.Context, apiCfg *apiConfig, principal auth.Principal) error {
+	grant, err := apiCfg.database.GetOauthGrant(ctx, principal.GrantID)
+	if err != nil {
+		return err
+	}
+	if grant.RevokedAt.Valid || grant.UserID != principal.UserID || grant.ClientID != principal.ClientID {
+		return errors.New("grant has been revoked")
+	}
+	return nil
+}
+
+// activeOAuthRefreshToken looks up a refresh token issued to client and
+// returns its grant if the token can still be used.
+func activeOAuthRefreshToken(ctx context.Context, apiCfg *apiConfig, client database.OauthClient, token string) (database.OauthRefreshToken, database.OauthGrant, bool) {
+	refresh, err := apiCfg.database.GetOauthRefreshTokenForUpdate(ctx, auth.HashToken(token))
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			log.Printf("Error fetching OAuth refresh token: %v", err)
+		}
+		return refresh, database.OauthGrant{}, false
+	}
+	grant, err := apiCfg.database.GetOauthGrant(ctx, refresh.GrantID)
+	if err != nil {
+		log.Printf("Error fetching OAuth grant: %v", err)
+		return refresh, grant, false
+	}
+	active := grant.ClientID == client.ID && !grant.RevokedAt.Valid && !refresh.UsedAt.Valid && refresh.ExpiresAt.After(time.Now())
+	return refresh, grant, active
+}
@@ -0,0 +1,425 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/lib/pq"
+)
+
+func TestValidRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri string
+		valid bool
+	}{
+		{"https://app.example.com/callback", true},
+		{"http://localhost:3000/callback", true},
+		{"http://127.0.0.1:8000/cb", true},
+		{"http://[::1]/cb", true},
+		{"com.example.app:/oauth", true},
+		{"http://app.example.com/callback", false},
+		{"https://app.example.com/callback#frag", false},
+		{"/callback", false},
+		{"javascript:alert(1)", false},
+		{"data:/text", false},
+		{"https:///callback", false},
+	}
+
+	for _, test := range tests {
+		err := validRedirectURI(test.uri)
+		if (err == nil) != test.valid {
+			t.Errorf("Expected valid=%v for '%s', but got %v", test.valid, test.uri, err)
+		}
+	}
+}
+
+func TestAuthorizationRedirect(t *testing.T) {
+	redirect := authorizationRedirect("https://app.example.com/cb?tab=1", url.Values{"code": {"abc"}, "state": {""}})
+	if redirect != "https://app.example.com/cb?code=abc&tab=1" {
+		t.Errorf("Expected the code added and the empty state left out, but got '%s'", redirect)
+	}
+}
+
+func TestClientScopes(t *testing.T) {
+	client := database.OauthClient{Scopes: []string{"read", "write"}}
+
+	scopes, err := clientScopes(client, "")
+	if err != nil || !slices.Equal(scopes, []string{"read", "write"}) {
+		t.Errorf("Expected every client scope, but got %v, %v", scopes, err)
+	}
+
+	scopes, err = clientScopes(client, "write write")
+	if err != nil || !slices.Equal(scopes, []string{"write"}) {
+		t.Errorf("Expected [write], but got %v, %v", scopes, err)
+	}
+
+	_, err = clientScopes(client, "read account")
+	var oauthErr *oauthError
+	if !errors.As(err, &oauthErr) || oauthErr.Code != "invalid_scope" {
+		t.Errorf("Expected an invalid_scope error, but got %v", err)
+	}
+}
+
+func TestConsentRedirect(t *testing.T) {
+	tests := []struct {
+		consentURL string
+		expected   string
+	}{
+		{"http://localhost:8080/oauth/consent", "http://localhost:8080/oauth/consent?client_id=abc"},
+		{"https://app.example.com/#/consent", "https://app.example.com/#/consent?client_id=abc"},
+		{"https://app.example.com/consent?lang=en", "https://app.example.com/consent?lang=en&client_id=abc"},
+	}
+
+	for _, test := range tests {
+		result := consentRedirect(test.consentURL, "client_id=abc")
+		if result != test.expected {
+			t.Errorf("Expected '%s', but got '%s'", test.expected, result)
+		}
+	}
+}
+
+const testCodeVerifier = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+
+// fakeOAuthStore keeps the OAuth tables of a fakeDB in memory.
+type fakeOAuthStore struct {
+	clients map[string]database.OauthClient
+	codes   map[string]*database.OauthAuthorizationCode
+	grants  map[string]*database.OauthGrant
+	refresh map[string]*database.OauthRefreshToken
+}
+
+func newFakeOAuthStore(t *testing.T, db *fakeDB, user database.User, clients ...database.OauthClient) *fakeOAuthStore {
+	store := &fakeOAuthStore{
+		clients: map[string]database.OauthClient{},
+		codes:   map[string]*database.OauthAuthorizationCode{},
+		grants:  map[string]*database.OauthGrant{},
+		refresh: map[string]*database.OauthRefreshToken{},
+	}
+	for _, client := range clients {
+		store.clients[client.ID.String()] = client
+	}
+	scopes := func(value driver.Value) []string {
+		var result []string
+		if err := pq.Array(&result).Scan(value); err != nil {
+			t.Fatalf("Expected a scope array, but got %v", value)
+		}
+		return result
+	}
+
+	db.returns("GetUserById", user)
+	db.on("GetOauthClient", func(args []driver.Value) (any, error) {
+		client, ok := store.clients[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return client, nil
+	})
+	db.on("CreateOauthAuthorizationCode", func(args []driver.Value) (any, error) {
+		store.codes[args[0].(string)] = &database.OauthAuthorizationCode{
+			CodeHash:      args[0].(string),
+			ClientID:      uuid.MustParse(args[1].(string)),
+			UserID:        uuid.MustParse(args[2].(string)),
+			RedirectUri:   args[3].(string),
+			Scopes:        scopes(args[4]),
+			CodeChallenge: args[5].(string),
+			ExpiresAt:     args[6].(time.Time),
+		}
+		return nil, nil
+	})
+	db.on("GetOauthAuthorizationCodeForUpdate", func(args []driver.Value) (any, error) {
+		code, ok := store.codes[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return *code, nil
+	})
+	db.on("UseOauthAuthorizationCode", func(args []driver.Value) (any, error) {
+		code := store.codes[args[0].(string)]
+		code.UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		code.GrantID = uuid.NullUUID{UUID: uuid.MustParse(args[1].(string)), Valid: true}
+		return nil, nil
+	})
+	db.on("CreateOauthGrant", func(args []driver.Value) (any, error) {
+		grant := &database.OauthGrant{ID: uuid.New(), ClientID: uuid.MustParse(args[0].(string)), UserID: uuid.MustParse(args[1].(string)), Scopes: scopes(args[2]), CreatedAt: time.Now()}
+		store.grants[grant.ID.String()] = grant
+		return *grant, nil
+	})
+	db.on("GetOauthGrant", func(args []driver.Value) (any, error) {
+		grant, ok := store.grants[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return *grant, nil
+	})
+	db.on("RevokeOauthGrant", func(args []driver.Value) (any, error) {
+		grant, ok := store.grants[args[0].(string)]
+		if !ok || grant.RevokedAt.Valid {
+			return int64(0), nil
+		}
+		grant.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		return int64(1), nil
+	})
+	db.on("CreateOauthRefreshToken", func(args []driver.Value) (any, error) {
+		store.refresh[args[0].(string)] = &database.OauthRefreshToken{TokenHash: args[0].(string), GrantID: uuid.MustParse(args[1].(string)), ExpiresAt: args[2].(time.Time), CreatedAt: time.Now()}
+		return nil, nil
+	})
+	db.on("GetOauthRefreshTokenForUpdate", func(args []driver.Value) (any, error) {
+		refresh, ok := store.refresh[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return *refresh, nil
+	})
+	db.on("UseOauthRefreshToken", func(args []driver.Value) (any, error) {
+		store.refresh[args[0].(string)].UsedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		return nil, nil
+	})
+	return store
+}
+
+// authorizeOAuthClient approves an authorization request for client as user
+// and returns the code from the redirect.
+func authorizeOAuthClient(t *testing.T, apiCfg *apiConfig, user database.User, client database.OauthClient) string {
+	t.Helper()
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {client.RedirectUris[0]},
+		"state":                 {"xyz"},
+		"code_challenge":        {auth.PKCEChallenge(testCodeVerifier)},
+		"code_challenge_method": {auth.PKCEMethodS256},
+	}
+	r := httptest.NewRequest("POST", "/api/oauth/authorize?"+query.Encode(), strings.NewReader(`{"approved": true}`))
+	r.Header = bearer(t, apiCfg, user)
+	w := httptest.NewRecorder()
+	apiCfg.middleWareAuth(apiOAuthAuthorizeHandler(apiCfg), auth.ScopeAccount).ServeHTTP(w, r)
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200 approving the client, but got %d: %s", w.Code, w.Body.String())
+	}
+
+	var response OAuthRedirectResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	redirect, err := url.Parse(response.RedirectTo)
+	if err != nil || redirect.Query().Get("state") != "xyz" || redirect.Query().Get("code") == "" {
+		t.Fatalf("Expected a redirect with a code and the state, but got '%s'", response.RedirectTo)
+	}
+	return redirect.Query().Get("code")
+}
+
+// postOAuthForm sends a form to one of the /oauth endpoints as client.
+func postOAuthForm(apiCfg *apiConfig, handler func(*apiConfig) http.HandlerFunc, client database.OauthClient, secret string, form url.Values) *httptest.ResponseRecorder {
+	// Public clients only name themselves; confidential ones use Basic auth.
+	if secret == "" {
+		form.Set("client_id", client.ID.String())
+	}
+	r := httptest.NewRequest("POST", "/oauth/token", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if secret != "" {
+		r.SetBasicAuth(client.ID.String(), secret)
+	}
+	w := httptest.NewRecorder()
+	handler(apiCfg)(w, r)
+	return w
+}
+
+func oauthErrorCode(w *httptest.ResponseRecorder) string {
+	var response struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(w.Body).Decode(&response)
+	return response.Error
+}
+
+func TestOAuthCodeExchange(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	client := database.OauthClient{ID: uuid.New(), Name: "App", RedirectUris: []string{"https://app.example.com/cb"}, Scopes: []string{auth.ScopeRead, auth.ScopeWrite}}
+	other := database.OauthClient{ID: uuid.New(), Name: "Other", RedirectUris: []string{"https://other.example.com/cb"}, Scopes: []string{auth.ScopeRead}}
+	store := newFakeOAuthStore(t, db, user, client, other)
+
+	code := authorizeOAuthClient(t, apiCfg, user, client)
+	exchange := func(client database.OauthClient, verifier string) *httptest.ResponseRecorder {
+		return postOAuthForm(apiCfg, oauthTokenHandler, client, "", url.Values{
+			"grant_type":    {"authorization_code"},
+			"code":          {code},
+			"redirect_uri":  {client.RedirectUris[0]},
+			"code_verifier": {verifier},
+		})
+	}
+
+	tests := []struct {
+		name     string
+		client   database.OauthClient
+		verifier string
+	}{
+		{"wrong verifier", client, strings.Repeat("a", 43)},
+		{"missing verifier", client, ""},
+		{"other client", other, testCodeVerifier},
+	}
+	for _, test := range tests {
+		w := exchange(test.client, test.verifier)
+		if w.Code != 400 || oauthErrorCode(w) != "invalid_grant" {
+			t.Errorf("%s: expected an invalid_grant error, but got %d %s", test.name, w.Code, w.Body.String())
+		}
+	}
+	if store.codes[auth.HashToken(code)].UsedAt.Valid {
+		t.Fatalf("Expected a failed exchange to leave the code unused")
+	}
+
+	w := exchange(client, testCodeVerifier)
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response OAuthTokenResponse
+	json.NewDecoder(w.Body).Decode(&response)
+	if response.Scope != "read write" || response.RefreshToken == "" {
+		t.Errorf("Expected a refresh token and the approved scopes, but got %+v", response)
+	}
+	principal, err := apiCfg.keys.ValidateJWT(response.AccessToken)
+	if err != nil || principal.ClientID != client.ID || principal.UserID != user.ID {
+		t.Errorf("Expected an access token for the client and user, but got %+v, %v", principal, err)
+	}
+
+	// Redeeming the code again revokes what it was exchanged for.
+	w = exchange(client, testCodeVerifier)
+	if w.Code != 400 || oauthErrorCode(w) != "invalid_grant" {
+		t.Errorf("Expected a reused code to be rejected, but got %d %s", w.Code, w.Body.String())
+	}
+	if !store.grants[principal.GrantID.String()].RevokedAt.Valid {
+		t.Errorf("Expected the grant to be revoked after the code was reused")
+	}
+}
+
+func TestOAuthRefreshRotation(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	client := database.OauthClient{ID: uuid.New(), Name: "App", RedirectUris: []string{"https://app.example.com/cb"}, Scopes: []string{auth.ScopeRead, auth.ScopeWrite}}
+	store := newFakeOAuthStore(t, db, user, client)
+
+	code := authorizeOAuthClient(t, apiCfg, user, client)
+	w := postOAuthForm(apiCfg, oauthTokenHandler, client, "", url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {client.RedirectUris[0]}, "code_verifier": {testCodeVerifier}})
+	var first OAuthTokenResponse
+	json.NewDecoder(w.Body).Decode(&first)
+
+	refresh := func(token, scope string) *httptest.ResponseRecorder {
+		form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {token}}
+		if scope != "" {
+			form.Set("scope", scope)
+		}
+		return postOAuthForm(apiCfg, oauthTokenHandler, client, "", form)
+	}
+
+	w = refresh(first.RefreshToken, "account")
+	if w.Code != 400 || oauthErrorCode(w) != "invalid_scope" {
+		t.Errorf("Expected a scope that was not granted to be rejected, but got %d %s", w.Code, w.Body.String())
+	}
+
+	w = refresh(first.RefreshToken, "read")
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var second OAuthTokenResponse
+	json.NewDecoder(w.Body).Decode(&second)
+	if second.Scope != "read" || second.RefreshToken == first.RefreshToken {
+		t.Errorf("Expected a new refresh token with the narrower scope, but got %+v", second)
+	}
+	if !store.refresh[auth.HashToken(first.RefreshToken)].UsedAt.Valid {
+		t.Errorf("Expected the old refresh token to be used up")
+	}
+
+	// The first token coming back means it was copied.
+	w = refresh(first.RefreshToken, "")
+	if w.Code != 400 || oauthErrorCode(w) != "invalid_grant" {
+		t.Errorf("Expected a rotated refresh token to be rejected, but got %d %s", w.Code, w.Body.String())
+	}
+	w = refresh(second.RefreshToken, "")
+	if w.Code != 400 {
+		t.Errorf("Expected the newer refresh token to stop working with the grant, but got %d", w.Code)
+	}
+}
+
+func TestOAuthRevokeAndIntrospect(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	client := database.OauthClient{ID: uuid.New(), Name: "App", SecretHash: sql.NullString{String: auth.HashToken("secret"), Valid: true}, RedirectUris: []string{"https://app.example.com/cb"}, Scopes: []string{auth.ScopeRead}}
+	other := database.OauthClient{ID: uuid.New(), Name: "Other", SecretHash: sql.NullString{String: auth.HashToken("other-secret"), Valid: true}, RedirectUris: []string{"https://other.example.com/cb"}, Scopes: []string{auth.ScopeRead}}
+	public := database.OauthClient{ID: uuid.New(), Name: "Public", RedirectUris: []string{"https://public.example.com/cb"}, Scopes: []string{auth.ScopeRead}}
+	newFakeOAuthStore(t, db, user, client, other, public)
+	db.returns("TouchPersonalAccessToken", nil)
+
+	code := authorizeOAuthClient(t, apiCfg, user, client)
+	w := postOAuthForm(apiCfg, oauthTokenHandler, client, "secret", url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {client.RedirectUris[0]}, "code_verifier": {testCodeVerifier}})
+	var tokens OAuthTokenResponse
+	json.NewDecoder(w.Body).Decode(&tokens)
+	if tokens.AccessToken == "" {
+		t.Fatalf("Expected tokens, but got %d %s", w.Code, w.Body.String())
+	}
+
+	introspect := func(client database.OauthClient, secret, token string) OAuthIntrospectionResponse {
+		t.Helper()
+		w := postOAuthForm(apiCfg, oauthIntrospectHandler, client, secret, url.Values{"token": {token}})
+		if w.Code != 200 {
+			t.Fatalf("Expected status 200 introspecting, but got %d: %s", w.Code, w.Body.String())
+		}
+		var response OAuthIntrospectionResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		return response
+	}
+
+	if w := postOAuthForm(apiCfg, oauthIntrospectHandler, public, "", url.Values{"token": {tokens.AccessToken}}); w.Code != 401 {
+		t.Errorf("Expected public clients to be refused, but got %d", w.Code)
+	}
+	if w := postOAuthForm(apiCfg, oauthIntrospectHandler, client, "wrong", url.Values{"token": {tokens.AccessToken}}); w.Code != 401 {
+		t.Errorf("Expected a wrong secret to be refused, but got %d", w.Code)
+	}
+	if response := introspect(client, "secret", tokens.AccessToken); !response.Active || response.TokenType != "Bearer" || response.Subject != user.ID.String() {
+		t.Errorf("Expected the access token to be active, but got %+v", response)
+	}
+	if response := introspect(client, "secret", tokens.RefreshToken); !response.Active || response.TokenType != "refresh_token" {
+		t.Errorf("Expected the refresh token to be active, but got %+v", response)
+	}
+	if response := introspect(other, "other-secret", tokens.AccessToken); response.Active {
+		t.Errorf("Expected another client's token to read as inactive, but got %+v", response)
+	}
+
+	// Revoking as another client, or an unknown token, does nothing.
+	if w := postOAuthForm(apiCfg, oauthRevokeHandler, other, "other-secret", url.Values{"token": {tokens.RefreshToken}}); w.Code != 200 {
+		t.Errorf("Expected status 200, but got %d", w.Code)
+	}
+	if w := postOAuthForm(apiCfg, oauthRevokeHandler, client, "secret", url.Values{"token": {"unknown"}}); w.Code != 200 {
+		t.Errorf("Expected status 200 for an unknown token, but got %d", w.Code)
+	}
+	if response := introspect(client, "secret", tokens.AccessToken); !response.Active {
+		t.Errorf("Expected the grant to survive revocation by another client")
+	}
+
+	if w := postOAuthForm(apiCfg, oauthRevokeHandler, client, "secret", url.Values{"token": {tokens.RefreshToken}}); w.Code != 200 {
+		t.Errorf("Expected status 200, but got %d", w.Code)
+	}
+	if response := introspect(client, "secret", tokens.AccessToken); response.Active {
+		t.Errorf("Expected the access token to be inactive after revocation, but got %+v", response)
+	}
+	if response := introspect(client, "secret", tokens.RefreshToken); response.Active {
+		t.Errorf("Expected the refresh token to be inactive after revocation, but got %+v", response)
+	}
+
+	r := httptest.NewRequest("GET", "/api/chirps", nil)
+	r.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	w = httptest.NewRecorder()
+	apiCfg.middleWareAuth(func(w http.ResponseWriter, r *http.Request) {}, auth.ScopeRead).ServeHTTP(w, r)
+	if w.Code != 401 {
+		t.Errorf("Expected the revoked access token to be refused, but got %d", w.Code)
+	}
+}
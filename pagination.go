@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+type pageCursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uuid.UUID `json:"id"`
+	Prev      bool      `json:"p,omitempty"`
+	Rank      float32   `json:"r,omitempty"`
+	// Descending records the sort order the cursor was issued for, since
+	// it only points at the right page in that order.
+	Descending bool `json:"d,omitempty"`
+}
+
+type pageParams struct {
+	Limit  int32
+	Cursor *pageCursor
+}
+
+type page[T any] struct {
+	Items      []T
+	NextCursor string
+	PrevCursor string
+}
+
+// pageFetcher loads up to limit items strictly after the cursor in the given
+// direction. A nil cursor means start from the beginning.
+type pageFetcher[T any] func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]T, error)
+
+// pageKey returns the (created_at, id) pair an item is ordered by.
+type pageKey[T any] func(item T) (time.Time, uuid.UUID)
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	err = json.Unmarshal(data, &cursor)
+	if err != nil || cursor.ID == uuid.Nil || cursor.CreatedAt.IsZero() {
+		return cursor, fmt.Errorf("invalid cursor")
+	}
+	return cursor, nil
+}
+
+func parsePageParams(r *http.Request) (pageParams, error) {
+	params := pageParams{Limit: defaultPageLimit}
+
+	limitQuery := r.URL.Query().Get("limit")
+	if limitQuery != "" {
+		limit, err := strconv.Atoi(limitQuery)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			return params, fmt.Errorf("limit must be between 1 and %d", maxPageLimit)
+		}
+		params.Limit = int32(limit)
+	}
+
+	cursorQuery := r.URL.Query().Get("cursor")
+	if cursorQuery != "" {
+		cursor, err := decodeCursor(cursorQuery)
+		if err != nil {
+			return params, err
+		}
+		params.Cursor = &cursor
+	}
+
+	return params, nil
+}
+
+// checkCursorSort returns an error if the cursor in params was issued for the
+// other sort order.
+func checkCursorSort(params pageParams, descending bool) error {
+	if params.Cursor != nil && params.Cursor.Descending != descending {
+		return fmt.Errorf("cursor does not match the sort order")
+	}
+	return nil
+}
+
+// fetchPage pages through items ordered by (created_at, id). Paging backwards
+// runs the query in the opposite direction and reverses the result, so every
+// page is returned in the requested sort order.
+func fetchPage[T any](ctx context.Context, params pageParams, descending bool, fetch pageFetcher[T], key pageKey[T]) (page[T], error) {
+	backwards := params.Cursor != nil && params.Cursor.Prev
+	ascending := descending == backwards
+
+	items, err := fetch(ctx, ascending, params.Cursor, params.Limit+1)
+	if err != nil {
+		return page[T]{}, err
+	}
+
+	hasMore := len(items) > int(params.Limit)
+	if hasMore {
+		items = items[:params.Limit]
+	}
+	if backwards {
+		slices.Reverse(items)
+	}
+
+	result := page[T]{Items: items}
+	if len(items) == 0 {
+		return result, nil
+	}
+
+	if hasMore || backwards {
+		createdAt, id := key(items[len(items)-1])
+		result.NextCursor = encodeCursor(pageCursor{CreatedAt: createdAt, ID: id, Descending: descending})
+	}
+	if params.Cursor != nil && (hasMore || !backwards) {
+		createdAt, id := key(items[0])
+		result.PrevCursor = encodeCursor(pageCursor{CreatedAt: createdAt, ID: id, Prev: true, Descending: descending})
+	}
+
+	return result, nil
+}
+
+func chirpPageKey(chirp database.Chirp) (time.Time, uuid.UUID) {
+	return chirp.CreatedAt, chirp.ID
+}
+
+func cursorParams(cursor *pageCursor) (sql.NullTime, uuid.NullUUID) {
+	if cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: cursor.CreatedAt, Valid: true}, uuid.NullUUID{UUID: cursor.ID, Valid: true}
+}
+
+func setPaginationLinks(w http.ResponseWriter, r *http.Request, nextCursor, prevCursor string) {
+	var links []string
+	if nextCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="next"`, pageURL(r, nextCursor)))
+	}
+	if prevCursor != "" {
+		links = append(links, fmt.Sprintf(`<%s>; rel="prev"`, pageURL(r, prevCursor)))
+	}
+	if len(links) > 0 {
+		w.Header().Set("Link", strings.Join(links, ", "))
+	}
+}
+
+func pageURL(r *http.Request, cursor string) string {
+	query := r.URL.Query()
+	query.Set("cursor", cursor)
+	return r.URL.Path + "?" + query.Encode()
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := pageCursor{CreatedAt: time.Date(2025, 3, 1, 12, 30, 0, 123456000, time.UTC), ID: uuid.New(), Prev: true}
+
+	decoded, err := decodeCursor(encodeCursor(cursor))
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if !decoded.CreatedAt.Equal(cursor.CreatedAt) || decoded.ID != cursor.ID || decoded.Prev != cursor.Prev {
+		t.Errorf("Expected '%v', but got '%v'", cursor, decoded)
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	inputs := []string{"not-base64!", "bm90IGpzb24", encodeCursor(pageCursor{})}
+
+	for _, input := range inputs {
+		_, err := decodeCursor(input)
+		if err == nil {
+			t.Errorf("Expected an error for cursor '%s'", input)
+		}
+	}
+}
+
+func TestFetchPage(t *testing.T) {
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	chirps := make([]database.Chirp, 5)
+	for i := range chirps {
+		chirps[i] = database.Chirp{ID: uuid.New(), CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+	}
+
+	fetch := func(ctx context.Context, ascending bool, cursor *pageCursor, limit int32) ([]database.Chirp, error) {
+		ordered := slices.Clone(chirps)
+		if !ascending {
+			slices.Reverse(ordered)
+		}
+		var result []database.Chirp
+		for _, chirp := range ordered {
+			if cursor != nil {
+				if ascending && !chirp.CreatedAt.After(cursor.CreatedAt) {
+					continue
+				}
+				if !ascending && !chirp.CreatedAt.Before(cursor.CreatedAt) {
+					continue
+				}
+			}
+			if len(result) < int(limit) {
+				result = append(result, chirp)
+			}
+		}
+		return result, nil
+	}
+
+	tests := []struct {
+		descending bool
+		expected   [][]int
+	}{
+		{false, [][]int{{0, 1}, {2, 3}, {4}}},
+		{true, [][]int{{4, 3}, {2, 1}, {0}}},
+	}
+
+	for _, test := range tests {
+		params := pageParams{Limit: 2}
+		var pages []page[database.Chirp]
+		for {
+			page, err := fetchPage(context.Background(), params, test.descending, fetch, chirpPageKey)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %v", err)
+			}
+			pages = append(pages, page)
+			if page.NextCursor == "" {
+				break
+			}
+			cursor, _ := decodeCursor(page.NextCursor)
+			params.Cursor = &cursor
+		}
+
+		if len(pages) != len(test.expected) {
+			t.Fatalf("Expected %d pages, but got %d", len(test.expected), len(pages))
+		}
+		for i, page := range pages {
+			for j, chirp := range page.Items {
+				if chirp.ID != chirps[test.expected[i][j]].ID {
+					t.Errorf("Page %d: expected chirp %d at position %d", i, test.expected[i][j], j)
+				}
+			}
+		}
+		if pages[0].PrevCursor != "" {
+			t.Errorf("Expected no previous cursor on the first page")
+		}
+
+		cursor, _ := decodeCursor(pages[2].PrevCursor)
+		params.Cursor = &cursor
+		page, err := fetchPage(context.Background(), params, test.descending, fetch, chirpPageKey)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %v", err)
+		}
+		if len(page.Items) != 2 || page.Items[0].ID != pages[1].Items[0].ID || page.Items[1].ID != pages[1].Items[1].ID {
+			t.Errorf("Expected the previous page to match the second page")
+		}
+		if page.NextCursor == "" || page.PrevCursor == "" {
+			t.Errorf("Expected both cursors on a middle page")
+		}
+		if checkCursorSort(params, test.descending) != nil || checkCursorSort(params, !test.descending) == nil {
+			t.Errorf("Expected cursors to only be accepted for the sort order they were issued for")
+		}
+	}
+}
+
+func TestGetChirpsRejectsCursorForOtherSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		cursor   pageCursor
+		sort     string
+		expected int
+	}{
+		{"ascending cursor", pageCursor{}, "desc", 400},
+		{"descending cursor", pageCursor{Descending: true}, "asc", 400},
+		{"matching cursor", pageCursor{Descending: true}, "desc", 200},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		db.returns("GetChirpsDescending", nil)
+		test.cursor.CreatedAt, test.cursor.ID = time.Now(), uuid.New()
+
+		r := httptest.NewRequest("GET", "/api/chirps?sort="+test.sort+"&cursor="+encodeCursor(test.cursor), nil)
+		w := httptest.NewRecorder()
+		apiGetAllChirpsHandler(apiCfg)(w, r)
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d", test.name, test.expected, w.Code)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"net/http"
+)
+
+// rejectPassword checks password against the configured policy and, if it
+// breaks any rule, writes a 400 response listing every violation under the
+// password field. A breached list that cannot be read is logged and skipped
+// rather than blocking every password change.
+func rejectPassword(w http.ResponseWriter, apiCfg *apiConfig, password, email string) bool {
+	violations, err := apiCfg.passwordPolicy.Check(password, email)
+	if err != nil {
+		log.Printf("Error checking breached passwords: %v", err)
+	}
+	if len(violations) == 0 {
+		return false
+	}
+
+	fieldErrors := make([]FieldError, len(violations))
+	for i, violation := range violations {
+		fieldErrors[i] = FieldError{Code: violation.Code, Message: violation.Message}
+	}
+	respondWithFieldErrors(w, 400, "Password does not meet the requirements", map[string][]FieldError{"password": fieldErrors})
+	return true
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/isotronic/http-go-server/internal/passwordpolicy"
+)
+
+func TestRejectPassword(t *testing.T) {
+	apiCfg := &apiConfig{passwordPolicy: passwordpolicy.DefaultPolicy}
+
+	w := httptest.NewRecorder()
+	if rejectPassword(w, apiCfg, "correct horse battery", "walter@example.com") {
+		t.Fatalf("Expected an acceptable password to pass, but got %s", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	if !rejectPassword(w, apiCfg, "walter", "walter@example.com") {
+		t.Fatalf("Expected a short password containing the email to be rejected")
+	}
+	if w.Code != 400 {
+		t.Errorf("Expected status 400, but got %d", w.Code)
+	}
+
+	var body struct {
+		Error string `json:"error"`
+		Fields map[string][]FieldError `json:"fields"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("Expected a JSON body, but got %v", err)
+	}
+	var codes []string
+	for _, fieldError := range body.Fields["password"] {
+		codes = append(codes, fieldError.Code)
+	}
+	if len(codes) != 2 || codes[0] != passwordpolicy.CodeTooShort || codes[1] != passwordpolicy.CodeContainsEmail {
+		t.Errorf("Expected too_short and contains_email, but got %v", codes)
+	}
+}
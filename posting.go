@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/entitlements"
+	"github.com/isotronic/http-go-server/internal/moderation"
+)
+
+// chirpRateWindow is the period entitlements.Limits.ChirpsPerHour counts over.
+const chirpRateWindow = time.Hour
+
+// chirpRejection is a reason a chirp cannot be posted that the author has to
+// fix, as opposed to a server error.
+type chirpRejection struct {
+	Code int
+	Message string
+}
+
+func (e *chirpRejection) Error() string {
+	return e.Message
+}
+
+// userLimits returns the entitlements of the tier user is on.
+func userLimits(apiCfg *apiConfig, user database.User) entitlements.Limits {
+	return apiCfg.entitlements.For(entitlements.TierOf(user.IsChirpyRed))
+}
+
+// rejectChirpRate writes a 429 response and returns true if the user has
+// posted as many chirps in the last hour as their tier allows. Deleted chirps
+// still count. q should be in the transaction that inserts the chirp, with the
+// user locked, so concurrent requests cannot all pass the check.
+func rejectChirpRate(w http.ResponseWriter, r *http.Request, q *database.Queries, userID uuid.UUID, limits entitlements.Limits) bool {
+	if limits.ChirpsPerHour == 0 {
+		return false
+	}
+
+	now := time.Now()
+	recent, err := q.CountRecentChirps(r.Context(), database.CountRecentChirpsParams{
+		UserID: userID,
+		Since: now.Add(-chirpRateWindow),
+	})
+	if err != nil {
+		log.Printf("Error counting recent chirps: %v", err)
+		respondWithError(w, 500, "Error creating chirp")
+		return true
+	}
+	if recent.Count < int64(limits.ChirpsPerHour) {
+		return false
+	}
+
+	w.Header().Set("Retry-After", fmt.Sprint(retryAfterSeconds(recent.Oldest.Time.Add(chirpRateWindow), now)))
+	respondWithError(w, 429, fmt.Sprintf("You can post %d chirps per hour, try again later", limits.ChirpsPerHour))
+	return true
+}
+
+// validateChirpParent checks that userID may reply to the chirp parentID.
+// Replies are refused with a 403 when a block separates the two users, in
+// either direction.
+func validateChirpParent(ctx context.Context, apiCfg *apiConfig, userID, parentID uuid.UUID) (database.Chirp, error) {
+	parent, err := apiCfg.database.GetChirpById(ctx, parentID)
+	if err != nil || parent.DeletedAt.Valid {
+		return parent, &chirpRejection{400, "Parent chirp does not exist"}
+	}
+	rel, err := getRelationship(ctx, apiCfg, userID, parent.UserID)
+	if err != nil {
+		return parent, fmt.Errorf("fetching relationship: %w", err)
+	}
+	if !rel.canInteract() {
+		return parent, &chirpRejection{403, "You cannot reply to this user"}
+	}
+	return parent, nil
+}
+
+// insertChirp creates a chirp along with its hashtags, mentions and
+// moderation flags.
+func insertChirp(ctx context.Context, q *database.Queries, params database.CreateChirpParams, moderated moderation.Result) (database.Chirp, error) {
+	chirp, err := q.CreateChirp(ctx, params)
+	if err != nil {
+		return chirp, fmt.Errorf("creating chirp: %w", err)
+	}
+
+	err = saveChirpEntities(ctx, q, chirp)
+	if err != nil {
+		return chirp, fmt.Errorf("saving chirp entities: %w", err)
+	}
+
+	err = saveModerationFlags(ctx, q, chirp.ID, moderated)
+	if err != nil {
+		return chirp, fmt.Errorf("saving moderation flags: %w", err)
+	}
+	return chirp, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/entitlements"
+)
+
+func TestUserLimits(t *testing.T) {
+	apiCfg := &apiConfig{entitlements: entitlements.Tiers{
+		Free: entitlements.Limits{MaxChirpLength: 140},
+		Red: entitlements.Limits{MaxChirpLength: 280, EditChirps: true},
+	}}
+
+	free := userLimits(apiCfg, database.User{})
+	if free.MaxChirpLength != 140 || free.EditChirps {
+		t.Errorf("Expected the free tier limits, but got %+v", free)
+	}
+
+	red := userLimits(apiCfg, database.User{IsChirpyRed: true})
+	if red.MaxChirpLength != 280 || !red.EditChirps {
+		t.Errorf("Expected the red tier limits, but got %+v", red)
+	}
+}
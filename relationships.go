@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+// relationship describes how a viewer relates to the author of a chirp.
+type relationship struct {
+	BlockedByAuthor bool
+	BlocksAuthor bool
+	MutesAuthor bool
+}
+
+// canView reports whether the viewer may see the author's chirps. Only the
+// author's block hides them; a viewer who blocked the author can still open
+// a chirp directly.
+func (rel relationship) canView() bool {
+	return !rel.BlockedByAuthor
+}
+
+// canInteract reports whether the viewer may reply to, like, rechirp or
+// follow the author. A block in either direction prevents it.
+func (rel relationship) canInteract() bool {
+	return !rel.BlockedByAuthor && !rel.BlocksAuthor
+}
+
+func getRelationship(ctx context.Context, apiCfg *apiConfig, viewerID, authorID uuid.UUID) (relationship, error) {
+	if viewerID == uuid.Nil || viewerID == authorID {
+		return relationship{}, nil
+	}
+	row, err := apiCfg.database.GetUserRelationship(ctx, database.GetUserRelationshipParams{AuthorID: authorID, ViewerID: viewerID})
+	if err != nil {
+		return relationship{}, err
+	}
+	return relationship(row), nil
+}
+
+// hideBlockedChirps blanks the chirps by authors the viewer has blocked or
+// been blocked by. Used where dropping them would break a structure, like a
+// reply thread.
+func hideBlockedChirps(ctx context.Context, apiCfg *apiConfig, viewerID uuid.UUID, chirps []ChirpResponse) error {
+	if viewerID == uuid.Nil || len(chirps) == 0 {
+		return nil
+	}
+
+	authorIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		authorIDs[i] = chirp.UserID
+	}
+	blockedAuthors, err := apiCfg.database.GetBlockedAuthorsAmong(ctx, database.GetBlockedAuthorsAmongParams{UserID: viewerID, AuthorIds: authorIDs})
+	if err != nil {
+		return err
+	}
+	if len(blockedAuthors) == 0 {
+		return nil
+	}
+
+	blocked := make(map[uuid.UUID]bool, len(blockedAuthors))
+	for _, author := range blockedAuthors {
+		blocked[author] = true
+	}
+	for i := range chirps {
+		if blocked[chirps[i].UserID] {
+			chirps[i] = hiddenChirpResponse(chirps[i])
+		}
+	}
+	return nil
+}
+
+func hiddenChirpResponse(chirp ChirpResponse) ChirpResponse {
+	return ChirpResponse{
+		ID: chirp.ID,
+		UserID: chirp.UserID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		ParentID: chirp.ParentID,
+		Deleted: chirp.Deleted,
+		Hidden: true,
+		Entities: ChirpEntities{Hashtags: []HashtagEntity{}, Mentions: []MentionEntity{}},
+	}
+}
+
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func TestRelationship_Block(t *testing.T) {
+	// Alice has blocked Bob. Bob sees Alice's chirps with BlockedByAuthor set,
+	// Alice sees Bob's chirps with BlocksAuthor set.
+	tests := []struct {
+		name        string
+		rel         relationship
+		canView     bool
+		canInteract bool
+	}{
+		{"no relationship", relationship{}, true, true},
+		{"blocked user viewing blocker", relationship{BlockedByAuthor: true}, false, false},
+		{"blocker viewing blocked user", relationship{BlocksAuthor: true}, true, false},
+		{"mutual block", relationship{BlockedByAuthor: true, BlocksAuthor: true}, false, false},
+		{"muted author", relationship{MutesAuthor: true}, true, true},
+	}
+
+	for _, test := range tests {
+		if test.rel.canView() != test.canView {
+			t.Errorf("%s: expected canView %v, but got %v", test.name, test.canView, test.rel.canView())
+		}
+		if test.rel.canInteract() != test.canInteract {
+			t.Errorf("%s: expected canInteract %v, but got %v", test.name, test.canInteract, test.rel.canInteract())
+		}
+	}
+}
+
+func TestHiddenChirpResponse(t *testing.T) {
+	parentID := uuid.New()
+	chirp := ChirpResponse{
+		ID: uuid.New(),
+		UserID: uuid.New(),
+		Body: "#secret from @alice@example.com",
+		CreatedAt: time.Now(),
+		ParentID: &parentID,
+		LikeCount: 3,
+		Liked: true,
+		Entities: chirpEntitiesResponse("#secret", nil),
+	}
+
+	hidden := hiddenChirpResponse(chirp)
+	if !hidden.Hidden || hidden.Body != "" {
+		t.Errorf("Expected a hidden chirp without body, but got '%s'", hidden.Body)
+	}
+	if hidden.ID != chirp.ID || hidden.ParentID != chirp.ParentID {
+		t.Errorf("Expected the chirp to keep its place in the thread")
+	}
+	if hidden.LikeCount != 0 || hidden.Liked || len(hidden.Entities.Hashtags) != 0 {
+		t.Errorf("Expected engagement and entities to be dropped")
+	}
+}
+
+func TestGetChirpThreadHidesBlockedAuthors(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	viewer := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	blocked, blocker, other := uuid.New(), uuid.New(), uuid.New()
+
+	// The viewer blocked the author of the root and of the opened chirp, and
+	// the author of the first reply blocked the viewer.
+	now := time.Now().UTC()
+	root := database.GetChirpAncestorsRow{ID: uuid.New(), UserID: blocked, Body: "root", CreatedAt: now, UpdatedAt: now}
+	chirp := database.GetChirpDescendantsRow{ID: uuid.New(), UserID: blocked, Body: "opened", CreatedAt: now, UpdatedAt: now, ParentID: uuid.NullUUID{UUID: root.ID, Valid: true}}
+	reply := database.GetChirpDescendantsRow{ID: uuid.New(), UserID: blocker, Body: "reply", CreatedAt: now, UpdatedAt: now, ParentID: uuid.NullUUID{UUID: chirp.ID, Valid: true}, Depth: 1}
+	otherReply := database.GetChirpDescendantsRow{ID: uuid.New(), UserID: other, Body: "other", CreatedAt: now, UpdatedAt: now, ParentID: uuid.NullUUID{UUID: chirp.ID, Valid: true}, Depth: 1}
+
+	db.returns("GetUserById", viewer)
+	db.returns("GetChirpAncestors", []database.GetChirpAncestorsRow{root})
+	db.returns("GetChirpDescendants", []database.GetChirpDescendantsRow{chirp, reply, otherReply})
+	db.returns("GetUserRelationship", database.GetUserRelationshipRow{BlocksAuthor: true})
+	db.returns("GetChirpMentions", nil)
+	db.returns("GetViewerEngagement", nil)
+	db.on("GetBlockedAuthorsAmong", func(args []driver.Value) (any, error) {
+		if args[0] != viewer.ID.String() {
+			t.Errorf("Expected blocks to be looked up for the viewer, but got %v", args[0])
+		}
+		return []uuid.UUID{blocked, blocker}, nil
+	})
+
+	req := httptest.NewRequest("GET", "/api/chirps/"+chirp.ID.String()+"/thread", nil)
+	req.SetPathValue("chirpID", chirp.ID.String())
+	req.Header = bearer(t, apiCfg, viewer)
+	w := httptest.NewRecorder()
+	apiGetChirpThreadHandler(apiCfg)(w, req)
+
+	if w.Code != 200 {
+		t.Fatalf("Expected status 200, but got %d: %s", w.Code, w.Body.String())
+	}
+	var response ChirpThreadResponse
+	json.NewDecoder(w.Body).Decode(&response)
+
+	if !response.Ancestors[0].Hidden {
+		t.Errorf("Expected the root by a blocked user to be hidden")
+	}
+	if response.Chirp.Hidden || response.Chirp.Body != "opened" {
+		t.Errorf("Expected the opened chirp to stay readable, but got %+v", response.Chirp.ChirpResponse)
+	}
+	replies := map[uuid.UUID]ChirpThreadNode{}
+	for _, node := range response.Chirp.Replies {
+		replies[node.ID] = node
+	}
+	if !replies[reply.ID].Hidden {
+		t.Errorf("Expected the reply by a user who blocked the viewer to be hidden")
+	}
+	if replies[otherReply.ID].Hidden {
+		t.Errorf("Expected the reply by an unrelated user to be shown")
+	}
+}
+
+func TestBlockFilteringQueries(t *testing.T) {
+	_, q := newTestDatabase(t)
+	ctx := t.Context()
+
+	viewer := createTestUser(t, q, "viewer@example.com")
+	blocker := createTestUser(t, q, "blocker@example.com")
+	blocked := createTestUser(t, q, "blocked@example.com")
+	muted := createTestUser(t, q, "muted@example.com")
+	other := createTestUser(t, q, "other@example.com")
+
+	chirps := map[uuid.UUID]database.Chirp{}
+	for _, user := range []database.User{blocker, blocked, muted, other} {
+		chirps[user.ID] = createTestChirp(t, q, user.ID, "hello from "+user.Email)
+		err := q.FollowUser(ctx, database.FollowUserParams{FollowerID: viewer.ID, FolloweeID: user.ID})
+		if err != nil {
+			t.Fatalf("Expected no error following, but got %v", err)
+		}
+	}
+	for _, block := range []database.BlockUserParams{{BlockerID: blocker.ID, BlockedID: viewer.ID}, {BlockerID: viewer.ID, BlockedID: blocked.ID}} {
+		err := q.BlockUser(ctx, block)
+		if err != nil {
+			t.Fatalf("Expected no error blocking, but got %v", err)
+		}
+	}
+	err := q.MuteUser(ctx, database.MuteUserParams{MuterID: viewer.ID, MutedID: muted.ID})
+	if err != nil {
+		t.Fatalf("Expected no error muting, but got %v", err)
+	}
+
+	expectOnly := func(name string, got []database.Chirp, visible ...database.User) {
+		t.Helper()
+		ids := chirpIDs(got)
+		if len(ids) != len(visible) {
+			t.Errorf("%s: expected %d chirps, but got %d", name, len(visible), len(ids))
+		}
+		for _, user := range visible {
+			if !ids[chirps[user.ID].ID] {
+				t.Errorf("%s: expected the chirp by %s", name, user.Email)
+			}
+		}
+	}
+
+	listed, err := q.GetChirpsDescending(ctx, database.GetChirpsDescendingParams{Limit: 10, ViewerID: nullUUID(viewer.ID)})
+	if err != nil {
+		t.Fatalf("Expected no error listing chirps, but got %v", err)
+	}
+	expectOnly("chirp list", listed, other)
+
+	timeline, err := q.GetTimelineDescending(ctx, database.GetTimelineDescendingParams{Limit: 10, UserID: viewer.ID})
+	if err != nil {
+		t.Fatalf("Expected no error reading the timeline, but got %v", err)
+	}
+	followed := make([]database.Chirp, len(timeline))
+	for i, row := range timeline {
+		followed[i] = database.Chirp(row)
+	}
+	expectOnly("timeline", followed, other)
+
+	rows, err := q.SearchChirps(ctx, database.SearchChirpsParams{Query: "hello", Limit: 10, ViewerID: nullUUID(viewer.ID)})
+	if err != nil {
+		t.Fatalf("Expected no error searching, but got %v", err)
+	}
+	found := make([]database.Chirp, len(rows))
+	for i, row := range rows {
+		found[i] = row.Chirp
+	}
+	expectOnly("search", found, other)
+
+	authors, err := q.GetBlockedAuthorsAmong(ctx, database.GetBlockedAuthorsAmongParams{UserID: viewer.ID, AuthorIds: []uuid.UUID{blocker.ID, blocked.ID, muted.ID, other.ID}})
+	if err != nil {
+		t.Fatalf("Expected no error fetching blocks, but got %v", err)
+	}
+	if len(authors) != 2 {
+		t.Errorf("Expected both block directions, but got %v", authors)
+	}
+}
+
+func TestBlockedUsersCannotReachEachOther(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	blocker := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	blocked := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	other := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	users := map[string]database.User{blocker.ID.String(): blocker, blocked.ID.String(): blocked, other.ID.String(): other}
+
+	now := time.Now().UTC()
+	chirps := map[string]database.Chirp{}
+	for _, user := range users {
+		chirp := database.Chirp{ID: uuid.New(), UserID: user.ID, Body: "hello", CreatedAt: now, UpdatedAt: now}
+		chirps[user.ID.String()] = chirp
+		chirps[chirp.ID.String()] = chirp
+	}
+	// Only the blocker has blocked the other user; the queries below work
+	// out both directions from that, as the SQL does.
+	blocks := func(blockerID, blockedID string) bool {
+		return blockerID == blocker.ID.String() && blockedID == blocked.ID.String()
+	}
+
+	db.on("GetUserById", func(args []driver.Value) (any, error) { return users[args[0].(string)], nil })
+	db.on("GetChirpById", func(args []driver.Value) (any, error) { return chirps[args[0].(string)], nil })
+	db.on("GetUserRelationship", func(args []driver.Value) (any, error) {
+		author, viewer := args[0].(string), args[1].(string)
+		return database.GetUserRelationshipRow{BlockedByAuthor: blocks(author, viewer), BlocksAuthor: blocks(viewer, author)}, nil
+	})
+	db.on("GetChirpsAscending", func(args []driver.Value) (any, error) {
+		viewer, _ := args[4].(string)
+		var visible []database.Chirp
+		for _, user := range []database.User{blocker, blocked, other} {
+			author := user.ID.String()
+			if !blocks(author, viewer) && !blocks(viewer, author) {
+				visible = append(visible, chirps[author])
+			}
+		}
+		return visible, nil
+	})
+	db.returns("GetChirpMentions", nil)
+	db.returns("GetViewerEngagement", nil)
+
+	request := func(method string, viewer database.User, chirpID uuid.UUID, body string) *http.Request {
+		r := httptest.NewRequest(method, "/", strings.NewReader(body))
+		r.SetPathValue("chirpID", chirpID.String())
+		r.Header = bearer(t, apiCfg, viewer)
+		return withUser(r, viewer.ID)
+	}
+
+	tests := []struct {
+		name     string
+		viewer   database.User
+		author   database.User
+		handler  func(*apiConfig) http.HandlerFunc
+		method   string
+		reply    bool
+		expected int
+	}{
+		{"blocked user reads the blocker's chirp", blocked, blocker, apiGetChirpByIdHandler, "GET", false, 404},
+		{"blocked user likes the blocker's chirp", blocked, blocker, apiLikeChirpHandler, "POST", false, 403},
+		{"blocked user rechirps the blocker's chirp", blocked, blocker, apiRechirpHandler, "POST", false, 403},
+		{"blocked user replies to the blocker", blocked, blocker, apiPostChirpsHandler, "POST", true, 403},
+		{"blocker reads the blocked user's chirp", blocker, blocked, apiGetChirpByIdHandler, "GET", false, 200},
+		{"blocker likes the blocked user's chirp", blocker, blocked, apiLikeChirpHandler, "POST", false, 403},
+		{"blocker replies to the blocked user", blocker, blocked, apiPostChirpsHandler, "POST", true, 403},
+		{"blocked user reads someone else's chirp", blocked, other, apiGetChirpByIdHandler, "GET", false, 200},
+	}
+
+	for _, test := range tests {
+		chirp := chirps[test.author.ID.String()]
+		body := ""
+		if test.reply {
+			body = `{"body": "hi", "parent_id": "` + chirp.ID.String() + `"}`
+		}
+		w := httptest.NewRecorder()
+		test.handler(apiCfg)(w, request(test.method, test.viewer, chirp.ID, body))
+
+		if w.Code != test.expected {
+			t.Errorf("%s: expected status %d, but got %d: %s", test.name, test.expected, w.Code, w.Body.String())
+		}
+	}
+	if len(db.calls("LikeChirp")) != 0 || len(db.calls("Rechirp")) != 0 || len(db.calls("CreateChirp")) != 0 {
+		t.Errorf("Expected nothing to be written across the block")
+	}
+
+	for _, viewer := range []database.User{blocker, blocked} {
+		r := httptest.NewRequest("GET", "/api/chirps", nil)
+		r.Header = bearer(t, apiCfg, viewer)
+		w := httptest.NewRecorder()
+		apiGetAllChirpsHandler(apiCfg)(w, r)
+
+		var response ChirpPageResponse
+		json.NewDecoder(w.Body).Decode(&response)
+		if len(response.Chirps) != 2 {
+			t.Errorf("Expected %v to see their own chirp and the unrelated one, but got %d chirps", viewer.ID, len(response.Chirps))
+		}
+		for _, chirp := range response.Chirps {
+			if chirp.UserID != viewer.ID && chirp.UserID != other.ID {
+				t.Errorf("Expected the chirp by %v to be filtered out for %v", chirp.UserID, viewer.ID)
+			}
+		}
+	}
+}
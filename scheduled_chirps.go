@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	scheduledChirpInterval = 15 * time.Second
+	// maxScheduleAhead is how far in the future a chirp may be scheduled.
+	maxScheduleAhead = 365 * 24 * time.Hour
+	// maxScheduledChirpAttempts is how many times a chirp that fails to
+	// publish is tried before it is marked as failed. The wait between
+	// attempts starts at scheduledChirpRetryDelay and doubles each time.
+	maxScheduledChirpAttempts = 5
+	scheduledChirpRetryDelay = time.Minute
+)
+
+const (
+	scheduledChirpPending = "pending"
+	scheduledChirpFailed = "failed"
+)
+
+func validatePublishAt(publishAt, now time.Time) error {
+	if !publishAt.After(now) {
+		return errors.New("publish_at must be in the future")
+	}
+	if publishAt.After(now.Add(maxScheduleAhead)) {
+		return errors.New("publish_at must be within a year")
+	}
+	return nil
+}
+
+// publishScheduledChirps posts every scheduled chirp that is due. Chirps are
+// checked again when they are published: the author's tier must still include
+// scheduling and allow the chirp's length, and the moderation rules at that
+// time apply. Those that no longer pass are marked as failed. Hourly chirp
+// limits do not apply, since a tier caps how many chirps can be waiting
+// instead.
+func publishScheduledChirps(ctx context.Context, apiCfg *apiConfig) {
+	for ctx.Err() == nil {
+		published, err := publishNextScheduledChirp(ctx, apiCfg)
+		if err != nil {
+			log.Printf("Error publishing scheduled chirp: %v", err)
+			return
+		}
+		if !published {
+			return
+		}
+	}
+}
+
+// publishNextScheduledChirp publishes the oldest due chirp, if any, and
+// reports whether there was one. Several servers can run it at once since due
+// chirps are claimed with SKIP LOCKED. A chirp that cannot be published because
+// of an error is put back to be tried later, so it does not hold up the rest.
+func publishNextScheduledChirp(ctx context.Context, apiCfg *apiConfig) (bool, error) {
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	scheduled, err := qtx.GetDueScheduledChirp(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	chirp, err := publishScheduledChirp(ctx, apiCfg, qtx, scheduled)
+	var rejection *chirpRejection
+	if errors.As(err, &rejection) {
+		err = qtx.MarkScheduledChirpFailed(ctx, database.MarkScheduledChirpFailedParams{
+			ID: scheduled.ID,
+			Error: sql.NullString{String: rejection.Message, Valid: true},
+		})
+		if err != nil {
+			return false, err
+		}
+		return true, tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Error publishing scheduled chirp %s: %v", scheduled.ID, err)
+		tx.Rollback()
+		return true, retryScheduledChirp(ctx, apiCfg, scheduled)
+	}
+
+	err = qtx.MarkScheduledChirpPublished(ctx, database.MarkScheduledChirpPublishedParams{
+		ID: scheduled.ID,
+		ChirpID: uuid.NullUUID{UUID: chirp.ID, Valid: true},
+	})
+	if err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// retryScheduledChirp puts a chirp that failed to publish back to be tried
+// again after a delay, or marks it as failed once it has used up its attempts.
+func retryScheduledChirp(ctx context.Context, apiCfg *apiConfig, scheduled database.ScheduledChirp) error {
+	tx, err := apiCfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	qtx := apiCfg.database.WithTx(tx)
+
+	attempts, err := qtx.RetryScheduledChirp(ctx, database.RetryScheduledChirpParams{
+		ID: scheduled.ID,
+		NextAttemptAt: time.Now().Add(scheduledChirpRetryDelay << scheduled.Attempts),
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Another server published it or gave up on it in the meantime.
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if attempts >= maxScheduledChirpAttempts {
+		err = qtx.MarkScheduledChirpFailed(ctx, database.MarkScheduledChirpFailedParams{
+			ID: scheduled.ID,
+			Error: sql.NullString{String: "The chirp could not be published", Valid: true},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func publishScheduledChirp(ctx context.Context, apiCfg *apiConfig, q *database.Queries, scheduled database.ScheduledChirp) (database.Chirp, error) {
+	user, err := q.GetUserById(ctx, scheduled.UserID)
+	if err != nil {
+		return database.Chirp{}, fmt.Errorf("fetching user: %w", err)
+	}
+	if user.SuspendedAt.Valid {
+		return database.Chirp{}, &chirpRejection{403, "Your account is suspended"}
+	}
+	limits := userLimits(apiCfg, user)
+	if !limits.ScheduleChirps {
+		return database.Chirp{}, &chirpRejection{403, "Your plan does not include scheduling chirps"}
+	}
+
+	moderated, err := cleanChirpBody(apiCfg.moderation, scheduled.Body, limits.MaxChirpLength)
+	if err != nil {
+		return database.Chirp{}, &chirpRejection{400, err.Error()}
+	}
+
+	newChirp := database.CreateChirpParams{UserID: user.ID, Body: moderated.Text}
+	if scheduled.ParentID.Valid {
+		_, err = validateChirpParent(ctx, apiCfg, user.ID, scheduled.ParentID.UUID)
+		if err != nil {
+			return database.Chirp{}, err
+		}
+		newChirp.ParentID = scheduled.ParentID
+	}
+
+	return insertChirp(ctx, q, newChirp, moderated)
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/entitlements"
+	"github.com/isotronic/http-go-server/internal/moderation"
+)
+
+func TestValidatePublishAt(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		publishAt time.Time
+		wantErr bool
+	}{
+		{"in an hour", now.Add(time.Hour), false},
+		{"in a year", now.Add(maxScheduleAhead), false},
+		{"now", now, true},
+		{"in the past", now.Add(-time.Minute), true},
+		{"too far ahead", now.Add(maxScheduleAhead + time.Second), true},
+		{"missing", time.Time{}, true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validatePublishAt(tc.publishAt, now)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("Expected error %v, but got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+// fakeScheduledChirps keeps the scheduled chirps of a fakeDB in memory,
+// following what the queries do.
+type fakeScheduledChirps struct {
+	rows []*database.ScheduledChirp
+}
+
+func newFakeScheduledChirps(db *fakeDB, rows ...*database.ScheduledChirp) *fakeScheduledChirps {
+	f := &fakeScheduledChirps{rows: rows}
+	find := func(id driver.Value) *database.ScheduledChirp {
+		for _, row := range f.rows {
+			if row.ID.String() == id.(string) {
+				return row
+			}
+		}
+		return nil
+	}
+
+	db.on("GetDueScheduledChirp", func(args []driver.Value) (any, error) {
+		var due *database.ScheduledChirp
+		for _, row := range f.rows {
+			if row.PublishedAt.Valid || row.FailedAt.Valid || row.NextAttemptAt.After(time.Now()) {
+				continue
+			}
+			if due == nil || row.NextAttemptAt.Before(due.NextAttemptAt) {
+				due = row
+			}
+		}
+		if due == nil {
+			return nil, nil
+		}
+		return *due, nil
+	})
+	db.on("RetryScheduledChirp", func(args []driver.Value) (any, error) {
+		row := find(args[0])
+		row.Attempts++
+		row.NextAttemptAt = args[1].(time.Time)
+		return int64(row.Attempts), nil
+	})
+	db.on("MarkScheduledChirpFailed", func(args []driver.Value) (any, error) {
+		row := find(args[0])
+		row.FailedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		row.Error = sql.NullString{String: args[1].(string), Valid: true}
+		return nil, nil
+	})
+	db.on("MarkScheduledChirpPublished", func(args []driver.Value) (any, error) {
+		row := find(args[0])
+		row.PublishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+		row.ChirpID = uuid.NullUUID{UUID: uuid.MustParse(args[1].(string)), Valid: true}
+		return nil, nil
+	})
+	db.on("CreateChirp", func(args []driver.Value) (any, error) {
+		return database.Chirp{ID: uuid.New(), UserID: uuid.MustParse(args[0].(string)), Body: args[1].(string)}, nil
+	})
+	db.returns("DeleteChirpHashtags", nil)
+	db.returns("DeleteChirpMentions", nil)
+	return f
+}
+
+func TestPublishScheduledChirpsRetriesFailures(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	broken := database.User{ID: uuid.New(), IsChirpyRed: true}
+	working := database.User{ID: uuid.New(), IsChirpyRed: true}
+	db.on("GetUserById", func(args []driver.Value) (any, error) {
+		if args[0].(string) == broken.ID.String() {
+			return nil, errors.New("connection reset")
+		}
+		return working, nil
+	})
+
+	due := time.Now().Add(-time.Minute)
+	failing := &database.ScheduledChirp{ID: uuid.New(), UserID: broken.ID, Body: "first", PublishAt: due, NextAttemptAt: due}
+	later := &database.ScheduledChirp{ID: uuid.New(), UserID: working.ID, Body: "second", PublishAt: due.Add(time.Second), NextAttemptAt: due.Add(time.Second)}
+	newFakeScheduledChirps(db, failing, later)
+
+	publishScheduledChirps(t.Context(), apiCfg)
+
+	if !later.PublishedAt.Valid {
+		t.Errorf("Expected the chirp after a failing one to be published")
+	}
+	if failing.Attempts != 1 || !failing.NextAttemptAt.After(time.Now()) || failing.FailedAt.Valid {
+		t.Errorf("Expected the failing chirp to be retried later, but got %d attempts, next at %v", failing.Attempts, failing.NextAttemptAt)
+	}
+
+	failing.Attempts = maxScheduledChirpAttempts - 1
+	failing.NextAttemptAt = due
+	publishScheduledChirps(t.Context(), apiCfg)
+
+	if !failing.FailedAt.Valid || failing.Error.String == "" {
+		t.Errorf("Expected the chirp to be marked as failed after %d attempts", maxScheduledChirpAttempts)
+	}
+}
+
+func TestPublishScheduledChirpRequiresSchedulingTier(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	downgraded := database.User{ID: uuid.New(), IsChirpyRed: false}
+	db.returns("GetUserById", downgraded)
+
+	due := time.Now().Add(-time.Minute)
+	scheduled := &database.ScheduledChirp{ID: uuid.New(), UserID: downgraded.ID, Body: "hello", PublishAt: due, NextAttemptAt: due}
+	newFakeScheduledChirps(db, scheduled)
+
+	publishScheduledChirps(t.Context(), apiCfg)
+
+	if !scheduled.FailedAt.Valid || !strings.Contains(scheduled.Error.String, "scheduling") {
+		t.Errorf("Expected the chirp to fail because the plan no longer includes scheduling, but got %q", scheduled.Error.String)
+	}
+	if len(db.calls("CreateChirp")) != 0 {
+		t.Errorf("Expected no chirp to be created")
+	}
+}
+
+func TestPostingLimitsCountWithUserLocked(t *testing.T) {
+	user := database.User{ID: uuid.New(), IsChirpyRed: true}
+	publishAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		handler func(*apiConfig) http.HandlerFunc
+		body    string
+		count   string
+		full    any
+		insert  string
+		code    int
+	}{
+		{"chirps per hour", apiPostChirpsHandler, `{"body": "hello"}`, "CountRecentChirps", database.CountRecentChirpsRow{Count: 200, Oldest: sql.NullTime{Time: time.Now(), Valid: true}}, "CreateChirp", 429},
+		{"scheduled chirps", apiCreateScheduledChirpHandler, `{"body": "hello", "publish_at": "` + publishAt + `"}`, "CountPendingScheduledChirps", int64(50), "CreateScheduledChirp", 403},
+	}
+
+	for _, test := range tests {
+		apiCfg, db := newTestAPIConfig(t)
+		db.returns("GetUserById", user)
+		var order []string
+		db.on("GetUserByIdForUpdate", func(args []driver.Value) (any, error) {
+			order = append(order, "lock")
+			return user, nil
+		})
+		db.on(test.count, func(args []driver.Value) (any, error) {
+			order = append(order, "count")
+			return test.full, nil
+		})
+
+		r := withUser(httptest.NewRequest("POST", "/", strings.NewReader(test.body)), user.ID)
+		w := httptest.NewRecorder()
+		test.handler(apiCfg)(w, r)
+
+		if w.Code != test.code {
+			t.Errorf("%s: expected status %d, but got %d: %s", test.name, test.code, w.Code, w.Body.String())
+		}
+		if strings.Join(order, ",") != "lock,count" {
+			t.Errorf("%s: expected the user to be locked before counting, but got %v", test.name, order)
+		}
+		if len(db.calls(test.insert)) != 0 || db.rollbacks != 1 {
+			t.Errorf("%s: expected the transaction to be rolled back without an insert", test.name)
+		}
+	}
+}
+
+// TestPostingLimitsUnderConcurrency checks that the caps hold when requests
+// race each other, which the fake database cannot show.
+func TestPostingLimitsUnderConcurrency(t *testing.T) {
+	db, q := newTestDatabase(t)
+	filter, err := moderation.NewFilter(nil)
+	if err != nil {
+		t.Fatalf("Expected no error creating the filter, but got %v", err)
+	}
+	limits := entitlements.Limits{MaxChirpLength: 140, ChirpsPerHour: 3, ScheduleChirps: true, MaxScheduledChirps: 3}
+	apiCfg := &apiConfig{db: db, database: q, moderation: filter, entitlements: entitlements.Tiers{Free: limits, Red: limits}}
+	user := createTestUser(t, q, "racer@example.com")
+	publishAt := time.Now().Add(time.Hour).Format(time.RFC3339)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		body    string
+	}{
+		{"chirps per hour", apiPostChirpsHandler(apiCfg), `{"body": "hello"}`},
+		{"scheduled chirps", apiCreateScheduledChirpHandler(apiCfg), `{"body": "hello", "publish_at": "` + publishAt + `"}`},
+	}
+
+	for _, test := range tests {
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		created := 0
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				r := withUser(httptest.NewRequest("POST", "/", strings.NewReader(test.body)), user.ID)
+				w := httptest.NewRecorder()
+				test.handler(w, r)
+				if w.Code == 201 {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+
+		if created != 3 {
+			t.Errorf("%s: expected 3 requests to succeed, but got %d", test.name, created)
+		}
+	}
+}
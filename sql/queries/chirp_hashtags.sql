@@ -0,0 +1,49 @@
+-- name: InsertChirpHashtags :exec
+INSERT INTO chirp_hashtags (chirp_id, tag, created_at)
+SELECT sqlc.arg('chirp_id'), unnest(sqlc.arg('tags')::text[]), sqlc.arg('created_at')
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpHashtags :exec
+DELETE FROM chirp_hashtags WHERE chirp_id = $1;
+
+-- name: GetHashtagChirpsAscending :many
+SELECT chirps.* FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.tag = sqlc.arg('tag')
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirp_hashtags.created_at, chirp_hashtags.chirp_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+  OR (user_blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = sqlc.narg('viewer_id')::uuid AND user_mutes.muted_id = chirps.user_id
+)
+ORDER BY chirp_hashtags.created_at, chirp_hashtags.chirp_id
+LIMIT sqlc.arg('limit');
+
+-- name: GetHashtagChirpsDescending :many
+SELECT chirps.* FROM chirp_hashtags
+JOIN chirps ON chirps.id = chirp_hashtags.chirp_id
+WHERE chirp_hashtags.tag = sqlc.arg('tag')
+AND chirps.deleted_at IS NULL
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (chirp_hashtags.created_at, chirp_hashtags.chirp_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+  OR (user_blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = sqlc.narg('viewer_id')::uuid AND user_mutes.muted_id = chirps.user_id
+)
+ORDER BY chirp_hashtags.created_at DESC, chirp_hashtags.chirp_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTrendingHashtags :many
+SELECT tag, COUNT(*) AS chirp_count
+FROM chirp_hashtags
+WHERE created_at >= sqlc.arg('since')
+GROUP BY tag
+ORDER BY chirp_count DESC, tag
+LIMIT sqlc.arg('limit');
\ No newline at end of file
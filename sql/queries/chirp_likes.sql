@@ -0,0 +1,19 @@
+-- name: LikeChirp :execrows
+-- The counter is only touched when the insert or delete actually changed a
+-- row, and both happen in one statement, so concurrent requests stay consistent.
+WITH inserted AS (
+  INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+  VALUES ($1, $2, NOW())
+  ON CONFLICT DO NOTHING
+  RETURNING chirp_id
+)
+UPDATE chirps SET like_count = like_count + 1
+WHERE id IN (SELECT chirp_id FROM inserted);
+
+-- name: UnlikeChirp :execrows
+WITH deleted AS (
+  DELETE FROM chirp_likes WHERE user_id = $1 AND chirp_id = $2
+  RETURNING chirp_id
+)
+UPDATE chirps SET like_count = like_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
\ No newline at end of file
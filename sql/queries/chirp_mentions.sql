@@ -0,0 +1,13 @@
+-- name: InsertChirpMentions :exec
+INSERT INTO chirp_mentions (chirp_id, user_id)
+SELECT sqlc.arg('chirp_id'), unnest(sqlc.arg('user_ids')::uuid[])
+ON CONFLICT DO NOTHING;
+
+-- name: DeleteChirpMentions :exec
+DELETE FROM chirp_mentions WHERE chirp_id = $1;
+
+-- name: GetChirpMentions :many
+SELECT chirp_mentions.chirp_id, users.id AS user_id, users.email
+FROM chirp_mentions
+JOIN users ON users.id = chirp_mentions.user_id
+WHERE chirp_mentions.chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
\ No newline at end of file
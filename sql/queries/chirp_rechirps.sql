@@ -0,0 +1,17 @@
+-- name: Rechirp :execrows
+WITH inserted AS (
+  INSERT INTO chirp_rechirps (user_id, chirp_id, created_at)
+  VALUES ($1, $2, NOW())
+  ON CONFLICT DO NOTHING
+  RETURNING chirp_id
+)
+UPDATE chirps SET rechirp_count = rechirp_count + 1
+WHERE id IN (SELECT chirp_id FROM inserted);
+
+-- name: UndoRechirp :execrows
+WITH deleted AS (
+  DELETE FROM chirp_rechirps WHERE user_id = $1 AND chirp_id = $2
+  RETURNING chirp_id
+)
+UPDATE chirps SET rechirp_count = rechirp_count - 1
+WHERE id IN (SELECT chirp_id FROM deleted);
\ No newline at end of file
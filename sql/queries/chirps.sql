@@ -1,17 +1,129 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, user_id, body)
-VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2)
+INSERT INTO chirps (id, created_at, updated_at, user_id, body, parent_id)
+VALUES (gen_random_uuid(), NOW(), NOW(), $1, $2, $3)
 RETURNING *;
 
--- name: GetAllChirps :many
-SELECT * FROM chirps ORDER BY created_at;
+-- name: GetChirpsAscending :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+  OR (user_blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = sqlc.narg('viewer_id')::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
 
--- name: GetChirpsByUserId :many
-SELECT * FROM chirps WHERE user_id = $1 ORDER BY created_at;
+-- name: GetChirpsDescending :many
+SELECT * FROM chirps
+WHERE deleted_at IS NULL
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+  OR (user_blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = sqlc.narg('viewer_id')::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps), ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE deleted_at IS NULL
+AND to_tsvector('english', body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+AND (sqlc.narg('cursor_rank')::real IS NULL OR (ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.arg('query')))::real, created_at, id) < (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+  OR (user_blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = sqlc.narg('viewer_id')::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY rank DESC, created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirpsBackward :many
+SELECT sqlc.embed(chirps), ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE deleted_at IS NULL
+AND to_tsvector('english', body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('since')::timestamp IS NULL OR created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR created_at < sqlc.narg('until'))
+AND (sqlc.narg('cursor_rank')::real IS NULL OR (ts_rank(to_tsvector('english', body), websearch_to_tsquery('english', sqlc.arg('query')))::real, created_at, id) > (sqlc.narg('cursor_rank')::real, sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (user_blocks.blocker_id = chirps.user_id AND user_blocks.blocked_id = sqlc.narg('viewer_id')::uuid)
+  OR (user_blocks.blocker_id = sqlc.narg('viewer_id')::uuid AND user_blocks.blocked_id = chirps.user_id)
+)
+AND (sqlc.narg('author_id')::uuid IS NOT NULL OR NOT EXISTS (
+  SELECT 1 FROM user_mutes WHERE user_mutes.muter_id = sqlc.narg('viewer_id')::uuid AND user_mutes.muted_id = chirps.user_id
+))
+ORDER BY rank ASC, created_at ASC, id ASC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirpById :one
 SELECT * FROM chirps WHERE id = $1;
 
+-- name: GetChirpByIdForUpdate :one
+SELECT * FROM chirps WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW(), edited_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ChirpHasReplies :one
+SELECT EXISTS (SELECT 1 FROM chirps WHERE parent_id = $1);
+
+-- name: TombstoneChirp :one
+UPDATE chirps
+SET body = '', updated_at = NOW(), deleted_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+  SELECT *, 0 AS depth FROM chirps WHERE chirps.id = $1
+  UNION ALL
+  SELECT c.*, a.depth + 1 FROM chirps c JOIN ancestors a ON c.id = a.parent_id
+)
+SELECT * FROM ancestors WHERE depth > 0 ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+  SELECT *, 0 AS depth FROM chirps WHERE chirps.id = sqlc.arg('id')
+  UNION ALL
+  SELECT c.*, d.depth + 1 FROM chirps c JOIN descendants d ON c.parent_id = d.id
+)
+SELECT * FROM descendants ORDER BY depth, created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: GetViewerEngagement :many
+SELECT chirps.id AS chirp_id,
+  EXISTS (SELECT 1 FROM chirp_likes WHERE chirp_likes.chirp_id = chirps.id AND chirp_likes.user_id = sqlc.arg('user_id')) AS liked,
+  EXISTS (SELECT 1 FROM chirp_rechirps WHERE chirp_rechirps.chirp_id = chirps.id AND chirp_rechirps.user_id = sqlc.arg('user_id')) AS rechirped
+FROM chirps
+WHERE chirps.id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: CountRecentChirps :one
+SELECT COUNT(*) AS count, MIN(created_at)::timestamp AS oldest FROM chirps
+WHERE user_id = $1 AND created_at > $2;
+
 -- name: DeleteChirpById :exec
 DELETE FROM chirps WHERE id = $1;
 
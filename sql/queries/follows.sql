@@ -0,0 +1,82 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: DeleteFollowsBetween :exec
+DELETE FROM follows
+WHERE (follower_id = $1 AND followee_id = $2)
+OR (follower_id = $2 AND followee_id = $1);
+
+-- name: GetFollowersAscending :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, follower_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, follower_id
+LIMIT sqlc.arg('limit');
+
+-- name: GetFollowersDescending :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, follower_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetFollowingAscending :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, followee_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, followee_id
+LIMIT sqlc.arg('limit');
+
+-- name: GetFollowingDescending :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, followee_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetTimelineAscending :many
+SELECT c.* FROM follows f
+CROSS JOIN LATERAL (
+  SELECT * FROM chirps
+  WHERE chirps.user_id = f.followee_id
+  AND chirps.deleted_at IS NULL
+  AND (chirps.created_at, chirps.id) > (COALESCE(sqlc.narg('cursor_created_at')::timestamp, '-infinity'), COALESCE(sqlc.narg('cursor_id')::uuid, '00000000-0000-0000-0000-000000000000'))
+  ORDER BY chirps.created_at, chirps.id
+  LIMIT sqlc.arg('limit')
+) c
+WHERE f.follower_id = sqlc.arg('user_id')
+AND NOT EXISTS (SELECT 1 FROM user_mutes WHERE muter_id = sqlc.arg('user_id') AND muted_id = f.followee_id)
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (blocker_id = f.followee_id AND blocked_id = sqlc.arg('user_id'))
+  OR (blocker_id = sqlc.arg('user_id') AND blocked_id = f.followee_id)
+)
+ORDER BY c.created_at, c.id
+LIMIT sqlc.arg('limit');
+
+-- name: GetTimelineDescending :many
+-- Each followed account is probed through chirps (user_id, created_at, id),
+-- so the cost is bounded by followees * limit rather than their full history.
+SELECT c.* FROM follows f
+CROSS JOIN LATERAL (
+  SELECT * FROM chirps
+  WHERE chirps.user_id = f.followee_id
+  AND chirps.deleted_at IS NULL
+  AND (chirps.created_at, chirps.id) < (COALESCE(sqlc.narg('cursor_created_at')::timestamp, 'infinity'), COALESCE(sqlc.narg('cursor_id')::uuid, 'ffffffff-ffff-ffff-ffff-ffffffffffff'))
+  ORDER BY chirps.created_at DESC, chirps.id DESC
+  LIMIT sqlc.arg('limit')
+) c
+WHERE f.follower_id = sqlc.arg('user_id')
+AND NOT EXISTS (SELECT 1 FROM user_mutes WHERE muter_id = sqlc.arg('user_id') AND muted_id = f.followee_id)
+AND NOT EXISTS (
+  SELECT 1 FROM user_blocks
+  WHERE (blocker_id = f.followee_id AND blocked_id = sqlc.arg('user_id'))
+  OR (blocker_id = sqlc.arg('user_id') AND blocked_id = f.followee_id)
+)
+ORDER BY c.created_at DESC, c.id DESC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- name: GetLoginFailure :one
+SELECT * FROM login_failures WHERE kind = $1 AND subject = $2;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_failures (kind, subject, failures, last_failed_at)
+VALUES ($1, $2, 1, NOW())
+ON CONFLICT (kind, subject) DO UPDATE
+SET failures = CASE WHEN login_failures.last_failed_at < sqlc.arg('reset_before') THEN 1 ELSE login_failures.failures + 1 END,
+  last_failed_at = NOW()
+RETURNING *;
+
+-- name: SetLoginLockout :exec
+UPDATE login_failures
+SET locked_until = $3
+WHERE kind = $1 AND subject = $2;
+
+-- name: ClearLoginFailures :execrows
+DELETE FROM login_failures WHERE kind = $1 AND subject = $2;
\ No newline at end of file
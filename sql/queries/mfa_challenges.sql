@@ -0,0 +1,15 @@
+-- name: CreateMfaChallenge :exec
+INSERT INTO mfa_challenges (token_hash, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, NOW());
+
+-- name: GetMfaChallengeForUpdate :one
+SELECT * FROM mfa_challenges WHERE token_hash = $1 FOR UPDATE;
+
+-- name: IncrementMfaChallengeAttempts :exec
+UPDATE mfa_challenges SET attempts = attempts + 1 WHERE token_hash = $1;
+
+-- name: UseMfaChallenge :exec
+UPDATE mfa_challenges SET used_at = NOW() WHERE token_hash = $1;
+
+-- name: DeleteExpiredMfaChallenges :execrows
+DELETE FROM mfa_challenges WHERE expires_at < $1;
\ No newline at end of file
@@ -0,0 +1,16 @@
+-- name: CreateModerationAction :one
+INSERT INTO moderation_actions (id, action, report_id, chirp_id, user_id, note, actor_id, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, $6, NOW())
+RETURNING *;
+
+-- name: GetModerationActionsAscending :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: GetModerationActionsDescending :many
+SELECT * FROM moderation_actions
+WHERE (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
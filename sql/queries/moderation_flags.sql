@@ -0,0 +1,14 @@
+-- name: CreateModerationFlag :exec
+INSERT INTO moderation_flags (id, chirp_id, reason, created_at)
+VALUES (gen_random_uuid(), $1, $2, NOW());
+
+-- name: GetOpenModerationFlags :many
+SELECT sqlc.embed(moderation_flags), chirps.user_id, chirps.body
+FROM moderation_flags
+JOIN chirps ON chirps.id = moderation_flags.chirp_id
+WHERE moderation_flags.resolved_at IS NULL
+ORDER BY moderation_flags.created_at, moderation_flags.id;
+
+-- name: ResolveModerationFlag :execrows
+UPDATE moderation_flags SET resolved_at = NOW()
+WHERE id = $1 AND resolved_at IS NULL;
\ No newline at end of file
@@ -0,0 +1,11 @@
+-- name: UpsertModerationRule :one
+INSERT INTO moderation_rules (id, pattern, match_type, action, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW())
+ON CONFLICT (pattern, match_type) DO UPDATE SET action = EXCLUDED.action
+RETURNING *;
+
+-- name: GetModerationRules :many
+SELECT * FROM moderation_rules ORDER BY created_at, id;
+
+-- name: DeleteModerationRule :execrows
+DELETE FROM moderation_rules WHERE id = $1;
\ No newline at end of file
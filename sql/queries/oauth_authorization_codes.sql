@@ -0,0 +1,11 @@
+-- name: CreateOauthAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, redirect_uri, scopes, code_challenge, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, NOW());
+
+-- name: GetOauthAuthorizationCodeForUpdate :one
+SELECT * FROM oauth_authorization_codes WHERE code_hash = $1 FOR UPDATE;
+
+-- name: UseOauthAuthorizationCode :exec
+UPDATE oauth_authorization_codes
+SET used_at = NOW(), grant_id = $2
+WHERE code_hash = $1;
\ No newline at end of file
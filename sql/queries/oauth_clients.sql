@@ -0,0 +1,15 @@
+-- name: CreateOauthClient :one
+INSERT INTO oauth_clients (id, owner_id, name, secret_hash, redirect_uris, scopes, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW())
+RETURNING *;
+
+-- name: GetOauthClient :one
+SELECT * FROM oauth_clients WHERE id = $1;
+
+-- name: GetOauthClientsByOwner :many
+SELECT * FROM oauth_clients
+WHERE owner_id = $1
+ORDER BY created_at DESC;
+
+-- name: DeleteOauthClient :execrows
+DELETE FROM oauth_clients WHERE id = $1 AND owner_id = $2;
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- name: CreateOauthGrant :one
+INSERT INTO oauth_grants (id, client_id, user_id, scopes, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, NOW())
+RETURNING *;
+
+-- name: GetOauthGrant :one
+SELECT * FROM oauth_grants WHERE id = $1;
+
+-- name: GetActiveOauthGrantsByUser :many
+SELECT sqlc.embed(oauth_grants), oauth_clients.name AS client_name
+FROM oauth_grants
+JOIN oauth_clients ON oauth_clients.id = oauth_grants.client_id
+WHERE oauth_grants.user_id = $1 AND oauth_grants.revoked_at IS NULL
+ORDER BY oauth_grants.created_at DESC;
+
+-- name: RevokeOauthGrant :execrows
+UPDATE oauth_grants
+SET revoked_at = NOW()
+WHERE id = $1 AND revoked_at IS NULL;
+
+-- name: RevokeUserOauthGrant :execrows
+UPDATE oauth_grants
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
\ No newline at end of file
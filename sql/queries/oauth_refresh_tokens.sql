@@ -0,0 +1,9 @@
+-- name: CreateOauthRefreshToken :exec
+INSERT INTO oauth_refresh_tokens (token_hash, grant_id, expires_at, created_at)
+VALUES ($1, $2, $3, NOW());
+
+-- name: GetOauthRefreshTokenForUpdate :one
+SELECT * FROM oauth_refresh_tokens WHERE token_hash = $1 FOR UPDATE;
+
+-- name: UseOauthRefreshToken :exec
+UPDATE oauth_refresh_tokens SET used_at = NOW() WHERE token_hash = $1;
\ No newline at end of file
@@ -0,0 +1,9 @@
+-- name: CreateOidcLoginState :exec
+INSERT INTO oidc_login_states (state_hash, nonce, code_verifier, expires_at, created_at)
+VALUES ($1, $2, $3, $4, NOW());
+
+-- name: GetOidcLoginStateForUpdate :one
+SELECT * FROM oidc_login_states WHERE state_hash = $1 FOR UPDATE;
+
+-- name: UseOidcLoginState :exec
+UPDATE oidc_login_states SET used_at = NOW() WHERE state_hash = $1;
\ No newline at end of file
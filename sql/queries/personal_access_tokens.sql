@@ -0,0 +1,27 @@
+-- name: CreatePersonalAccessToken :one
+INSERT INTO personal_access_tokens (id, user_id, name, token_hash, scopes, expires_at, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $5, NOW())
+RETURNING *;
+
+-- name: GetPersonalAccessTokenByHash :one
+SELECT * FROM personal_access_tokens WHERE token_hash = $1;
+
+-- name: GetPersonalAccessTokens :many
+SELECT * FROM personal_access_tokens
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: RevokePersonalAccessToken :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserPersonalAccessTokens :execrows
+UPDATE personal_access_tokens
+SET revoked_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
+-- name: TouchPersonalAccessToken :exec
+UPDATE personal_access_tokens
+SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
\ No newline at end of file
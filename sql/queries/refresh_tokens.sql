@@ -1,9 +1,12 @@
 -- name: GetUserFromRefreshToken :one
 SELECT * FROM refresh_tokens WHERE token = $1;
 
+-- name: GetRefreshTokenForUpdate :one
+SELECT * FROM refresh_tokens WHERE token = $1 FOR UPDATE;
+
 -- name: InsertRefreshToken :one
-INSERT INTO refresh_tokens (token, user_id, expires_at)
-VALUES ($1, $2, $3)
+INSERT INTO refresh_tokens (token, user_id, expires_at, family_id, user_agent, ip_address, last_used_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
 RETURNING *;
 
 -- name: RevokeRefreshToken :one
@@ -12,5 +15,40 @@ SET revoked_at = NOW(), updated_at = NOW()
 WHERE token = $1
 RETURNING *;
 
+-- name: RotateRefreshToken :one
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW(), replaced_by = $2
+WHERE token = $1
+RETURNING *;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE family_id = $1 AND revoked_at IS NULL;
+
+-- name: GetActiveSessions :many
+SELECT refresh_tokens.family_id, refresh_tokens.user_agent, refresh_tokens.ip_address, refresh_tokens.last_used_at, refresh_tokens.expires_at,
+  (SELECT MIN(family.created_at) FROM refresh_tokens family WHERE family.family_id = refresh_tokens.family_id)::timestamptz AS started_at
+FROM refresh_tokens
+WHERE refresh_tokens.user_id = $1
+AND refresh_tokens.revoked_at IS NULL
+AND refresh_tokens.expires_at > NOW()
+ORDER BY refresh_tokens.last_used_at DESC;
+
+-- name: RevokeUserRefreshTokenFamily :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeOtherRefreshTokenFamilies :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND family_id <> $2 AND revoked_at IS NULL;
+
+-- name: RevokeAllUserRefreshTokens :execrows
+UPDATE refresh_tokens
+SET revoked_at = NOW(), updated_at = NOW()
+WHERE user_id = $1 AND revoked_at IS NULL;
+
 -- name: ResetRefreshTokens :exec
 DELETE FROM refresh_tokens;
\ No newline at end of file
@@ -0,0 +1,20 @@
+-- name: CreateReport :one
+INSERT INTO reports (id, reporter_id, reported_user_id, chirp_id, reason, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: GetOpenReports :many
+SELECT sqlc.embed(reports), chirps.body AS chirp_body
+FROM reports
+LEFT JOIN chirps ON chirps.id = reports.chirp_id
+WHERE reports.resolved_at IS NULL
+ORDER BY reports.created_at, reports.id;
+
+-- name: GetReportByIdForUpdate :one
+SELECT * FROM reports WHERE id = $1 FOR UPDATE;
+
+-- name: ResolveReport :one
+UPDATE reports
+SET resolved_at = NOW(), resolution = $2
+WHERE id = $1
+RETURNING *;
\ No newline at end of file
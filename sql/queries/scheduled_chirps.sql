@@ -0,0 +1,35 @@
+-- name: CreateScheduledChirp :one
+INSERT INTO scheduled_chirps (id, user_id, body, parent_id, publish_at, next_attempt_at, created_at)
+VALUES (gen_random_uuid(), $1, $2, $3, $4, $4, NOW())
+RETURNING *;
+
+-- name: GetScheduledChirps :many
+SELECT * FROM scheduled_chirps
+WHERE user_id = $1 AND published_at IS NULL
+ORDER BY publish_at, id;
+
+-- name: CountPendingScheduledChirps :one
+SELECT COUNT(*) FROM scheduled_chirps
+WHERE user_id = $1 AND published_at IS NULL AND failed_at IS NULL;
+
+-- name: DeleteScheduledChirp :execrows
+DELETE FROM scheduled_chirps
+WHERE id = $1 AND user_id = $2 AND published_at IS NULL;
+
+-- name: GetDueScheduledChirp :one
+SELECT * FROM scheduled_chirps
+WHERE published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= NOW()
+ORDER BY next_attempt_at, id
+LIMIT 1
+FOR UPDATE SKIP LOCKED;
+
+-- name: MarkScheduledChirpPublished :exec
+UPDATE scheduled_chirps SET chirp_id = $2, published_at = NOW() WHERE id = $1;
+
+-- name: RetryScheduledChirp :one
+UPDATE scheduled_chirps SET attempts = attempts + 1, next_attempt_at = $2
+WHERE id = $1 AND published_at IS NULL AND failed_at IS NULL
+RETURNING attempts;
+
+-- name: MarkScheduledChirpFailed :exec
+UPDATE scheduled_chirps SET error = $2, failed_at = NOW() WHERE id = $1;
\ No newline at end of file
@@ -0,0 +1,40 @@
+-- name: GetSubscription :one
+SELECT * FROM subscriptions WHERE user_id = $1;
+
+-- name: GetSubscriptionForUpdate :one
+SELECT * FROM subscriptions WHERE user_id = $1 FOR UPDATE;
+
+-- name: ActivateSubscription :one
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, created_at, updated_at)
+VALUES (sqlc.arg('user_id'), COALESCE(sqlc.narg('plan'), 'red'), 'active', sqlc.arg('current_period_end'), NOW(), NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET plan = COALESCE(sqlc.narg('plan'), subscriptions.plan),
+  status = 'active',
+  current_period_end = GREATEST(subscriptions.current_period_end, EXCLUDED.current_period_end),
+  canceled_at = NULL,
+  updated_at = NOW()
+RETURNING *;
+
+-- name: SetSubscriptionStatus :exec
+UPDATE subscriptions
+SET status = $2, canceled_at = $3, updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: EndSubscription :exec
+UPDATE subscriptions
+SET status = 'expired', ended_period_end = current_period_end,
+  current_period_end = LEAST(current_period_end, NOW()), updated_at = NOW()
+WHERE user_id = $1;
+
+-- name: ExpireSubscriptions :many
+WITH expired AS (
+  UPDATE subscriptions
+  SET status = 'expired', updated_at = NOW()
+  WHERE status <> 'expired' AND current_period_end <= NOW()
+  RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = FALSE, updated_at = NOW()
+FROM expired
+WHERE users.id = expired.user_id
+RETURNING users.id;
\ No newline at end of file
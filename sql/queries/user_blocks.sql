@@ -0,0 +1,37 @@
+-- name: BlockUser :exec
+INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnblockUser :exec
+DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2;
+
+-- name: GetBlocksAscending :many
+SELECT * FROM user_blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, blocked_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, blocked_id
+LIMIT sqlc.arg('limit');
+
+-- name: GetBlocksDescending :many
+SELECT * FROM user_blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, blocked_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, blocked_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: GetUserRelationship :one
+SELECT
+  EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = sqlc.arg('author_id') AND blocked_id = sqlc.arg('viewer_id')) AS blocked_by_author,
+  EXISTS (SELECT 1 FROM user_blocks WHERE blocker_id = sqlc.arg('viewer_id') AND blocked_id = sqlc.arg('author_id')) AS blocks_author,
+  EXISTS (SELECT 1 FROM user_mutes WHERE muter_id = sqlc.arg('viewer_id') AND muted_id = sqlc.arg('author_id')) AS mutes_author;
+
+-- name: GetBlockedAuthorsAmong :many
+-- Returns the authors the user has blocked or been blocked by.
+SELECT blocker_id AS author_id FROM user_blocks
+WHERE blocked_id = sqlc.arg('user_id')
+AND blocker_id = ANY(sqlc.arg('author_ids')::uuid[])
+UNION
+SELECT blocked_id FROM user_blocks
+WHERE blocker_id = sqlc.arg('user_id')
+AND blocked_id = ANY(sqlc.arg('author_ids')::uuid[]);
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities WHERE provider = $1 AND subject = $2;
+
+-- name: CreateUserIdentity :execrows
+INSERT INTO user_identities (provider, subject, user_id, email, created_at, last_login_at)
+VALUES ($1, $2, $3, $4, NOW(), NOW())
+ON CONFLICT (provider, subject) DO NOTHING;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities
+SET email = $3, last_login_at = NOW()
+WHERE provider = $1 AND subject = $2;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- name: MuteUser :exec
+INSERT INTO user_mutes (muter_id, muted_id, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT DO NOTHING;
+
+-- name: UnmuteUser :exec
+DELETE FROM user_mutes WHERE muter_id = $1 AND muted_id = $2;
+
+-- name: GetMutesAscending :many
+SELECT * FROM user_mutes
+WHERE muter_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, muted_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, muted_id
+LIMIT sqlc.arg('limit');
+
+-- name: GetMutesDescending :many
+SELECT * FROM user_mutes
+WHERE muter_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL OR (created_at, muted_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, muted_id DESC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
@@ -0,0 +1,14 @@
+-- name: CreateUserToken :exec
+INSERT INTO user_tokens (token_hash, user_id, purpose, email, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, NOW());
+
+-- name: GetUserTokenForUpdate :one
+SELECT * FROM user_tokens WHERE token_hash = $1 FOR UPDATE;
+
+-- name: UseUserToken :exec
+UPDATE user_tokens SET used_at = NOW() WHERE token_hash = $1;
+
+-- name: InvalidateUserTokens :exec
+UPDATE user_tokens
+SET used_at = NOW()
+WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL;
\ No newline at end of file
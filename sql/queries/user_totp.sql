@@ -0,0 +1,23 @@
+-- name: UpsertUserTotp :one
+INSERT INTO user_totp (user_id, secret, created_at)
+VALUES ($1, $2, NOW())
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, created_at = NOW()
+WHERE user_totp.confirmed_at IS NULL
+RETURNING *;
+
+-- name: GetUserTotp :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- name: ConfirmUserTotp :execrows
+UPDATE user_totp
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseTotpStep :execrows
+UPDATE user_totp
+SET last_used_step = $2
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserTotp :execrows
+DELETE FROM user_totp WHERE user_id = $1;
\ No newline at end of file
@@ -5,18 +5,66 @@ RETURNING *;
 
 -- name: UpdateUser :one
 UPDATE users
-SET updated_at = NOW(), email = $2, hashed_password = $3
+SET updated_at = NOW(), email = $2, hashed_password = $3,
+  email_verified_at = CASE WHEN email = $2 THEN email_verified_at END
 WHERE id = $1
 RETURNING *;
 
+-- name: GetUserById :one
+SELECT * FROM users WHERE id = $1;
+
+-- name: GetUserByIdForUpdate :one
+-- GetUserByIdForUpdate locks the user so checks of their limits and the
+-- insert they guard are not interleaved with another request.
+SELECT * FROM users WHERE id = $1 FOR UPDATE;
+
 -- name: GetUserByEmail :one
 SELECT * FROM users WHERE email = $1;
 
--- name: UpgradeUserToChirpyRed :one
+-- name: GetUserByEmailInsensitive :one
+-- GetUserByEmailInsensitive prefers an exact match when addresses differ only
+-- in case.
+SELECT * FROM users
+WHERE lower(email) = lower(sqlc.arg('email'))
+ORDER BY email = sqlc.arg('email') DESC, created_at
+LIMIT 1;
+
+-- name: GetUserIdsByEmails :many
+SELECT id, email FROM users WHERE lower(email) = ANY(sqlc.arg('emails')::text[]);
+
+-- name: SetUserChirpyRed :one
+UPDATE users
+SET is_chirpy_red = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: SuspendUser :one
+UPDATE users
+SET suspended_at = COALESCE(suspended_at, NOW()), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UnsuspendUser :one
 UPDATE users
-SET is_chirpy_red = TRUE
+SET suspended_at = NULL, updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
 -- name: ResetUsers :exec
-DELETE FROM users;
\ No newline at end of file
+DELETE FROM users;
+
+-- name: SetUserRole :one
+UPDATE users
+SET role = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: UpdateUserPassword :exec
+UPDATE users
+SET hashed_password = $2, updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkEmailVerified :execrows
+UPDATE users
+SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2;
\ No newline at end of file
@@ -0,0 +1,24 @@
+-- name: ReceiveWebhookEvent :one
+INSERT INTO webhook_events (id, event_id, event, payload, status, attempts, received_at)
+VALUES (gen_random_uuid(), $1, $2, $3, 'pending', 1, NOW())
+ON CONFLICT (event_id) DO UPDATE SET attempts = webhook_events.attempts + 1
+RETURNING *;
+
+-- name: FinishWebhookEvent :exec
+UPDATE webhook_events
+SET status = $2, error = $3, processed_at = NOW()
+WHERE id = $1;
+
+-- name: GetWebhookEventsAscending :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg('cursor_received_at')::timestamp IS NULL OR (received_at, id) > (sqlc.narg('cursor_received_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+ORDER BY received_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: GetWebhookEventsDescending :many
+SELECT * FROM webhook_events
+WHERE (sqlc.narg('cursor_received_at')::timestamp IS NULL OR (received_at, id) < (sqlc.narg('cursor_received_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+AND (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status'))
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg('limit');
\ No newline at end of file
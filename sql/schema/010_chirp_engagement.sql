@@ -0,0 +1,26 @@
+-- +goose Up
+ALTER TABLE chirps ADD COLUMN like_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE chirps ADD COLUMN rechirp_count INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE chirp_likes (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE TABLE chirp_rechirps (
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX chirp_likes_chirp_id_idx ON chirp_likes (chirp_id);
+CREATE INDEX chirp_rechirps_chirp_id_idx ON chirp_rechirps (chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_rechirps;
+DROP TABLE chirp_likes;
+ALTER TABLE chirps DROP COLUMN rechirp_count;
+ALTER TABLE chirps DROP COLUMN like_count;
\ No newline at end of file
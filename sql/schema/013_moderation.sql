@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE moderation_rules (
+  id UUID PRIMARY KEY,
+  pattern TEXT NOT NULL,
+  match_type TEXT NOT NULL CHECK (match_type IN ('word', 'substring')),
+  action TEXT NOT NULL CHECK (action IN ('mask', 'flag', 'reject')),
+  created_at TIMESTAMP NOT NULL,
+  UNIQUE (pattern, match_type)
+);
+
+CREATE TABLE moderation_flags (
+  id UUID PRIMARY KEY,
+  chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+  reason TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  resolved_at TIMESTAMP
+);
+
+CREATE INDEX moderation_flags_open_idx ON moderation_flags (created_at) WHERE resolved_at IS NULL;
+
+-- +goose Down
+DROP TABLE moderation_flags;
+DROP TABLE moderation_rules;
\ No newline at end of file
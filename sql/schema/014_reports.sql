@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN suspended_at TIMESTAMP;
+
+CREATE TABLE reports (
+  id UUID PRIMARY KEY,
+  reporter_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  reported_user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+  reason TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  resolved_at TIMESTAMP,
+  resolution TEXT CHECK (resolution IN ('dismiss', 'delete_chirp', 'suspend_user'))
+);
+
+CREATE INDEX reports_open_idx ON reports (created_at, id) WHERE resolved_at IS NULL;
+
+CREATE TABLE moderation_actions (
+  id UUID PRIMARY KEY,
+  action TEXT NOT NULL,
+  report_id UUID REFERENCES reports(id) ON DELETE SET NULL,
+  chirp_id UUID,
+  user_id UUID,
+  note TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX moderation_actions_created_at_idx ON moderation_actions (created_at, id);
+
+-- +goose Down
+DROP TABLE moderation_actions;
+DROP TABLE reports;
+ALTER TABLE users DROP COLUMN suspended_at;
\ No newline at end of file
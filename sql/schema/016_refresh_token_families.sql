@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE refresh_tokens ADD COLUMN family_id UUID;
+UPDATE refresh_tokens SET family_id = gen_random_uuid();
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ADD COLUMN replaced_by TEXT;
+
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN replaced_by;
+ALTER TABLE refresh_tokens DROP COLUMN family_id;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN ip_address TEXT NOT NULL DEFAULT '';
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+CREATE INDEX refresh_tokens_user_id_idx ON refresh_tokens (user_id) WHERE revoked_at IS NULL;
+
+-- +goose Down
+DROP INDEX refresh_tokens_user_id_idx;
+ALTER TABLE refresh_tokens DROP COLUMN last_used_at;
+ALTER TABLE refresh_tokens DROP COLUMN ip_address;
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
\ No newline at end of file
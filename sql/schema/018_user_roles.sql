@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user' CHECK (role IN ('user', 'admin'));
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN role;
\ No newline at end of file
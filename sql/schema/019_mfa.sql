@@ -0,0 +1,31 @@
+-- +goose Up
+CREATE TABLE user_totp (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  secret TEXT NOT NULL,
+  confirmed_at TIMESTAMP,
+  last_used_step BIGINT NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE recovery_codes (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  code_hash TEXT NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE mfa_challenges (
+  token_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  attempts INTEGER NOT NULL DEFAULT 0,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE mfa_challenges;
+DROP TABLE recovery_codes;
+DROP TABLE user_totp;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+CREATE TABLE user_tokens (
+  token_hash TEXT PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  purpose TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+  email TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX user_tokens_user_id_idx ON user_tokens (user_id, purpose) WHERE used_at IS NULL;
+
+-- +goose Down
+DROP TABLE user_tokens;
+ALTER TABLE users DROP COLUMN email_verified_at;
\ No newline at end of file
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE login_failures (
+  kind TEXT NOT NULL CHECK (kind IN ('account', 'ip')),
+  subject TEXT NOT NULL,
+  failures INTEGER NOT NULL,
+  last_failed_at TIMESTAMP NOT NULL,
+  locked_until TIMESTAMP,
+  PRIMARY KEY (kind, subject)
+);
+
+-- +goose Down
+DROP TABLE login_failures;
\ No newline at end of file
@@ -0,0 +1,48 @@
+-- +goose Up
+CREATE TABLE oauth_clients (
+  id UUID PRIMARY KEY,
+  owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  name TEXT NOT NULL,
+  secret_hash TEXT,
+  redirect_uris TEXT[] NOT NULL,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE oauth_grants (
+  id UUID PRIMARY KEY,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  scopes TEXT[] NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  revoked_at TIMESTAMP
+);
+
+CREATE INDEX oauth_grants_user_id_idx ON oauth_grants (user_id) WHERE revoked_at IS NULL;
+
+CREATE TABLE oauth_authorization_codes (
+  code_hash TEXT PRIMARY KEY,
+  client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  redirect_uri TEXT NOT NULL,
+  scopes TEXT[] NOT NULL,
+  code_challenge TEXT NOT NULL,
+  grant_id UUID REFERENCES oauth_grants(id) ON DELETE SET NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE oauth_refresh_tokens (
+  token_hash TEXT PRIMARY KEY,
+  grant_id UUID NOT NULL REFERENCES oauth_grants(id) ON DELETE CASCADE,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+-- +goose Down
+DROP TABLE oauth_refresh_tokens;
+DROP TABLE oauth_authorization_codes;
+DROP TABLE oauth_grants;
+DROP TABLE oauth_clients;
\ No newline at end of file
@@ -0,0 +1,25 @@
+-- +goose Up
+CREATE TABLE oidc_login_states (
+  state_hash TEXT PRIMARY KEY,
+  nonce TEXT NOT NULL,
+  code_verifier TEXT NOT NULL,
+  expires_at TIMESTAMP NOT NULL,
+  used_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE user_identities (
+  provider TEXT NOT NULL,
+  subject TEXT NOT NULL,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  email TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL,
+  last_login_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX user_identities_user_id_idx ON user_identities (user_id);
+
+-- +goose Down
+DROP TABLE user_identities;
+DROP TABLE oidc_login_states;
\ No newline at end of file
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE webhook_events (
+  id UUID PRIMARY KEY,
+  event_id TEXT NOT NULL UNIQUE,
+  event TEXT NOT NULL,
+  payload JSONB NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('pending', 'processed', 'ignored', 'failed')),
+  error TEXT,
+  attempts INTEGER NOT NULL,
+  received_at TIMESTAMP NOT NULL,
+  processed_at TIMESTAMP
+);
+
+CREATE INDEX webhook_events_received_at_idx ON webhook_events (received_at, id);
+
+-- +goose Down
+DROP TABLE webhook_events;
\ No newline at end of file
@@ -0,0 +1,21 @@
+-- +goose Up
+CREATE TABLE subscriptions (
+  user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+  plan TEXT NOT NULL,
+  status TEXT NOT NULL CHECK (status IN ('active', 'past_due', 'canceled', 'expired')),
+  current_period_end TIMESTAMP NOT NULL,
+  canceled_at TIMESTAMP,
+  created_at TIMESTAMP NOT NULL,
+  updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX subscriptions_current_period_end_idx ON subscriptions (current_period_end) WHERE status <> 'expired';
+
+-- Users upgraded before subscriptions were tracked keep Red for one more
+-- period, which gives Polka time to send a renewal.
+INSERT INTO subscriptions (user_id, plan, status, current_period_end, created_at, updated_at)
+SELECT id, 'red', 'active', NOW() + INTERVAL '1 month', NOW(), NOW()
+FROM users WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
\ No newline at end of file
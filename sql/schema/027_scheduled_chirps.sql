@@ -0,0 +1,19 @@
+-- +goose Up
+CREATE TABLE scheduled_chirps (
+  id UUID PRIMARY KEY,
+  user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  body TEXT NOT NULL,
+  parent_id UUID REFERENCES chirps(id) ON DELETE CASCADE,
+  publish_at TIMESTAMP NOT NULL,
+  chirp_id UUID REFERENCES chirps(id) ON DELETE SET NULL,
+  published_at TIMESTAMP,
+  failed_at TIMESTAMP,
+  error TEXT,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (publish_at, id) WHERE published_at IS NULL AND failed_at IS NULL;
+CREATE INDEX scheduled_chirps_user_id_idx ON scheduled_chirps (user_id, publish_at);
+
+-- +goose Down
+DROP TABLE scheduled_chirps;
\ No newline at end of file
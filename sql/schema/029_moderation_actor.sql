@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE moderation_actions ADD COLUMN actor_id UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- +goose Down
+ALTER TABLE moderation_actions DROP COLUMN actor_id;
\ No newline at end of file
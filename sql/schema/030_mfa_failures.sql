@@ -0,0 +1,11 @@
+-- +goose Up
+ALTER TABLE login_failures DROP CONSTRAINT login_failures_kind_check;
+ALTER TABLE login_failures ADD CONSTRAINT login_failures_kind_check CHECK (kind IN ('account', 'ip', 'mfa'));
+
+CREATE INDEX mfa_challenges_expires_at_idx ON mfa_challenges (expires_at);
+
+-- +goose Down
+DROP INDEX mfa_challenges_expires_at_idx;
+DELETE FROM login_failures WHERE kind = 'mfa';
+ALTER TABLE login_failures DROP CONSTRAINT login_failures_kind_check;
+ALTER TABLE login_failures ADD CONSTRAINT login_failures_kind_check CHECK (kind IN ('account', 'ip'));
\ No newline at end of file
@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE login_failures DROP CONSTRAINT login_failures_kind_check;
+ALTER TABLE login_failures ADD CONSTRAINT login_failures_kind_check CHECK (kind IN ('account', 'ip', 'mfa', 'reset_account', 'reset_ip'));
+
+-- +goose Down
+DELETE FROM login_failures WHERE kind IN ('reset_account', 'reset_ip');
+ALTER TABLE login_failures DROP CONSTRAINT login_failures_kind_check;
+ALTER TABLE login_failures ADD CONSTRAINT login_failures_kind_check CHECK (kind IN ('account', 'ip', 'mfa'));
\ No newline at end of file
@@ -0,0 +1,7 @@
+-- +goose Up
+-- A downgrade cuts the current period short. The period it had is kept so
+-- late events for it can be told apart from a new subscription.
+ALTER TABLE subscriptions ADD COLUMN ended_period_end TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE subscriptions DROP COLUMN ended_period_end;
\ No newline at end of file
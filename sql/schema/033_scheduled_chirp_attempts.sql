@@ -0,0 +1,16 @@
+-- +goose Up
+-- Chirps that fail to publish for reasons other than a rejection are retried
+-- later instead of holding up the chirps due after them.
+ALTER TABLE scheduled_chirps ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE scheduled_chirps ADD COLUMN next_attempt_at TIMESTAMP;
+UPDATE scheduled_chirps SET next_attempt_at = publish_at;
+ALTER TABLE scheduled_chirps ALTER COLUMN next_attempt_at SET NOT NULL;
+
+DROP INDEX scheduled_chirps_due_idx;
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (next_attempt_at, id) WHERE published_at IS NULL AND failed_at IS NULL;
+
+-- +goose Down
+DROP INDEX scheduled_chirps_due_idx;
+CREATE INDEX scheduled_chirps_due_idx ON scheduled_chirps (publish_at, id) WHERE published_at IS NULL AND failed_at IS NULL;
+ALTER TABLE scheduled_chirps DROP COLUMN next_attempt_at;
+ALTER TABLE scheduled_chirps DROP COLUMN attempts;
\ No newline at end of file
@@ -0,0 +1,22 @@
+-- +goose Up
+-- Likes and rechirps go with a deleted user through ON DELETE CASCADE, which
+-- would leave the chirps' counters too high. Give the counts back first.
+-- +goose StatementBegin
+CREATE FUNCTION release_user_engagement() RETURNS trigger AS $$
+BEGIN
+  UPDATE chirps SET like_count = like_count - 1
+  WHERE id IN (SELECT chirp_id FROM chirp_likes WHERE user_id = OLD.id);
+  UPDATE chirps SET rechirp_count = rechirp_count - 1
+  WHERE id IN (SELECT chirp_id FROM chirp_rechirps WHERE user_id = OLD.id);
+  RETURN OLD;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER users_release_engagement
+BEFORE DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION release_user_engagement();
+
+-- +goose Down
+DROP TRIGGER users_release_engagement ON users;
+DROP FUNCTION release_user_engagement();
\ No newline at end of file
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	subscriptionActive = "active"
+	subscriptionPastDue = "past_due"
+	subscriptionCanceled = "canceled"
+	subscriptionExpired = "expired"
+)
+
+// defaultSubscriptionPeriod is how long Chirpy Red lasts when Polka does not
+// say when the billed period ends.
+const defaultSubscriptionPeriod = 30 * 24 * time.Hour
+
+const subscriptionExpiryInterval = time.Minute
+
+// subscriptionStatus is the status to show for sub. A period can lapse
+// shortly before expireSubscriptions gets to it.
+func subscriptionStatus(sub database.Subscription, now time.Time) string {
+	if sub.Status != subscriptionExpired && !sub.CurrentPeriodEnd.After(now) {
+		return subscriptionExpired
+	}
+	return sub.Status
+}
+
+// activateSubscription starts or renews a subscription and turns on Chirpy Red.
+// The period end never moves backwards, so a late redelivery of an older
+// renewal does not shorten the subscription. A subscription that is no longer
+// active is only made active again for a period that ends after every period
+// seen so far, so an older upgrade or renewal arriving after a downgrade or
+// cancellation does not bring Red back.
+func activateSubscription(ctx context.Context, q *database.Queries, userID uuid.UUID, plan string, periodEnd time.Time) error {
+	sub, err := q.GetSubscriptionForUpdate(ctx, userID)
+	if err == nil && sub.Status != subscriptionActive && !periodEnd.After(latestPeriodEnd(sub)) {
+		log.Printf("Ignoring stale activation for user %s: period ends %v, subscription is %s", userID, periodEnd, sub.Status)
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	_, err = q.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{ID: userID, IsChirpyRed: true})
+	if errors.Is(err, sql.ErrNoRows) {
+		return &webhookEventError{404, "User not found"}
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ActivateSubscription(ctx, database.ActivateSubscriptionParams{
+		UserID: userID,
+		Plan: sql.NullString{String: plan, Valid: plan != ""},
+		CurrentPeriodEnd: periodEnd,
+	})
+	return err
+}
+
+// latestPeriodEnd is the end of the latest period sub was billed for, before
+// a downgrade cut it short.
+func latestPeriodEnd(sub database.Subscription) time.Time {
+	if sub.EndedPeriodEnd.Valid && sub.EndedPeriodEnd.Time.After(sub.CurrentPeriodEnd) {
+		return sub.EndedPeriodEnd.Time
+	}
+	return sub.CurrentPeriodEnd
+}
+
+// changeSubscriptionStatus moves a subscription that still grants Chirpy Red
+// to status. Red stays on until the current period ends; a renewal after a
+// failed payment makes the subscription active again.
+func changeSubscriptionStatus(ctx context.Context, q *database.Queries, userID uuid.UUID, status string) error {
+	sub, err := q.GetSubscriptionForUpdate(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &webhookEventError{404, "Subscription not found"}
+	}
+	if err != nil {
+		return err
+	}
+
+	// A canceled subscription stays canceled when a payment fails afterwards.
+	if sub.Status == subscriptionExpired || sub.Status == subscriptionCanceled || sub.Status == status {
+		return nil
+	}
+	canceledAt := sub.CanceledAt
+	if status == subscriptionCanceled {
+		canceledAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return q.SetSubscriptionStatus(ctx, database.SetSubscriptionStatusParams{
+		UserID: userID,
+		Status: status,
+		CanceledAt: canceledAt,
+	})
+}
+
+// endSubscription turns off Chirpy Red right away.
+func endSubscription(ctx context.Context, q *database.Queries, userID uuid.UUID) error {
+	_, err := q.SetUserChirpyRed(ctx, database.SetUserChirpyRedParams{ID: userID, IsChirpyRed: false})
+	if errors.Is(err, sql.ErrNoRows) {
+		return &webhookEventError{404, "User not found"}
+	}
+	if err != nil {
+		return err
+	}
+	return q.EndSubscription(ctx, userID)
+}
+
+// expireSubscriptions turns off Chirpy Red for subscriptions whose period has
+// ended without a renewal.
+func expireSubscriptions(ctx context.Context, apiCfg *apiConfig) {
+	userIDs, err := apiCfg.database.ExpireSubscriptions(ctx)
+	if err != nil {
+		log.Printf("Error expiring subscriptions: %v", err)
+	}
+	for _, userID := range userIDs {
+		log.Printf("Chirpy Red expired for user %s", userID)
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+func TestSubscriptionStatus(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		status string
+		periodEnd time.Time
+		want string
+	}{
+		{"active", subscriptionActive, now.Add(time.Hour), subscriptionActive},
+		{"canceled within the period", subscriptionCanceled, now.Add(time.Hour), subscriptionCanceled},
+		{"past due within the period", subscriptionPastDue, now.Add(time.Hour), subscriptionPastDue},
+		{"active after the period", subscriptionActive, now.Add(-time.Second), subscriptionExpired},
+		{"canceled after the period", subscriptionCanceled, now, subscriptionExpired},
+		{"expired", subscriptionExpired, now.Add(time.Hour), subscriptionExpired},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := subscriptionStatus(database.Subscription{Status: tc.status, CurrentPeriodEnd: tc.periodEnd}, now)
+			if got != tc.want {
+				t.Errorf("Expected %s, but got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+// fakeSubscriptions keeps the subscriptions table and the users' Chirpy Red
+// flag of a fakeDB in memory, following what the queries do.
+type fakeSubscriptions struct {
+	red  map[string]bool
+	subs map[string]*database.Subscription
+}
+
+func newFakeSubscriptions(db *fakeDB, userIDs ...uuid.UUID) *fakeSubscriptions {
+	f := &fakeSubscriptions{red: map[string]bool{}, subs: map[string]*database.Subscription{}}
+	for _, userID := range userIDs {
+		f.red[userID.String()] = false
+	}
+
+	db.on("SetUserChirpyRed", func(args []driver.Value) (any, error) {
+		id := args[0].(string)
+		if _, ok := f.red[id]; !ok {
+			return nil, nil
+		}
+		f.red[id] = args[1].(bool)
+		return database.User{ID: uuid.MustParse(id), IsChirpyRed: args[1].(bool)}, nil
+	})
+	db.on("GetSubscriptionForUpdate", func(args []driver.Value) (any, error) {
+		sub, ok := f.subs[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return *sub, nil
+	})
+	db.on("ActivateSubscription", func(args []driver.Value) (any, error) {
+		id := args[0].(string)
+		periodEnd := args[2].(time.Time)
+		sub, ok := f.subs[id]
+		if !ok {
+			sub = &database.Subscription{UserID: uuid.MustParse(id), Plan: "red", CurrentPeriodEnd: periodEnd}
+			f.subs[id] = sub
+		}
+		if plan, ok := args[1].(string); ok {
+			sub.Plan = plan
+		}
+		if periodEnd.After(sub.CurrentPeriodEnd) {
+			sub.CurrentPeriodEnd = periodEnd
+		}
+		sub.Status = subscriptionActive
+		sub.CanceledAt = sql.NullTime{}
+		return *sub, nil
+	})
+	db.on("SetSubscriptionStatus", func(args []driver.Value) (any, error) {
+		sub := f.subs[args[0].(string)]
+		sub.Status = args[1].(string)
+		canceledAt, ok := args[2].(time.Time)
+		sub.CanceledAt = sql.NullTime{Time: canceledAt, Valid: ok}
+		return nil, nil
+	})
+	db.on("EndSubscription", func(args []driver.Value) (any, error) {
+		sub, ok := f.subs[args[0].(string)]
+		if !ok {
+			return int64(0), nil
+		}
+		sub.Status = subscriptionExpired
+		sub.EndedPeriodEnd = sql.NullTime{Time: sub.CurrentPeriodEnd, Valid: true}
+		if now := time.Now(); now.Before(sub.CurrentPeriodEnd) {
+			sub.CurrentPeriodEnd = now
+		}
+		return nil, nil
+	})
+	db.on("ExpireSubscriptions", func(args []driver.Value) (any, error) {
+		var expired []uuid.UUID
+		for id, sub := range f.subs {
+			if sub.Status != subscriptionExpired && !sub.CurrentPeriodEnd.After(time.Now()) {
+				sub.Status = subscriptionExpired
+				f.red[id] = false
+				expired = append(expired, sub.UserID)
+			}
+		}
+		return expired, nil
+	})
+	return f
+}
+
+func TestSubscriptionLifecycle(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	userID := uuid.New()
+	subs := newFakeSubscriptions(db, userID)
+
+	now := time.Now()
+	first, second, third := now.Add(24*time.Hour), now.Add(30*24*time.Hour), now.Add(60*24*time.Hour)
+	event := func(name string, periodEnd time.Time) polkaEvent {
+		event := polkaEvent{Event: name}
+		event.Data.UserID = userID.String()
+		if !periodEnd.IsZero() {
+			event.Data.CurrentPeriodEnd = &periodEnd
+		}
+		return event
+	}
+
+	steps := []struct {
+		name      string
+		event     polkaEvent
+		status    string
+		red       bool
+		periodEnd time.Time
+	}{
+		{"upgrade", event("user.upgraded", first), subscriptionActive, true, first},
+		{"failed payment", event("subscription.payment_failed", time.Time{}), subscriptionPastDue, true, first},
+		{"renewal", event("subscription.renewed", second), subscriptionActive, true, second},
+		{"older renewal", event("subscription.renewed", first), subscriptionActive, true, second},
+		{"cancellation", event("subscription.canceled", time.Time{}), subscriptionCanceled, true, second},
+		{"failed payment after cancellation", event("subscription.payment_failed", time.Time{}), subscriptionCanceled, true, second},
+		{"renewal redelivered after cancellation", event("subscription.renewed", second), subscriptionCanceled, true, second},
+		{"downgrade", event("user.downgraded", time.Time{}), subscriptionExpired, false, time.Time{}},
+		{"upgrade redelivered after downgrade", event("user.upgraded", first), subscriptionExpired, false, time.Time{}},
+		{"renewal redelivered after downgrade", event("subscription.renewed", second), subscriptionExpired, false, time.Time{}},
+		{"new upgrade", event("user.upgraded", third), subscriptionActive, true, third},
+	}
+
+	for _, step := range steps {
+		handled, err := applyPolkaEvent(t.Context(), apiCfg.database, step.event)
+		if !handled || err != nil {
+			t.Fatalf("%s: expected the event to be applied, but got %v, %v", step.name, handled, err)
+		}
+		sub := subs.subs[userID.String()]
+		if sub.Status != step.status || subs.red[userID.String()] != step.red {
+			t.Errorf("%s: expected %s with Red %v, but got %s with Red %v", step.name, step.status, step.red, sub.Status, subs.red[userID.String()])
+		}
+		if !step.periodEnd.IsZero() && !sub.CurrentPeriodEnd.Equal(step.periodEnd) {
+			t.Errorf("%s: expected the period to end %v, but got %v", step.name, step.periodEnd, sub.CurrentPeriodEnd)
+		}
+		if step.status == subscriptionCanceled && !sub.CanceledAt.Valid {
+			t.Errorf("%s: expected the cancellation time to be kept", step.name)
+		}
+	}
+}
+
+func TestSubscriptionEventsForUnknownUsers(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	withoutSubscription := uuid.New()
+	newFakeSubscriptions(db, withoutSubscription)
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		apply  func(userID uuid.UUID) error
+	}{
+		{"status change without a subscription", withoutSubscription, func(userID uuid.UUID) error {
+			return changeSubscriptionStatus(t.Context(), apiCfg.database, userID, subscriptionCanceled)
+		}},
+		{"downgrade of an unknown user", uuid.New(), func(userID uuid.UUID) error {
+			return endSubscription(t.Context(), apiCfg.database, userID)
+		}},
+		{"upgrade of an unknown user", uuid.New(), func(userID uuid.UUID) error {
+			return activateSubscription(t.Context(), apiCfg.database, userID, "", time.Now().Add(time.Hour))
+		}},
+	}
+
+	for _, test := range tests {
+		err := test.apply(test.userID)
+		var eventErr *webhookEventError
+		if !errors.As(err, &eventErr) || eventErr.Code != 404 {
+			t.Errorf("%s: expected a 404 event error, but got %v", test.name, err)
+		}
+	}
+}
+
+func TestExpireSubscriptions(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	lapsed, canceled, current := uuid.New(), uuid.New(), uuid.New()
+	subs := newFakeSubscriptions(db, lapsed, canceled, current)
+	past := time.Now().Add(-time.Minute)
+	for _, userID := range []uuid.UUID{lapsed, canceled, current} {
+		subs.red[userID.String()] = true
+	}
+	subs.subs[lapsed.String()] = &database.Subscription{UserID: lapsed, Status: subscriptionPastDue, CurrentPeriodEnd: past}
+	subs.subs[canceled.String()] = &database.Subscription{UserID: canceled, Status: subscriptionCanceled, CurrentPeriodEnd: past}
+	subs.subs[current.String()] = &database.Subscription{UserID: current, Status: subscriptionActive, CurrentPeriodEnd: time.Now().Add(time.Hour)}
+
+	expireSubscriptions(t.Context(), apiCfg)
+
+	for _, userID := range []uuid.UUID{lapsed, canceled} {
+		if subs.subs[userID.String()].Status != subscriptionExpired || subs.red[userID.String()] {
+			t.Errorf("Expected the lapsed subscription of %v to expire", userID)
+		}
+	}
+	if subs.subs[current.String()].Status != subscriptionActive || !subs.red[current.String()] {
+		t.Errorf("Expected the current subscription to stay active")
+	}
+}
+
+// TestSubscriptionQueries checks the SQL the fake above stands in for.
+func TestSubscriptionQueries(t *testing.T) {
+	_, q := newTestDatabase(t)
+	user := createTestUser(t, q, "red@example.com")
+	periodEnd := time.Now().Add(30 * 24 * time.Hour).UTC().Truncate(time.Second)
+
+	_, err := q.ActivateSubscription(t.Context(), database.ActivateSubscriptionParams{UserID: user.ID, CurrentPeriodEnd: periodEnd})
+	if err != nil {
+		t.Fatalf("Expected no error activating, but got %v", err)
+	}
+	err = q.EndSubscription(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error ending, but got %v", err)
+	}
+
+	sub, err := q.GetSubscriptionForUpdate(t.Context(), user.ID)
+	if err != nil {
+		t.Fatalf("Expected no error fetching, but got %v", err)
+	}
+	if sub.Status != subscriptionExpired || !sub.CurrentPeriodEnd.Before(periodEnd) {
+		t.Errorf("Expected the subscription to end now, but got %s until %v", sub.Status, sub.CurrentPeriodEnd)
+	}
+	if !sub.EndedPeriodEnd.Valid || !sub.EndedPeriodEnd.Time.Equal(periodEnd) {
+		t.Errorf("Expected the ended period to be kept, but got %v", sub.EndedPeriodEnd)
+	}
+	if !latestPeriodEnd(sub).Equal(periodEnd) {
+		t.Errorf("Expected the latest period to end %v, but got %v", periodEnd, latestPeriodEnd(sub))
+	}
+}
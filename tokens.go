@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+)
+
+const (
+	accessTokenTTL = time.Hour
+	refreshTokenTTL = 60 * 24 * time.Hour
+
+	maxUserAgentLength = 512
+)
+
+// deviceInfo is the client metadata stored with each refresh token so users
+// can recognise their sessions.
+type deviceInfo struct {
+	UserAgent string
+	IPAddress string
+}
+
+func deviceFromRequest(r *http.Request, apiCfg *apiConfig) deviceInfo {
+	userAgent := r.UserAgent()
+	if utf8.RuneCountInString(userAgent) > maxUserAgentLength {
+		userAgent = string([]rune(userAgent)[:maxUserAgentLength])
+	}
+	return deviceInfo{UserAgent: userAgent, IPAddress: clientIP(r, apiCfg.trustedProxyHops)}
+}
+
+// clientIP returns the address of the client. Behind proxyHops trusted
+// proxies, each of which appends the address it received the request from to
+// X-Forwarded-For, the client is the entry that many places from the right.
+// Entries further left were sent by the client and could say anything.
+func clientIP(r *http.Request, proxyHops int) string {
+	if proxyHops > 0 {
+		var forwarded []string
+		for _, header := range r.Header.Values("X-Forwarded-For") {
+			for _, entry := range strings.Split(header, ",") {
+				forwarded = append(forwarded, strings.TrimSpace(entry))
+			}
+		}
+		// Fewer entries than hops means the request skipped some of the
+		// proxies, so every entry was still added by one we trust.
+		if len(forwarded) > 0 {
+			ip := net.ParseIP(forwarded[max(len(forwarded)-proxyHops, 0)])
+			if ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// issueRefreshToken creates and stores a refresh token. Tokens handed out by
+// rotation share the family and the expiry of the token they replace, so a
+// session ends refreshTokenTTL after login however often it is refreshed; a
+// login starts a new family.
+func issueRefreshToken(ctx context.Context, q *database.Queries, userID, familyID uuid.UUID, expiresAt time.Time, device deviceInfo) (string, error) {
+	refresh, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = q.InsertRefreshToken(ctx, database.InsertRefreshTokenParams{
+		Token: refresh,
+		UserID: userID,
+		ExpiresAt: expiresAt,
+		FamilyID: familyID,
+		UserAgent: device.UserAgent,
+		IpAddress: device.IPAddress,
+	})
+	if err != nil {
+		return "", err
+	}
+	return refresh, nil
+}
+
+func logSecurityEvent(event string, userID uuid.UUID, format string, args ...any) {
+	log.Printf("Security event %s for user %s: %s", event, userID, fmt.Sprintf(format, args...))
+}
+
+// issueSession starts a new session for user: a fresh refresh token family
+// and an access token, returned in the shape of the login response.
+func issueSession(r *http.Request, q *database.Queries, apiCfg *apiConfig, user database.User) (LoginResponse, error) {
+	jwt, err := apiCfg.keys.MakeJWT(userPrincipal(user), accessTokenTTL)
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	refresh, err := issueRefreshToken(r.Context(), q, user.ID, uuid.New(), time.Now().Add(refreshTokenTTL), deviceFromRequest(r, apiCfg))
+	if err != nil {
+		return LoginResponse{}, err
+	}
+
+	return LoginResponse{
+		ID: user.ID,
+		Email: user.Email,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		AccessToken: jwt,
+		RefreshToken: refresh,
+		IsChirpyRed: user.IsChirpyRed,
+		Role: user.Role,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+	}, nil
+}
+
+// respondWithLogin finishes signing in a user whose first factor has been
+// checked: it asks for the second factor if one is set up and otherwise
+// starts a session.
+func respondWithLogin(w http.ResponseWriter, r *http.Request, apiCfg *apiConfig, user database.User) {
+	if user.SuspendedAt.Valid {
+		respondWithError(w, 403, "Your account is suspended")
+		return
+	}
+
+	totp, err := apiCfg.database.GetUserTotp(r.Context(), user.ID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		log.Printf("Error fetching TOTP settings: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	// Login failures are only cleared once the second factor is provided too,
+	// so a known password does not reset the count of wrong codes.
+	if err == nil && totp.ConfirmedAt.Valid {
+		challenge, err := createMFAChallenge(r.Context(), apiCfg.database, user.ID)
+		if err != nil {
+			log.Printf("Error creating MFA challenge: %v", err)
+			w.WriteHeader(500)
+			return
+		}
+		respondWithJSON(w, 200, challenge)
+		return
+	}
+
+	response, err := issueSession(r, apiCfg.database, apiCfg, user)
+	if err != nil {
+		log.Printf("Error issuing session: %v", err)
+		w.WriteHeader(500)
+		return
+	}
+	clearLoginFailures(r.Context(), apiCfg, user.Email)
+	respondWithJSON(w, 200, response)
+}
+
+// userPrincipal is the principal of an access token issued at login, scoped
+// to everything the user's role allows.
+func userPrincipal(user database.User) auth.Principal {
+	return auth.Principal{UserID: user.ID, Role: user.Role, Scopes: auth.ScopesForRole(user.Role)}
+}
+
+// jwksHandler publishes the public keys access tokens can be verified with.
+func jwksHandler(apiCfg *apiConfig) http.HandlerFunc {return func(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	respondWithJSON(w, 200, apiCfg.keys.JWKS())
+}}
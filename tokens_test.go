@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		remoteAddr string
+		forwarded  []string
+		hops       int
+		expected   string
+	}{
+		{"192.0.2.1:1234", nil, 0, "192.0.2.1"},
+		{"192.0.2.1:1234", []string{"198.51.100.7"}, 0, "192.0.2.1"},
+		{"192.0.2.1:1234", []string{"198.51.100.7"}, 1, "198.51.100.7"},
+		// The client made up the first entry; the proxy appended the second.
+		{"192.0.2.1:1234", []string{"203.0.113.9, 198.51.100.7"}, 1, "198.51.100.7"},
+		{"192.0.2.1:1234", []string{"203.0.113.9, 198.51.100.7, 10.0.0.1"}, 2, "198.51.100.7"},
+		{"192.0.2.1:1234", []string{"203.0.113.9", "198.51.100.7"}, 1, "198.51.100.7"},
+		{"192.0.2.1:1234", []string{"198.51.100.7"}, 2, "198.51.100.7"},
+		{"192.0.2.1:1234", []string{"not-an-ip"}, 1, "192.0.2.1"},
+		{"[2001:db8::1]:443", nil, 0, "2001:db8::1"},
+	}
+
+	for _, test := range tests {
+		r := httptest.NewRequest("POST", "/api/login", nil)
+		r.RemoteAddr = test.remoteAddr
+		for _, forwarded := range test.forwarded {
+			r.Header.Add("X-Forwarded-For", forwarded)
+		}
+		result := clientIP(r, test.hops)
+		if result != test.expected {
+			t.Errorf("Expected '%s', but got '%s'", test.expected, result)
+		}
+	}
+}
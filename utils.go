@@ -1,10 +1,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/moderation"
 )
 
 func respondWithError(w http.ResponseWriter, code int, msg string) {
@@ -25,6 +32,17 @@ func respondWithError(w http.ResponseWriter, code int, msg string) {
 	w.Write(data)
 }
 
+// respondWithFieldErrors writes an error response that also explains, per
+// request field, which rules the submitted value broke.
+func respondWithFieldErrors(w http.ResponseWriter, code int, msg string, fields map[string][]FieldError) {
+	type response struct {
+		Error string `json:"error"`
+		Fields map[string][]FieldError `json:"fields"`
+	}
+
+	respondWithJSON(w, code, response{Error: msg, Fields: fields})
+}
+
 func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	data, err := json.Marshal(payload)
 	if err != nil {
@@ -38,17 +56,156 @@ func respondWithJSON(w http.ResponseWriter, code int, payload any) {
 	w.Write(data)
 }
 
-func profanityFilter(msg string) string {
-	bannedWords := []string {"kerfuffle", "sharbert", "fornax"}
-	words := strings.Split(msg, " ")
+func newChirpResponse(chirp database.Chirp) ChirpResponse {
+	response := ChirpResponse{
+		ID: chirp.ID,
+		UserID: chirp.UserID,
+		Body: chirp.Body,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		LikeCount: chirp.LikeCount,
+		RechirpCount: chirp.RechirpCount,
+	}
+	if chirp.EditedAt.Valid {
+		response.EditedAt = &chirp.EditedAt.Time
+	}
+	if chirp.ParentID.Valid {
+		response.ParentID = &chirp.ParentID.UUID
+	}
+	if chirp.DeletedAt.Valid {
+		response.Body = ""
+		response.Deleted = true
+	}
+	response.Entities = chirpEntitiesResponse(response.Body, nil)
+	return response
+}
+
+const maxThreadSize = 500
+
+func chirpFromThreadRow(row database.GetChirpDescendantsRow) database.Chirp {
+	return database.Chirp{
+		ID: row.ID,
+		UserID: row.UserID,
+		Body: row.Body,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+		EditedAt: row.EditedAt,
+		ParentID: row.ParentID,
+		DeletedAt: row.DeletedAt,
+		LikeCount: row.LikeCount,
+		RechirpCount: row.RechirpCount,
+	}
+}
+
+// buildChirpThread arranges chirps into a reply tree rooted at rootID. Chirps
+// whose parent is not part of the list are dropped.
+func buildChirpThread(rootID uuid.UUID, chirps []ChirpResponse) ChirpThreadNode {
+	children := make(map[uuid.UUID][]ChirpResponse)
+	var root ChirpResponse
+	for _, chirp := range chirps {
+		if chirp.ID == rootID {
+			root = chirp
+			continue
+		}
+		if chirp.ParentID != nil {
+			children[*chirp.ParentID] = append(children[*chirp.ParentID], chirp)
+		}
+	}
+
+	var build func(chirp ChirpResponse) ChirpThreadNode
+	build = func(chirp ChirpResponse) ChirpThreadNode {
+		node := ChirpThreadNode{ChirpResponse: chirp, Replies: []ChirpThreadNode{}}
+		for _, reply := range children[chirp.ID] {
+			node.Replies = append(node.Replies, build(reply))
+		}
+		return node
+	}
+
+	return build(root)
+}
+
+// chirpResponses converts chirps for the response, resolves their mentions and
+// marks the ones the viewer has liked or rechirped. Anonymous viewers pass
+// uuid.Nil.
+func chirpResponses(ctx context.Context, apiCfg *apiConfig, viewerID uuid.UUID, chirps []database.Chirp) ([]ChirpResponse, error) {
+	responses := make([]ChirpResponse, len(chirps))
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		responses[i] = newChirpResponse(chirp)
+		chirpIDs[i] = chirp.ID
+	}
+
+	if len(chirps) == 0 {
+		return responses, nil
+	}
 
-	for i, word := range words {
-		for _, banned := range bannedWords {
-			if strings.ToLower(word) == banned {
-				words[i] = "****"
+	mentions, err := apiCfg.database.GetChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return nil, err
+	}
+	if len(mentions) > 0 {
+		mentioned := make(map[uuid.UUID]map[string]uuid.UUID)
+		for _, mention := range mentions {
+			if mentioned[mention.ChirpID] == nil {
+				mentioned[mention.ChirpID] = make(map[string]uuid.UUID)
 			}
+			mentioned[mention.ChirpID][strings.ToLower(mention.Email)] = mention.UserID
+		}
+		for i := range responses {
+			responses[i].Entities = chirpEntitiesResponse(responses[i].Body, mentioned[responses[i].ID])
 		}
 	}
 
-	return strings.Join(words, " ")
+	if viewerID == uuid.Nil {
+		return responses, nil
+	}
+
+	engagement, err := apiCfg.database.GetViewerEngagement(ctx, database.GetViewerEngagementParams{UserID: viewerID, ChirpIds: chirpIDs})
+	if err != nil {
+		return nil, err
+	}
+
+	byChirp := make(map[uuid.UUID]database.GetViewerEngagementRow, len(engagement))
+	for _, row := range engagement {
+		byChirp[row.ChirpID] = row
+	}
+	for i := range responses {
+		responses[i].Liked = byChirp[responses[i].ID].Liked
+		responses[i].Rechirped = byChirp[responses[i].ID].Rechirped
+	}
+
+	return responses, nil
+}
+
+// viewerIDFromRequest returns the user behind an optional access token or
+// personal access token, or uuid.Nil for anonymous requests. Tokens are
+// resolved as by middleWareAuth, so a revoked grant or deleted user reads as
+// anonymous.
+func viewerIDFromRequest(r *http.Request, apiCfg *apiConfig) uuid.UUID {
+	token, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil
+	}
+	principal, _, err := resolvePrincipal(r.Context(), apiCfg, token)
+	if err != nil {
+		return uuid.Nil
+	}
+	return principal.UserID
+}
+
+// principalFromRequest returns the caller stored by middleWareAuth.
+func principalFromRequest(r *http.Request) auth.Principal {
+	principal, _ := r.Context().Value(principalContextKey{}).(auth.Principal)
+	return principal
+}
+
+func cleanChirpBody(filter *moderation.Filter, body string, maxLength int) (moderation.Result, error) {
+	if len([]rune(body)) > maxLength {
+		return moderation.Result{}, fmt.Errorf("Your message is too long")
+	}
+	result := filter.Check(body)
+	if result.Rejected {
+		return result, fmt.Errorf("Your message contains prohibited language")
+	}
+	return result, nil
 }
\ No newline at end of file
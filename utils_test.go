@@ -1,25 +1,127 @@
 package main
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/isotronic/http-go-server/internal/auth"
+	"github.com/isotronic/http-go-server/internal/database"
+	"github.com/isotronic/http-go-server/internal/moderation"
 )
 
-func TestProfanityFilter(t *testing.T) {
+func TestCleanChirpBody(t *testing.T) {
+	filter, err := moderation.NewFilter([]moderation.Rule{
+		{Pattern: "kerfuffle", Match: moderation.MatchWord, Action: moderation.ActionMask},
+		{Pattern: "fornax", Match: moderation.MatchWord, Action: moderation.ActionReject},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+
+	result, err := cleanChirpBody(filter, "What a kerfuffle!", 140)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if result.Text != "What a ****!" {
+		t.Errorf("Expected 'What a ****!', but got '%s'", result.Text)
+	}
+
+	_, err = cleanChirpBody(filter, "Fornax again", 140)
+	if err == nil {
+		t.Errorf("Expected an error for a chirp with a rejected word")
+	}
+
+	_, err = cleanChirpBody(filter, strings.Repeat("é", 141), 140)
+	if err == nil {
+		t.Errorf("Expected an error for a chirp longer than 140 characters")
+	}
+
+	_, err = cleanChirpBody(filter, strings.Repeat("é", 141), 500)
+	if err != nil {
+		t.Errorf("Expected no error for a chirp within a higher limit, but got %v", err)
+	}
+}
+
+func TestBuildChirpThread(t *testing.T) {
+	root := database.Chirp{ID: uuid.New(), Body: "root"}
+	reply := database.Chirp{ID: uuid.New(), Body: "reply", ParentID: uuid.NullUUID{UUID: root.ID, Valid: true}}
+	deleted := database.Chirp{ID: uuid.New(), Body: "gone", ParentID: uuid.NullUUID{UUID: root.ID, Valid: true}, DeletedAt: sql.NullTime{Time: time.Now(), Valid: true}}
+	nested := database.Chirp{ID: uuid.New(), Body: "nested", ParentID: uuid.NullUUID{UUID: deleted.ID, Valid: true}}
+
+	chirps := []ChirpResponse{newChirpResponse(root), newChirpResponse(reply), newChirpResponse(deleted), newChirpResponse(nested)}
+	thread := buildChirpThread(root.ID, chirps)
+
+	if thread.ID != root.ID || len(thread.Replies) != 2 {
+		t.Fatalf("Expected root with 2 replies, but got %d", len(thread.Replies))
+	}
+	if thread.Replies[0].ID != reply.ID || len(thread.Replies[0].Replies) != 0 {
+		t.Errorf("Expected first reply to be a leaf")
+	}
+	tombstone := thread.Replies[1]
+	if !tombstone.Deleted || tombstone.Body != "" {
+		t.Errorf("Expected deleted chirp to be a tombstone, but got '%s'", tombstone.Body)
+	}
+	if len(tombstone.Replies) != 1 || tombstone.Replies[0].ID != nested.ID {
+		t.Errorf("Expected replies to a tombstone to be kept")
+	}
+}
+func TestViewerIDFromRequest(t *testing.T) {
+	apiCfg, db := newTestAPIConfig(t)
+	user := database.User{ID: uuid.New(), Role: auth.RoleUser}
+	db.on("GetUserById", func(args []driver.Value) (any, error) {
+		if args[0] != user.ID.String() {
+			return nil, nil
+		}
+		return user, nil
+	})
+	pat := auth.PersonalAccessTokenPrefix + "reader"
+	revokedPAT := auth.PersonalAccessTokenPrefix + "revoked"
+	db.on("GetPersonalAccessTokenByHash", func(args []driver.Value) (any, error) {
+		switch args[0] {
+		case auth.HashToken(pat):
+			return database.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, Scopes: []string{auth.ScopeRead}}, nil
+		case auth.HashToken(revokedPAT):
+			return database.PersonalAccessToken{ID: uuid.New(), UserID: user.ID, Scopes: []string{auth.ScopeRead}, RevokedAt: sql.NullTime{Time: time.Now(), Valid: true}}, nil
+		}
+		return nil, nil
+	})
+	db.returns("TouchPersonalAccessToken", nil)
+
+	jwt, err := apiCfg.keys.MakeJWT(userPrincipal(user), time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error making a token, but got %v", err)
+	}
+	unknown, err := apiCfg.keys.MakeJWT(userPrincipal(database.User{ID: uuid.New(), Role: auth.RoleUser}), time.Hour)
+	if err != nil {
+		t.Fatalf("Expected no error making a token, but got %v", err)
+	}
+
 	tests := []struct {
-		input    string
-		expected string
+		name     string
+		token    string
+		expected uuid.UUID
 	}{
-		{"This is a kerfuffle", "This is a ****"},
-		{"Sharbert is a word", "**** is a word"},
-		{"Fornax is banned", "**** is banned"},
-		{"No banned words here", "No banned words here"},
-		{"Mixed case Kerfuffle", "Mixed case ****"},
+		{"anonymous", "", uuid.Nil},
+		{"access token", jwt, user.ID},
+		{"personal access token", pat, user.ID},
+		{"revoked personal access token", revokedPAT, uuid.Nil},
+		{"unknown user", unknown, uuid.Nil},
+		{"invalid token", "not-a-token", uuid.Nil},
 	}
 
 	for _, test := range tests {
-		result := profanityFilter(test.input)
+		r := httptest.NewRequest("GET", "/api/chirps", nil)
+		if test.token != "" {
+			r.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		result := viewerIDFromRequest(r, apiCfg)
 		if result != test.expected {
-			t.Errorf("Expected '%s', but got '%s'", test.expected, result)
+			t.Errorf("%s: expected viewer %v, but got %v", test.name, test.expected, result)
 		}
 	}
-}
\ No newline at end of file
+}
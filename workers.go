@@ -0,0 +1,22 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// runEvery calls task right away and then every interval until ctx is done.
+func runEvery(ctx context.Context, interval time.Duration, task func(ctx context.Context)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		task(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}